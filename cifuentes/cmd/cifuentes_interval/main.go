@@ -75,7 +75,7 @@ func printNodes(ns []*interval.Node) {
 		if n.IsCondNode != false {
 			fmt.Println("IsCondNode:", n.IsCondNode)
 		}
-		if len(n.CompCond) > 0 {
+		if n.CompCond != nil {
 			fmt.Println("CompCond:  ", n.CompCond)
 		}
 		fmt.Println()
@@ -1,23 +1,37 @@
 // The ll2go tool decompiles LLVM IR assembly to Go source code (*.ll -> *.go).
 //
 // The input of ll2go is LLVM IR assembly and the output is unpolished Go source
-// code.
+// code. Several LLVM IR assembly files (e.g. the translation units of a
+// program) are decompiled into one Go source file each, of the same Go
+// package.
+//
+// Functions which fail to decompile are replaced by stubs which panic with the
+// error message, and instructions which fail to decompile are replaced by TODO
+// comments holding the LLVM IR assembly of the instruction. Errors are reported
+// to a JSON side file, and ll2go exits with a non-zero status.
 //
 // Usage:
 //
-//     ll2go [OPTION]... [FILE.ll]
+//     ll2go [OPTION]... [FILE.ll]...
 //
 // Flags:
 //
+//   -bigfloat
+//         represent extended precision floating-point types as big.Float
 //   -funcs string
 //         comma-separated list of functions to parse
 //   -o string
-//         output path
+//         output path (output directory if several files are decompiled)
+//   -pkg string
+//         package name (default derived from source file of module)
 //   -q    suppress non-error messages
+//   -report string
+//         path of JSON error report (default "FILE_errors.json")
 package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"go/ast"
@@ -55,7 +69,7 @@ Decompile LLVM IR assembly to Go source code (*.ll -> *.go).
 
 Usage:
 
-	ll2go [OPTION]... [FILE.ll]
+	ll2go [OPTION]... [FILE.ll]...
 
 Flags:
 `
@@ -66,28 +80,45 @@ Flags:
 func main() {
 	// Parse command line arguments.
 	var (
+		// bigFloat specifies whether to represent extended precision
+		// floating-point types as big.Float.
+		bigFloat bool
 		// funcs represents a comma-separated list of functions to parse.
 		funcs string
 		// output specifies the output path.
 		output string
+		// pkgName specifies the package name of the generated Go source file.
+		pkgName string
 		// quiet specifies whether to suppress non-error messages.
 		quiet bool
+		// reportPath specifies the path of the JSON error report.
+		reportPath string
 	)
+	flag.BoolVar(&bigFloat, "bigfloat", false, "represent extended precision floating-point types as big.Float")
 	flag.StringVar(&funcs, "funcs", "", "comma-separated list of functions to parse")
-	flag.StringVar(&output, "o", "", "output path")
+	flag.StringVar(&output, "o", "", "output path (output directory if several files are decompiled)")
+	flag.StringVar(&pkgName, "pkg", "", "package name (default derived from source file of module)")
 	flag.BoolVar(&quiet, "q", false, "suppress non-error messages")
+	flag.StringVar(&reportPath, "report", "", `path of JSON error report (default "FILE_errors.json")`)
 	flag.Usage = usage
 	flag.Parse()
-	var llPath string
+	var llPaths []string
 	switch flag.NArg() {
 	case 0:
 		// Parse LLVM IR assembly file from standard input.
-		llPath = "-"
+		llPaths = []string{"-"}
 	case 1:
-		llPath = flag.Arg(0)
+		llPaths = []string{flag.Arg(0)}
 	default:
-		flag.Usage()
-		os.Exit(1)
+		for _, llPath := range flag.Args() {
+			if llPath == "-" {
+				log.Fatal("standard input may not be decompiled together with other files")
+			}
+			llPaths = append(llPaths, llPath)
+		}
+	}
+	if len(pkgName) > 0 && !token.IsIdentifier(pkgName) {
+		log.Fatalf("invalid package name %q specified by the `-pkg` flag", pkgName)
 	}
 	// Parse functions specified by the `-funcs` flag.
 	funcNames := make(map[string]bool)
@@ -103,59 +134,84 @@ func main() {
 		dbg.SetOutput(ioutil.Discard)
 	}
 
-	// Parse LLMV IR assembly file.
-	m, err := parseModule(llPath)
-	if err != nil {
-		log.Fatalf("%+v", err)
+	// Parse LLMV IR assembly files.
+	var ms []*ir.Module
+	for _, llPath := range llPaths {
+		m, err := parseModule(llPath)
+		if err != nil {
+			log.Fatalf("%+v", err)
+		}
+		ms = append(ms, m)
 	}
 
 	// Decompile LLVM IR assembly to Go source code.
-	file, err := ll2go(m, llPath, funcNames)
-	if err != nil {
+	files, errs := ll2go(ms, llPaths, funcNames, bigFloat, pkgName)
+
+	// Output Go source files, including partial results of decompilation.
+	if err := outputFiles(files, llPaths, output); err != nil {
 		log.Fatalf("%+v", err)
 	}
 
-	// Output Go source file.
-	w := os.Stdout
-	if len(output) > 0 {
-		f, err := os.Create(output)
-		if err != nil {
-			log.Fatal(err)
+	// Report errors.
+	if len(errs) > 0 {
+		if len(reportPath) == 0 {
+			reportPath = "errors.json"
+			if llPaths[0] != "-" {
+				reportPath = pathutil.TrimExt(llPaths[0]) + "_errors.json"
+			}
 		}
-		defer f.Close()
-		w = f
-	}
-	if err := outputGo(w, file); err != nil {
-		log.Fatalf("%+v", err)
+		if err := outputReport(reportPath, errs); err != nil {
+			log.Fatalf("%+v", err)
+		}
+		warn.Printf("%v (see %q)", errs, reportPath)
+		os.Exit(1)
 	}
 }
 
-// ll2go decompiles the given LLVM IR module into a corresponding Go source
-// file.
+// ll2go decompiles the given LLVM IR modules into corresponding Go source files
+// of the same Go package.
 //
-// llPath specifies the path to the LLVM IR assembly file, relative to which the
-// output of other analysis phases are located.
+// llPaths specifies the paths to the LLVM IR assembly files of the modules,
+// relative to which the output of other analysis phases are located.
 //
 // funcNames specifies the set of function names to decompile. When funcNames is
-// emtpy, all functions of the module are decompiled.
-func ll2go(m *ir.Module, llPath string, funcNames map[string]bool) (*ast.File, error) {
+// emtpy, all functions of the modules are decompiled.
+//
+// bigFloat specifies whether to represent extended precision floating-point
+// types as big.Float.
+//
+// pkgName specifies the package name of the generated Go source files. When
+// pkgName is empty, the package name is derived from the source files of the
+// modules.
+//
+// The Go source files are returned even if errors are encountered during
+// decompilation, with stubs in place of the functions and TODO comments in
+// place of the instructions which failed to decompile.
+func ll2go(ms []*ir.Module, llPaths []string, funcNames map[string]bool, bigFloat bool, pkgName string) ([]*ast.File, ErrorList) {
 	// Error handler.
 	var errs ErrorList
 	eh := func(err error) {
 		errs = append(errs, err)
 	}
-	// Decompile LLVM IR module to Go source code.
-	gen := decompile.NewGenerator(eh, m)
-	// Set function for parsing recovered control flow primitives.
-	gen.Prims = func(f *ir.Func) ([]*primitive.Primitive, error) {
-		return parsePrims(llPath, f.Name())
+	// Decompile LLVM IR modules to Go source code.
+	gen := decompile.NewGenerator(eh, ms...)
+	gen.BigFloat = bigFloat
+	if len(pkgName) > 0 {
+		gen.PkgName = pkgName
 	}
-	file := gen.Decompile()
-	if len(errs) > 0 {
-		// TODO: return partial results of decompilation?
-		return nil, errs
+	// Set function for parsing recovered control flow primitives, which are
+	// located relative to the LLVM IR assembly file of the function.
+	funcPaths := make(map[*ir.Func]string)
+	for i, m := range ms {
+		for _, f := range m.Funcs {
+			funcPaths[f] = llPaths[i]
+		}
+	}
+	gen.Prims = func(f *ir.Func) ([]*primitive.Primitive, error) {
+		return parsePrims(funcPaths[f], f.Name())
 	}
-	return file, nil
+	files := gen.Decompile()
+	return files, errs
 }
 
 // parseModule parses the given LLVM IR assembly file into an LLVM IR module.
@@ -192,6 +248,63 @@ func outputGo(w io.Writer, file *ast.File) error {
 	return nil
 }
 
+// outputFiles outputs the given Go source files, corresponding to the given
+// LLVM IR assembly files. A single Go source file is written to the output path
+// if specified, and to standard output otherwise. Several Go source files are
+// written to the output directory if specified, and next to their LLVM IR
+// assembly files otherwise.
+func outputFiles(files []*ast.File, llPaths []string, output string) error {
+	if len(files) == 1 {
+		if len(output) > 0 {
+			return outputGoFile(output, files[0])
+		}
+		return outputGo(os.Stdout, files[0])
+	}
+	for i, file := range files {
+		goPath := pathutil.TrimExt(llPaths[i]) + ".go"
+		if len(output) > 0 {
+			goPath = filepath.Join(output, filepath.Base(goPath))
+		}
+		dbg.Printf("creating %q", goPath)
+		if err := outputGoFile(goPath, file); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// outputGoFile outputs the given Go source file, writing to goPath.
+func outputGoFile(goPath string, file *ast.File) error {
+	f, err := os.Create(goPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer f.Close()
+	return outputGo(f, file)
+}
+
+// outputReport outputs a JSON report of the given decompilation errors, writing
+// to reportPath.
+func outputReport(reportPath string, errs ErrorList) error {
+	var report []*decompile.Error
+	for _, err := range errs {
+		e, ok := err.(*decompile.Error)
+		if !ok {
+			e = &decompile.Error{Msg: err.Error()}
+		}
+		report = append(report, e)
+	}
+	buf, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	buf = append(buf, '\n')
+	if err := ioutil.WriteFile(reportPath, buf, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
 // ### [ Helper functions ] ####################################################
 
 // ErrorList is a list of zero or more errors.
@@ -19,11 +19,16 @@
 //   -method string
 //         control flow recovery method (hammock, interval, pattern-independent)
 //         (default "hammock")
+//   -normalize
+//         normalize control flow graph (prune unreachable nodes, add unique exit,
+//         split critical edges and add entry pre-header)
 //   -o string
 //         output path
 //   -q    suppress non-error messages
 //   -steps
 //         output intermediate steps
+//   -templates string
+//         directory of primitive templates (*.dot) located by the hammock method
 package main
 
 import (
@@ -82,19 +87,27 @@ func main() {
 		// method specifies the control flow recovery method (hammock, interval,
 		// pattern-independent).
 		method string
+		// normalize specifies whether to normalize the control flow graph before
+		// control flow recovery.
+		normalize bool
 		// output specifies the output path.
 		output string
 		// quiet specifies whether to suppress non-error messages.
 		quiet bool
 		// steps specifies whether to output intermediate steps.
 		steps bool
+		// templatesDir specifies the directory of primitive templates (*.dot)
+		// located by the hammock method.
+		templatesDir string
 	)
 	flag.BoolVar(&img, "img", false, "output image representation of graphs")
 	flag.BoolVar(&indent, "indent", false, "indent JSON output")
 	flag.StringVar(&method, "method", "hammock", "control flow recovery method (hammock, interval, pattern-independent)")
+	flag.BoolVar(&normalize, "normalize", false, "normalize control flow graph (prune unreachable nodes, add unique exit, split critical edges and add entry pre-header)")
 	flag.StringVar(&output, "o", "", "output path")
 	flag.BoolVar(&quiet, "q", false, "suppress non-error messages")
 	flag.BoolVar(&steps, "steps", false, "output intermediate steps")
+	flag.StringVar(&templatesDir, "templates", "", "directory of primitive templates (*.dot) located by the hammock method")
 	flag.Usage = usage
 	flag.Parse()
 	var dotPath string
@@ -114,7 +127,7 @@ func main() {
 	}
 
 	// Perform control flow analysis.
-	prims, err := restructure(dotPath, method, steps, img)
+	prims, err := restructure(dotPath, method, templatesDir, normalize, steps, img)
 	if err != nil {
 		log.Fatalf("%+v", err)
 	}
@@ -140,13 +153,20 @@ func main() {
 // single nodes until the entire graph is reduced into a single node or no
 // structured subgraphs may be located.
 //
+// The templatesDir argument specifies the directory of primitive templates
+// (*.dot) located by the hammock method, in addition to the built-in
+// primitives; or the empty string if none.
+//
+// The normalize argument specifies whether to normalize the control flow graph
+// before control flow recovery.
+//
 // The steps argument specifies whether to record the intermediate control flow
 // graphs at each step. The returned list of primitives is ordered in the same
 // sequence as they were located.
 //
 // img specifies whether to output image representations of the intermediate
 // control flow graphs.
-func restructure(dotPath, method string, steps, img bool) ([]*primitive.Primitive, error) {
+func restructure(dotPath, method, templatesDir string, normalize, steps, img bool) ([]*primitive.Primitive, error) {
 	var stepPrefix string
 	switch dotPath {
 	case "-":
@@ -155,45 +175,37 @@ func restructure(dotPath, method string, steps, img bool) ([]*primitive.Primitiv
 	default:
 		stepPrefix = pathutil.TrimExt(dotPath)
 	}
-	// Output intermediate steps in Graphviz DOT format.
+	// Record intermediate steps as copy-on-write snapshots, which are output in
+	// Graphviz DOT format once control flow analysis has completed.
 	var (
 		before func(g cfa.Graph, prim *primitive.Primitive)
 		after  func(g cfa.Graph, prim *primitive.Primitive)
+		ss     []*stepSnapshot
 	)
-	step := 1
 	if steps {
+		step := 1
 		before = func(g cfa.Graph, prim *primitive.Primitive) {
-			data := []byte(dotBeforeMerge(g, prim))
 			dbg.Printf("located primitive:\n%s", prim)
-			beforePath := fmt.Sprintf("%s_%04da.dot", stepPrefix, step)
-			dbg.Printf("creating file %q", beforePath)
-			if err := ioutil.WriteFile(beforePath, data, 0644); err != nil {
-				warn.Printf("unable to create %q; %v", beforePath, err)
-			}
-			// Store an image representation of the intermediate CFG if `-img` is
-			// set.
-			if img {
-				if err := outputImg(beforePath); err != nil {
-					warn.Println(err)
-				}
+			s := &stepSnapshot{
+				Snapshot: g.Snapshot(),
+				prim:     prim,
+				path:     fmt.Sprintf("%s_%04da.dot", stepPrefix, step),
 			}
+			ss = append(ss, s)
 		}
 		after = func(g cfa.Graph, prim *primitive.Primitive) {
-			data := []byte(dotAfterMerge(g, prim))
-			afterPath := fmt.Sprintf("%s_%04db.dot", stepPrefix, step)
-			dbg.Printf("creating file %q", afterPath)
-			if err := ioutil.WriteFile(afterPath, data, 0644); err != nil {
-				warn.Printf("unable to create %q; %v", afterPath, err)
-			}
-			// Store an image representation of the intermediate CFG if `-img` is
-			// set.
-			if img {
-				if err := outputImg(afterPath); err != nil {
-					warn.Println(err)
-				}
+			s := &stepSnapshot{
+				Snapshot: g.Snapshot(),
+				prim:     prim,
+				path:     fmt.Sprintf("%s_%04db.dot", stepPrefix, step),
+				merged:   true,
 			}
+			ss = append(ss, s)
 			step++
 		}
+		defer func() {
+			outputSteps(ss, img)
+		}()
 	}
 	// Recovery control flow primitives.
 	switch method {
@@ -203,8 +215,20 @@ func restructure(dotPath, method string, steps, img bool) ([]*primitive.Primitiv
 		if err := parseCFGInto(dotPath, g); err != nil {
 			return nil, errors.WithStack(err)
 		}
+		if err := prepareCFG(g, normalize); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// Load primitive templates.
+		var templates []*hammock.Template
+		if len(templatesDir) > 0 {
+			ts, err := hammock.LoadTemplates(templatesDir)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			templates = ts
+		}
 		// Perform control flow analysis.
-		prims, err := hammock.Analyze(g, before, after)
+		prims, err := hammock.AnalyzeTemplates(g, templates, before, after)
 		if err != nil {
 			if errors.Cause(err) == cfa.ErrIncomplete {
 				warn.Printf("warning: %v", err)
@@ -219,6 +243,9 @@ func restructure(dotPath, method string, steps, img bool) ([]*primitive.Primitiv
 		if err := parseCFGInto(dotPath, g); err != nil {
 			return nil, errors.WithStack(err)
 		}
+		if err := prepareCFG(g, normalize); err != nil {
+			return nil, errors.WithStack(err)
+		}
 		// Output derived sequence of graphs.
 		if steps {
 			Gs, IIs := interval.DerivedSequence(g)
@@ -245,6 +272,45 @@ func restructure(dotPath, method string, steps, img bool) ([]*primitive.Primitiv
 	}
 }
 
+// stepSnapshot is a snapshot of the intermediate control flow graph before or
+// after merging the nodes of a located primitive.
+type stepSnapshot struct {
+	// Copy-on-write snapshot of intermediate control flow graph.
+	*cfa.Snapshot
+	// Located primitive.
+	prim *primitive.Primitive
+	// Output path of intermediate step in Graphviz DOT format.
+	path string
+	// Specifies whether the snapshot was taken after merging the nodes of the
+	// primitive.
+	merged bool
+}
+
+// outputSteps outputs the intermediate steps in Graphviz DOT format, and as
+// image representations if img is set.
+func outputSteps(ss []*stepSnapshot, img bool) {
+	for _, s := range ss {
+		var data []byte
+		if s.merged {
+			data = []byte(dotAfterMerge(s.Graph(), s.prim))
+		} else {
+			data = []byte(dotBeforeMerge(s.Graph(), s.prim))
+		}
+		dbg.Printf("creating file %q", s.path)
+		if err := ioutil.WriteFile(s.path, data, 0644); err != nil {
+			warn.Printf("unable to create %q; %v", s.path, err)
+			continue
+		}
+		// Store an image representation of the intermediate CFG if `-img` is
+		// set.
+		if img {
+			if err := outputImg(s.path); err != nil {
+				warn.Println(err)
+			}
+		}
+	}
+}
+
 // dotBeforeMerge returns the intermediate graph g in Graphviz DOT format with
 // nodes before merge highlighted in red that are part of the located primitive.
 func dotBeforeMerge(g cfa.Graph, prim *primitive.Primitive) string {
@@ -321,6 +387,20 @@ func parseCFGInto(dotPath string, dst cfa.Graph) error {
 	}
 }
 
+// prepareCFG normalizes the control flow graph if normalize is set, and
+// reports problems of the control flow graph located by validation as warnings.
+func prepareCFG(g cfa.Graph, normalize bool) error {
+	if normalize {
+		if err := cfg.Normalize(g); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, diag := range cfg.Validate(g) {
+		warn.Printf("warning: %v", diag)
+	}
+	return nil
+}
+
 // outputJSON outputs the primitives in JSON format with optional indentation,
 // writing to w.
 func outputJSON(w io.Writer, prims []*primitive.Primitive, indent bool) error {
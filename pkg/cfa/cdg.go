@@ -0,0 +1,359 @@
+package cfa
+
+import (
+	"sort"
+
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
+	"github.com/rickypai/natsort"
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/encoding"
+)
+
+// exitID is the node ID of the virtual exit node of post-dominator trees.
+const exitID = -1
+
+// PostDominatorTree is a post-dominator tree of a control flow graph. To
+// handle control flow graphs with several exit nodes, the root of the tree is
+// a virtual exit node which succeeds every exit node. Nodes from which no exit
+// node is reachable (e.g. nodes of infinite loops) are treated as exit nodes.
+type PostDominatorTree struct {
+	// ipdom maps from node ID to the node ID of its immediate post-dominator;
+	// or exitID if immediately post-dominated by the virtual exit node.
+	ipdom map[int64]int64
+}
+
+// NewPostDom returns a new post-dominator tree based on the given control flow
+// graph.
+//
+// The post-dominator tree is computed by the iterative algorithm of Cooper,
+// Harvey and Kennedy on the reverse control flow graph.
+func NewPostDom(g Graph) PostDominatorTree {
+	// Exit nodes.
+	var exits []int64
+	for _, n := range sortedNodes(g) {
+		if g.From(n.ID()).Len() == 0 {
+			exits = append(exits, n.ID())
+		}
+	}
+	// preds returns the predecessors of the node with the given ID in the
+	// reverse control flow graph.
+	preds := func(id int64) []int64 {
+		var ids []int64
+		for succs := g.From(id); succs.Next(); {
+			ids = append(ids, succs.Node().ID())
+		}
+		return ids
+	}
+	// succs returns the successors of the node with the given ID in the reverse
+	// control flow graph.
+	succs := func(id int64) []int64 {
+		if id == exitID {
+			return exits
+		}
+		var ids []int64
+		for _, pred := range sortedNodesOf(g.To(id)) {
+			ids = append(ids, pred.ID())
+		}
+		return ids
+	}
+	// Post-order of the reverse control flow graph. Nodes not reachable from
+	// the virtual exit node are treated as exit nodes.
+	var order []int64
+	postNum := make(map[int64]int)
+	var visit func(id int64)
+	visit = func(id int64) {
+		postNum[id] = -1
+		for _, succ := range succs(id) {
+			if _, ok := postNum[succ]; !ok {
+				visit(succ)
+			}
+		}
+		postNum[id] = len(order)
+		order = append(order, id)
+	}
+	visit(exitID)
+	nodes := RevPostOrder(g)
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if id := nodes[i].ID(); !isVisited(postNum, id) {
+			exits = append(exits, id)
+			order = order[:len(order)-1]
+			delete(postNum, exitID)
+			visit(exitID)
+		}
+	}
+	// isExit reports whether the node with the given ID is an exit node.
+	isExit := make(map[int64]bool)
+	for _, id := range exits {
+		isExit[id] = true
+	}
+	// Compute immediate post-dominators.
+	ipdom := map[int64]int64{exitID: exitID}
+	intersect := func(a, b int64) int64 {
+		for a != b {
+			for postNum[a] < postNum[b] {
+				a = ipdom[a]
+			}
+			for postNum[b] < postNum[a] {
+				b = ipdom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		// Traverse in reverse post-order, skipping the virtual exit node.
+		for i := len(order) - 2; i >= 0; i-- {
+			id := order[i]
+			ps := preds(id)
+			if isExit[id] {
+				ps = append(ps, exitID)
+			}
+			newIPDom, ok := int64(0), false
+			for _, p := range ps {
+				if _, done := ipdom[p]; !done {
+					continue
+				}
+				if !ok {
+					newIPDom, ok = p, true
+					continue
+				}
+				newIPDom = intersect(p, newIPDom)
+			}
+			if prev, done := ipdom[id]; ok && (!done || prev != newIPDom) {
+				ipdom[id] = newIPDom
+				changed = true
+			}
+		}
+	}
+	return PostDominatorTree{ipdom: ipdom}
+}
+
+// isVisited reports whether the node with the given ID has been visited by the
+// post-order traversal.
+func isVisited(postNum map[int64]int, id int64) bool {
+	_, ok := postNum[id]
+	return ok
+}
+
+// PostDominatorOf returns the node ID of the immediate post-dominator of the
+// node with the given ID. The boolean return value is false if the node is
+// immediately post-dominated by the virtual exit node.
+func (pdom PostDominatorTree) PostDominatorOf(id int64) (int64, bool) {
+	ipdom, ok := pdom.ipdom[id]
+	if !ok || ipdom == exitID {
+		return 0, false
+	}
+	return ipdom, true
+}
+
+// PostDominates reports whether node x post-dominates y, with node IDs xid and
+// yid. Every node post-dominates itself.
+func (pdom PostDominatorTree) PostDominates(xid, yid int64) bool {
+	for id := yid; ; {
+		if id == xid {
+			return true
+		}
+		ipdom, ok := pdom.ipdom[id]
+		if !ok || ipdom == id {
+			return false
+		}
+		id = ipdom
+	}
+}
+
+// --- [ Control dependence ] --------------------------------------------------
+
+// ControlDep is a control dependence of a node on the outgoing edge of a
+// branching node.
+type ControlDep struct {
+	// Branching node.
+	From Node
+	// Successor of the branching node of the outgoing edge.
+	To Node
+	// Condition of the outgoing edge of the branching node; as recorded by its
+	// "cond" attribute.
+	Cond string
+}
+
+// ControlDeps computes the control dependence graph of src, storing it in the
+// empty control flow graph dst. The nodes of dst have the DOT node IDs of the
+// corresponding nodes of src, and an edge x -> y of dst with the "cond"
+// attribute c denotes that y is control dependent on x; i.e. that y executes
+// if the outgoing edge of x with condition c is taken. Nodes of dst without
+// predecessors execute whenever the function executes.
+//
+// Control dependences are computed from post-dominators (Ferrante, Ottenstein
+// and Warren); for each edge a -> b of src, the nodes on the path from b up to
+// (but not including) the immediate post-dominator of a in the post-dominator
+// tree are control dependent on a.
+//
+// The control dependence graph may be output in Graphviz DOT format by
+// dst.String.
+func ControlDeps(dst, src Graph) {
+	deps := NodeControlDeps(src)
+	dst.SetDOTID(src.DOTID())
+	nodes := make(map[int64]Node)
+	for _, n := range sortedNodes(src) {
+		nn := dst.NewNode().(Node)
+		nn.SetDOTID(n.DOTID())
+		dst.AddNode(nn)
+		nodes[n.ID()] = nn
+	}
+	if entry := src.Entry(); entry != nil {
+		dst.SetEntry(nodes[entry.ID()])
+	}
+	for _, n := range sortedNodes(src) {
+		for _, dep := range deps[n.ID()] {
+			e := dst.NewEdge(nodes[dep.From.ID()], nodes[n.ID()]).(Edge)
+			if len(dep.Cond) > 0 {
+				e.SetAttribute(encoding.Attribute{Key: "label", Value: dep.Cond})
+				e.SetAttribute(encoding.Attribute{Key: "cond", Value: dep.Cond})
+			}
+			dst.SetEdge(e)
+		}
+	}
+}
+
+// NodeControlDeps returns the control dependences of each node of the control flow
+// graph, indexed by node ID. The control dependences of each node are sorted
+// by DOT node ID of the branching node and condition.
+func NodeControlDeps(g Graph) map[int64][]ControlDep {
+	pdom := NewPostDom(g)
+	deps := make(map[int64][]ControlDep)
+	for _, a := range sortedNodes(g) {
+		succs := sortedNodesOf(g.From(a.ID()))
+		if len(succs) < 2 {
+			// Control dependences only arise from branching nodes.
+			continue
+		}
+		stop, ok := pdom.ipdom[a.ID()]
+		if !ok {
+			stop = exitID
+		}
+		for _, b := range succs {
+			e := g.Edge(a.ID(), b.ID()).(Edge)
+			conds := EdgeConds(e)
+			if len(conds) == 0 {
+				conds = []string{""}
+			}
+			for id := b.ID(); id != stop && id != exitID; {
+				for _, cond := range conds {
+					deps[id] = append(deps[id], ControlDep{From: a, To: b, Cond: cond})
+				}
+				next, ok := pdom.ipdom[id]
+				if !ok || next == id {
+					break
+				}
+				id = next
+			}
+		}
+	}
+	for _, ds := range deps {
+		less := func(i, j int) bool {
+			if ds[i].From.DOTID() != ds[j].From.DOTID() {
+				return natsort.Less(ds[i].From.DOTID(), ds[j].From.DOTID())
+			}
+			if ds[i].Cond != ds[j].Cond {
+				return natsort.Less(ds[i].Cond, ds[j].Cond)
+			}
+			return natsort.Less(ds[i].To.DOTID(), ds[j].To.DOTID())
+		}
+		sort.Slice(ds, less)
+	}
+	return deps
+}
+
+// --- [ Reaching conditions ] -------------------------------------------------
+
+// ReachingConds returns the reaching condition of each node of the control
+// flow graph, indexed by node ID. The reaching condition of a node is the
+// boolean condition -- over the conditions of the outgoing edges of branching
+// nodes -- under which the node executes; e.g.
+//
+//    ("A" AND NOT "B") OR "C" == "case (x=1)"
+//
+// The condition that the outgoing edge of node A is taken is the leaf "A" for
+// the true branch, NOT "A" for the false branch, and a leaf recording the
+// condition of the edge otherwise (e.g. a switch case). The reaching condition
+// of a node executing whenever the function executes is nil.
+//
+// Control dependences on nodes which in turn are (transitively) control
+// dependent on the node (i.e. loop back edges) are disregarded, as the
+// reaching condition describes the condition under which the node is first
+// executed.
+func ReachingConds(g Graph) map[int64]*primitive.Cond {
+	deps := NodeControlDeps(g)
+	conds := make(map[int64]*primitive.Cond)
+	done := make(map[int64]bool)
+	active := make(map[int64]bool)
+	var reachingCond func(n Node) *primitive.Cond
+	reachingCond = func(n Node) *primitive.Cond {
+		if done[n.ID()] {
+			return conds[n.ID()]
+		}
+		active[n.ID()] = true
+		var cond *primitive.Cond
+		seen := make(map[string]bool)
+		for _, dep := range deps[n.ID()] {
+			if active[dep.From.ID()] {
+				// Skip loop back dependences.
+				continue
+			}
+			term := edgeCond(dep)
+			if c := reachingCond(dep.From); c != nil {
+				term = primitive.And(c, term)
+			}
+			if s := term.String(); !seen[s] {
+				seen[s] = true
+				if cond == nil {
+					cond = term
+				} else {
+					cond = primitive.Or(cond, term)
+				}
+			}
+		}
+		delete(active, n.ID())
+		// Note, cond is nil if the node executes whenever the function executes.
+		conds[n.ID()] = cond
+		done[n.ID()] = true
+		return cond
+	}
+	for _, n := range sortedNodes(g) {
+		reachingCond(n)
+	}
+	return conds
+}
+
+// edgeCond returns the condition under which the outgoing edge of the branching
+// node of the given control dependence is taken. Edges without conditions are
+// identified by their successor.
+func edgeCond(dep ControlDep) *primitive.Cond {
+	from := dep.From.DOTID()
+	switch dep.Cond {
+	case "true":
+		return primitive.Leaf(from)
+	case "false":
+		return primitive.Not(primitive.Leaf(from))
+	case "":
+		return primitive.CaseLeaf(from, "-> "+dep.To.DOTID())
+	default:
+		return primitive.CaseLeaf(from, dep.Cond)
+	}
+}
+
+// sortedNodes returns the nodes of the control flow graph, in natural sort
+// order of their DOT node IDs.
+func sortedNodes(g Graph) []Node {
+	return sortedNodesOf(g.Nodes())
+}
+
+// sortedNodesOf returns the given nodes, in natural sort order of their DOT
+// node IDs.
+func sortedNodesOf(it graph.Nodes) []Node {
+	nodes := NodesOf(it)
+	less := func(i, j int) bool {
+		return natsort.Less(nodes[i].DOTID(), nodes[j].DOTID())
+	}
+	sort.Slice(nodes, less)
+	return nodes
+}
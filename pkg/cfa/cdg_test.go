@@ -0,0 +1,108 @@
+package cfa_test
+
+import (
+	"testing"
+
+	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/mewmew/lnp/pkg/cfg"
+)
+
+func TestReachingConds(t *testing.T) {
+	golden := []struct {
+		name string
+		in   string
+		// Reaching conditions, indexed by DOT node ID; "true" if the node
+		// executes whenever the function executes.
+		want map[string]string
+	}{
+		{
+			name: "diamond",
+			in: `digraph f {
+	A [entry=true]
+	A -> B [cond="true"]
+	A -> C [cond="false"]
+	B -> D
+	C -> D
+}`,
+			want: map[string]string{
+				"A": "true",
+				"B": `"A"`,
+				"C": `NOT "A"`,
+				"D": "true",
+			},
+		},
+		{
+			name: "loop",
+			in: `digraph f {
+	A [entry=true]
+	A -> B
+	B -> C [cond="true"]
+	B -> D [cond="false"]
+	C -> B
+}`,
+			want: map[string]string{
+				"A": "true",
+				// The loop back dependence of B on itself is disregarded.
+				"B": "true",
+				"C": `"B"`,
+				"D": "true",
+			},
+		},
+		{
+			name: "multi_exit",
+			in: `digraph f {
+	A [entry=true]
+	A -> B [cond="true"]
+	A -> C [cond="false"]
+	C -> D [cond="case (x=1)"]
+	C -> E [cond="default case"]
+}`,
+			want: map[string]string{
+				"A": "true",
+				"B": `"A"`,
+				"C": `NOT "A"`,
+				"D": `NOT "A" AND "C" == "case (x=1)"`,
+				"E": `NOT "A" AND "C" == "default case"`,
+			},
+		},
+		{
+			name: "unreachable_exit",
+			in: `digraph f {
+	A [entry=true]
+	A -> B [cond="true"]
+	A -> C [cond="false"]
+	B -> D
+	D -> B
+}`,
+			want: map[string]string{
+				"A": "true",
+				// Nodes of the infinite loop are treated as exit nodes.
+				"B": `"A"`,
+				"C": `NOT "A"`,
+				"D": `"A"`,
+			},
+		},
+	}
+	for _, g := range golden {
+		graph, err := cfg.ParseString(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse control flow graph; %+v", g.name, err)
+			continue
+		}
+		conds := cfa.ReachingConds(graph)
+		for _, n := range cfa.NodesOf(graph.Nodes()) {
+			got := "true"
+			if cond := conds[n.ID()]; cond != nil {
+				got = cond.String()
+			}
+			want, ok := g.want[n.DOTID()]
+			if !ok {
+				t.Errorf("%q: unable to locate reaching condition of node %q", g.name, n.DOTID())
+				continue
+			}
+			if want != got {
+				t.Errorf("%q: reaching condition mismatch of node %q; expected `%s`, got `%s`", g.name, n.DOTID(), want, got)
+			}
+		}
+	}
+}
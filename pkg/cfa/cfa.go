@@ -33,6 +33,11 @@ type Graph interface {
 	// String returns the string representation of the control flow graph in
 	// Graphviz DOT format.
 	fmt.Stringer
+	// Clone returns a deep copy of the control flow graph, including its nodes,
+	// edges and attributes.
+	Clone() Graph
+	// Snapshot returns a copy-on-write snapshot of the control flow graph.
+	Snapshot() *Snapshot
 }
 
 // Node is a node of a control flow graph and implements the graph.Node,
@@ -52,6 +57,31 @@ type Edge interface {
 	Attributes
 }
 
+// CondKey returns the attribute key of the i:th condition of an edge. An edge
+// merged from parallel edges (e.g. switch cases sharing a target) records the
+// condition of each merged edge in a separate attribute; "cond" for the first
+// condition, followed by "cond_1", "cond_2", etc.
+func CondKey(i int) string {
+	if i == 0 {
+		return "cond"
+	}
+	return fmt.Sprintf("cond_%d", i)
+}
+
+// EdgeConds returns the conditions of the given edge, as recorded by its "cond"
+// attributes. An edge merged from parallel edges (e.g. switch cases sharing a
+// target) records the condition of each merged edge.
+func EdgeConds(e Edge) []string {
+	var conds []string
+	for i := 0; ; i++ {
+		cond, ok := e.Attribute(CondKey(i))
+		if !ok || len(cond) == 0 {
+			return conds
+		}
+		conds = append(conds, cond)
+	}
+}
+
 // NodesOf returns it.Len() nodes from it. It is safe to pass a nil Nodes to
 // NodesOf.
 func NodesOf(it graph.Nodes) []Node {
@@ -1,26 +1,133 @@
 package cfa
 
+//go:generate stringer -linecomment -type EdgeKind
+
+// EdgeKind is the classification of an edge of the control flow graph, as
+// encountered during depth-first traversal.
+type EdgeKind uint8
+
+// Edge kinds.
+const (
+	// Edge to a node not yet visited; part of the depth-first spanning tree.
+	TreeEdge EdgeKind = iota // tree
+	// Edge to an ancestor in the depth-first spanning tree (or to the node
+	// itself); i.e. a loop back edge.
+	BackEdge // back
+	// Edge to a visited descendant in the depth-first spanning tree.
+	ForwardEdge // forward
+	// Edge to a visited node which is neither an ancestor nor a descendant in the
+	// depth-first spanning tree.
+	CrossEdge // cross
+)
+
+// Visitor holds the functions invoked during depth-first traversal of a control
+// flow graph. Nil functions are not invoked.
+type Visitor struct {
+	// Pre is invoked for each node during pre-order traversal.
+	Pre func(n Node)
+	// Post is invoked for each node during post-order traversal.
+	Post func(n Node)
+	// Edge is invoked for each edge from -> to, before the traversal continues
+	// to to in case of tree edges.
+	Edge func(from, to Node, kind EdgeKind)
+}
+
 // DFS performs a depth-first search of the control flow graph, starting at the
 // entry node. The functions pre and post are invoked if non-nil during pre- and
-// post-order traversal of the graph, respectively.
+// post-order traversal of the graph, respectively; including for the entry
+// node.
+//
+// Successors are visited in natural sort order of their DOT node IDs.
 func DFS(g Graph, pre, post func(n Node)) {
-	visited := make(map[int64]bool)
-	var visit func(n Node)
-	visit = func(n Node) {
-		visited[n.ID()] = true
-		for succs := g.From(n.ID()); succs.Next(); {
-			succ := succs.Node().(Node)
-			if visited[succ.ID()] {
-				continue
-			}
-			if pre != nil {
-				pre(succ)
+	Walk(g, Succs(g), Visitor{Pre: pre, Post: post})
+}
+
+// Walk performs a depth-first search of the control flow graph, starting at the
+// entry node, and invokes the functions of v for every node and edge reachable
+// from the entry node. The successors of each node are visited in the order
+// returned by succs.
+//
+// The traversal is iterative, using an explicit stack, and may therefore be
+// used on control flow graphs of arbitrary depth.
+func Walk(g Graph, succs func(n Node) []Node, v Visitor) {
+	// frame is a stack frame of the depth-first traversal.
+	type frame struct {
+		// Node being visited.
+		n Node
+		// Successors of the node.
+		succs []Node
+		// Index of the next successor to visit.
+		i int
+	}
+	// Pre-order visit number of visited nodes.
+	preNum := make(map[int64]int)
+	// Nodes on the stack; i.e. ancestors in the depth-first spanning tree of the
+	// node currently visited.
+	onStack := make(map[int64]bool)
+	var stack []*frame
+	push := func(n Node) {
+		preNum[n.ID()] = len(preNum)
+		onStack[n.ID()] = true
+		if v.Pre != nil {
+			v.Pre(n)
+		}
+		stack = append(stack, &frame{n: n, succs: succs(n)})
+	}
+	push(g.Entry())
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		if f.i >= len(f.succs) {
+			stack = stack[:len(stack)-1]
+			delete(onStack, f.n.ID())
+			if v.Post != nil {
+				v.Post(f.n)
 			}
-			visit(succ)
-			if post != nil {
-				post(succ)
+			continue
+		}
+		succ := f.succs[f.i]
+		f.i++
+		kind := TreeEdge
+		if num, ok := preNum[succ.ID()]; ok {
+			switch {
+			case onStack[succ.ID()]:
+				kind = BackEdge
+			case num > preNum[f.n.ID()]:
+				kind = ForwardEdge
+			default:
+				kind = CrossEdge
 			}
 		}
+		if v.Edge != nil {
+			v.Edge(f.n, succ, kind)
+		}
+		if kind == TreeEdge {
+			push(succ)
+		}
+	}
+}
+
+// Succs returns a function which returns the immediate successors of a node in
+// the control flow graph, in natural sort order of their DOT node IDs.
+func Succs(g Graph) func(n Node) []Node {
+	return func(n Node) []Node {
+		return sortedNodesOf(g.From(n.ID()))
+	}
+}
+
+// DFSOrder performs a depth-first search of the control flow graph, starting at
+// the entry node and visiting the successors of each node in the order returned
+// by succs. It returns the IDs of the nodes reachable from the entry node in
+// pre-order and reverse post-order, respectively.
+func DFSOrder(g Graph, succs func(n Node) []Node) (preOrder, revPostOrder []int64) {
+	pre := func(n Node) {
+		preOrder = append(preOrder, n.ID())
+	}
+	post := func(n Node) {
+		revPostOrder = append(revPostOrder, n.ID())
+	}
+	Walk(g, succs, Visitor{Pre: pre, Post: post})
+	for i, j := 0, len(revPostOrder)-1; i < j; i, j = i+1, j-1 {
+		revPostOrder[i], revPostOrder[j] = revPostOrder[j], revPostOrder[i]
 	}
-	visit(g.Entry())
+	return preOrder, revPostOrder
 }
@@ -0,0 +1,63 @@
+package cfa_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/mewmew/lnp/pkg/cfg"
+)
+
+func TestWalk(t *testing.T) {
+	const in = `
+digraph f {
+	A [entry=true]
+	A -> B
+	A -> C
+	A -> E
+	B -> C
+	B -> D
+	C -> A
+	D -> B
+	E -> D
+}`
+	g, err := cfg.ParseString(in[1:])
+	if err != nil {
+		t.Fatalf("unable to parse control flow graph; %+v", err)
+	}
+	var pre, post, edges []string
+	v := cfa.Visitor{
+		Pre: func(n cfa.Node) {
+			pre = append(pre, n.DOTID())
+		},
+		Post: func(n cfa.Node) {
+			post = append(post, n.DOTID())
+		},
+		Edge: func(from, to cfa.Node, kind cfa.EdgeKind) {
+			edges = append(edges, fmt.Sprintf("%s -> %s (%v)", from.DOTID(), to.DOTID(), kind))
+		},
+	}
+	cfa.Walk(g, cfa.Succs(g), v)
+	wantPre := []string{"A", "B", "C", "D", "E"}
+	if !reflect.DeepEqual(wantPre, pre) {
+		t.Errorf("pre-order mismatch; expected %q, got %q", wantPre, pre)
+	}
+	wantPost := []string{"C", "D", "B", "E", "A"}
+	if !reflect.DeepEqual(wantPost, post) {
+		t.Errorf("post-order mismatch; expected %q, got %q", wantPost, post)
+	}
+	wantEdges := []string{
+		"A -> B (tree)",
+		"B -> C (tree)",
+		"C -> A (back)",
+		"B -> D (tree)",
+		"D -> B (back)",
+		"A -> C (forward)",
+		"A -> E (tree)",
+		"E -> D (cross)",
+	}
+	if !reflect.DeepEqual(wantEdges, edges) {
+		t.Errorf("edge classification mismatch; expected %q, got %q", wantEdges, edges)
+	}
+}
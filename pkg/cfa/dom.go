@@ -1,24 +1,126 @@
 package cfa
 
 import (
+	"gonum.org/v1/gonum/graph"
 	"gonum.org/v1/gonum/graph/path"
 )
 
 // DominatorTree is a dominator tree of a control flow graph.
+//
+// The dominator tree may be updated in place by Merge as the nodes of single
+// entry regions of the control flow graph are merged.
 type DominatorTree struct {
-	path.DominatorTree
+	// idom maps from node ID to the immediate dominator of the node.
+	idom map[int64]graph.Node
+	// dominatedBy maps from node ID to the nodes immediately dominated by the
+	// node.
+	dominatedBy map[int64][]graph.Node
 }
 
 // NewDom returns a new dominator tree based on the given control flow graph.
 func NewDom(g Graph) DominatorTree {
 	tree := path.Dominators(g.Entry(), g)
-	return DominatorTree{
-		DominatorTree: tree,
+	dom := DominatorTree{
+		idom:        make(map[int64]graph.Node),
+		dominatedBy: make(map[int64][]graph.Node),
 	}
+	for nodes := g.Nodes(); nodes.Next(); {
+		n := nodes.Node()
+		idom := tree.DominatorOf(n.ID())
+		if idom == nil {
+			continue
+		}
+		dom.idom[n.ID()] = idom
+		dom.dominatedBy[idom.ID()] = append(dom.dominatedBy[idom.ID()], n)
+	}
+	return dom
 }
 
 // Dominates reports whether node x dominates y, with node IDs xid and yid.
 func (dom DominatorTree) Dominates(xid, yid int64) bool {
-	dominator := dom.DominatorTree.DominatorOf(yid)
+	dominator := dom.DominatorOf(yid)
 	return dominator != nil && dominator.ID() == xid
 }
+
+// DominatorOf returns the immediate dominator of the node with the given ID;
+// or nil if the node is the entry node or not present in the dominator tree.
+func (dom DominatorTree) DominatorOf(id int64) graph.Node {
+	if idom, ok := dom.idom[id]; ok {
+		return idom
+	}
+	return nil
+}
+
+// DominatedBy returns the nodes immediately dominated by the node with the
+// given ID.
+func (dom DominatorTree) DominatedBy(id int64) []graph.Node {
+	return dom.dominatedBy[id]
+}
+
+// Merge updates the dominator tree in place after the nodes with the given IDs
+// have been merged into the node n. The merged nodes must form a single entry
+// region, the entry of which has node ID entryID; i.e. every merged node must
+// be dominated by the entry node.
+//
+// The merged node inherits the immediate dominator of the entry node, and
+// immediately dominates every node previously immediately dominated by a merged
+// node (outside of the merged region). The boolean return value is false if the
+// merged nodes do not form a single entry region, in which case the dominator
+// tree is left unmodified and should be recomputed.
+func (dom DominatorTree) Merge(ids []int64, entryID int64, n graph.Node) bool {
+	region := make(map[int64]bool)
+	for _, id := range ids {
+		region[id] = true
+	}
+	if !region[entryID] {
+		return false
+	}
+	for _, id := range ids {
+		if !dom.dominatesTrans(entryID, id) {
+			return false
+		}
+	}
+	// Replace the entry node by the merged node in the list of nodes
+	// immediately dominated by its immediate dominator.
+	if idom, ok := dom.idom[entryID]; ok {
+		dom.idom[n.ID()] = idom
+		var children []graph.Node
+		for _, child := range dom.dominatedBy[idom.ID()] {
+			if child.ID() != entryID {
+				children = append(children, child)
+			}
+		}
+		dom.dominatedBy[idom.ID()] = append(children, n)
+	}
+	// Nodes immediately dominated by merged nodes are immediately dominated by
+	// the merged node.
+	var children []graph.Node
+	for _, id := range ids {
+		for _, child := range dom.dominatedBy[id] {
+			if region[child.ID()] {
+				continue
+			}
+			dom.idom[child.ID()] = n
+			children = append(children, child)
+		}
+	}
+	for _, id := range ids {
+		delete(dom.idom, id)
+		delete(dom.dominatedBy, id)
+	}
+	dom.dominatedBy[n.ID()] = children
+	return true
+}
+
+// dominatesTrans reports whether node x dominates y, with node IDs xid and yid;
+// not necessarily immediately. Every node dominates itself.
+func (dom DominatorTree) dominatesTrans(xid, yid int64) bool {
+	for id := yid; id != xid; {
+		idom, ok := dom.idom[id]
+		if !ok {
+			return false
+		}
+		id = idom.ID()
+	}
+	return true
+}
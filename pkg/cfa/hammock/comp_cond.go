@@ -0,0 +1,300 @@
+package hammock
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
+	"github.com/mewmew/lnp/pkg/cfg"
+	"github.com/rickypai/natsort"
+	"gonum.org/v1/gonum/graph"
+)
+
+// CompOp is the boolean operator of a compound condition.
+type CompOp uint8
+
+// Boolean operators of compound conditions.
+const (
+	// Short-circuit logical AND; B is evaluated only if A holds.
+	CompAnd CompOp = iota + 1
+	// Short-circuit logical OR; B is evaluated only if A does not hold.
+	CompOr
+)
+
+// CompCond represents a compound condition of two conditions, evaluated using
+// short-circuit evaluation.
+//
+// Pseudo-code:
+//
+//    if (A && B) {
+//       T
+//    } else {
+//       E
+//    }
+type CompCond struct {
+	// First condition node (A).
+	A cfa.Node
+	// Second condition node (B); contains only conditional and branching
+	// information (i.e. has the "condnode" attribute).
+	B cfa.Node
+	// Boolean operator of the compound condition.
+	Op CompOp
+	// Negate the condition of B.
+	NotB bool
+	// Target node taken if the compound condition holds (T).
+	True cfa.Node
+	// Target node taken if the compound condition does not hold (E).
+	False cfa.Node
+}
+
+// Prim returns a representation of the high-level control flow primitive, as a
+// mapping from control flow primitive node names to control flow graph node
+// names.
+//
+// Example mapping:
+//
+//    "a": "A"
+//    "b": "B"
+//
+// Example edges:
+//
+//    {"from": "A", "to": "T", "label": "true"}
+//    {"from": "A", "to": "E", "label": "false"}
+//
+// Example compound condition:
+//
+//    "A" AND "B"
+func (prim CompCond) Prim() *primitive.Primitive {
+	a, b := prim.A.DOTID(), prim.B.DOTID()
+	y := primitive.Leaf(b)
+	if prim.NotB {
+		y = primitive.Not(y)
+	}
+	cond := primitive.And(primitive.Leaf(a), y)
+	if prim.Op == CompOr {
+		cond = primitive.Or(primitive.Leaf(a), y)
+	}
+	return &primitive.Primitive{
+		Prim: prim.name(),
+		Nodes: map[string]string{
+			"a": a,
+			"b": b,
+		},
+		Entry: a,
+		// Outgoing edges of the merged compound condition, labelled by the
+		// outcome of the compound condition.
+		Edges: []primitive.Edge{
+			{From: a, To: prim.True.DOTID(), Label: "true"},
+			{From: a, To: prim.False.DOTID(), Label: "false"},
+		},
+		Cond: cond,
+	}
+}
+
+// name returns the name of the compound condition primitive; e.g.
+//
+//    "comp_cond_a_AND_NOT_b"
+func (prim CompCond) name() string {
+	op := "AND"
+	if prim.Op == CompOr {
+		op = "OR"
+	}
+	if prim.NotB {
+		op += "_NOT"
+	}
+	return fmt.Sprintf("comp_cond_a_%s_b", op)
+}
+
+// String returns a string representation of prim in Graphviz DOT format.
+//
+// Example output:
+//
+//    digraph comp_cond_a_AND_b {
+//       A -> B [label="true"]
+//       A -> E [label="false"]
+//       B -> T [label="true"]
+//       B -> E [label="false"]
+//    }
+func (prim CompCond) String() string {
+	a, b := prim.A.DOTID(), prim.B.DOTID()
+	t, e := prim.True.DOTID(), prim.False.DOTID()
+	// Targets of A and B when their conditions hold and do not hold.
+	aTrue, aFalse := b, e
+	if prim.Op == CompOr {
+		aTrue, aFalse = t, b
+	}
+	bTrue, bFalse := t, e
+	if prim.NotB {
+		bTrue, bFalse = e, t
+	}
+	const format = `
+digraph %[1]s {
+	%[2]s -> %[4]s [label="true"]
+	%[2]s -> %[5]s [label="false"]
+	%[3]s -> %[6]s [label="true"]
+	%[3]s -> %[7]s [label="false"]
+}`
+	return fmt.Sprintf(format[1:], prim.name(), a, b, aTrue, aFalse, bTrue, bFalse)
+}
+
+// FindCompCond returns the first occurrence of a compound condition in g, and
+// a boolean indicating if such a primitive was found.
+func FindCompCond(g graph.Directed, dom cfa.DominatorTree) (prim CompCond, ok bool) {
+	// Range through A node candidates.
+	for nodes := g.Nodes(); nodes.Next(); {
+		// Note: This run-time type assertion goes away, should Gonum graph start
+		// to leverage generics in Go2.
+		a := nodes.Node().(cfa.Node)
+		if prim, ok := findCompCondAt(g, dom, a); ok {
+			return prim, true
+		}
+	}
+	return CompCond{}, false
+}
+
+// findCompCondAt returns the compound condition with the given A node in g,
+// and a boolean indicating if such a primitive was found.
+func findCompCondAt(g graph.Directed, dom cfa.DominatorTree, a cfa.Node) (prim CompCond, ok bool) {
+	// Verify that A has two successors.
+	aSuccs := condSuccs(g, a)
+	if len(aSuccs) != 2 {
+		return CompCond{}, false
+	}
+	prim.A = a
+	// Try B as the true successor of A (AND), and then as the false successor
+	// of A (OR).
+	for i, op := range []CompOp{CompAnd, CompOr} {
+		b, other := aSuccs[i], aSuccs[1-i]
+		bSuccs := condSuccs(g, b)
+		if len(bSuccs) != 2 {
+			continue
+		}
+		prim.B = b
+		prim.Op = op
+		// The other successor of A is the target of either the true or the false
+		// branch of B. The condition of B is negated if the other successor of A
+		// is the target of the true branch of B for AND (and the false branch of
+		// B for OR).
+		for j := range bSuccs {
+			if bSuccs[j].ID() != other.ID() {
+				continue
+			}
+			target := bSuccs[1-j]
+			prim.NotB = (op == CompAnd) == (j == 0)
+			prim.True, prim.False = target, other
+			if op == CompOr {
+				prim.True, prim.False = other, target
+			}
+			if prim.IsValid(g, dom) {
+				return prim, true
+			}
+		}
+	}
+	return CompCond{}, false
+}
+
+// IsValid reports whether the A, B, True and False node candidates of prim form
+// a valid compound condition in g.
+//
+// Control flow graph (AND):
+//
+//    A
+//    ↓ ↘
+//    ↓   B
+//    ↓ ↙   ↘
+//    E       T
+//
+// Control flow graph (OR):
+//
+//    A
+//    ↓ ↘
+//    ↓   B
+//    ↓ ↙   ↘
+//    T       E
+func (prim CompCond) IsValid(g graph.Directed, dom cfa.DominatorTree) bool {
+	a, b, t, e := prim.A, prim.B, prim.True, prim.False
+	// Verify that the nodes are distinct, as edges from B to A may not be
+	// preserved when merging A and B.
+	ids := map[int64]bool{a.ID(): true, b.ID(): true, t.ID(): true, e.ID(): true}
+	if len(ids) != 4 {
+		return false
+	}
+	// Dominator sanity check.
+	if !dom.Dominates(a.ID(), b.ID()) {
+		return false
+	}
+	// Verify that A has two successors (B and either T or E).
+	if g.From(a.ID()).Len() != 2 || !g.HasEdgeFromTo(a.ID(), b.ID()) {
+		return false
+	}
+	// Verify that B has one predecessor (A) and two successors (T and E).
+	bPreds := g.To(b.ID())
+	bSuccs := g.From(b.ID())
+	if bPreds.Len() != 1 || bSuccs.Len() != 2 || !g.HasEdgeFromTo(b.ID(), t.ID()) || !g.HasEdgeFromTo(b.ID(), e.ID()) {
+		return false
+	}
+	// Verify that B contains only conditional and branching information, as the
+	// instructions of B are evaluated as part of the compound condition.
+	return isCondNode(b)
+}
+
+// setCompCondConds sets the conditions of the outgoing edges of the node merged
+// from the given compound condition primitive in g to the outcome of the
+// compound condition; as the conditions of the merged edges from A and B may
+// conflict (e.g. the false branch of A and the true branch of B for "A && !B").
+func setCompCondConds(g cfa.Graph, prim *primitive.Primitive) {
+	for _, e := range prim.Edges {
+		from, ok := g.NodeWithDOTID(e.From)
+		if !ok {
+			continue
+		}
+		to, ok := g.NodeWithDOTID(e.To)
+		if !ok {
+			continue
+		}
+		if ee, ok := g.Edge(from.ID(), to.ID()).(cfa.Edge); ok {
+			cfg.SetConds(ee, []string{e.Label})
+		}
+	}
+}
+
+// isCondNode reports whether the given node contains only conditional and
+// branching information, as recorded by its "condnode" attribute.
+func isCondNode(n cfa.Node) bool {
+	v, ok := n.Attribute("condnode")
+	return ok && v == "true"
+}
+
+// condSuccs returns the successors of the given node in g, ordered such that
+// the target of the true branch precedes the target of the false branch. Nodes
+// of edges without true or false conditions are sorted by DOT node ID, to make
+// output deterministic.
+func condSuccs(g graph.Directed, n cfa.Node) []cfa.Node {
+	succs := cfa.NodesOf(g.From(n.ID()))
+	// rank returns the sort rank of the given successor.
+	rank := func(succ cfa.Node) int {
+		// Note: This run-time type assertion goes away, should Gonum graph start
+		// to leverage generics in Go2.
+		e := g.Edge(n.ID(), succ.ID()).(cfa.Edge)
+		conds := cfa.EdgeConds(e)
+		if len(conds) == 1 {
+			switch conds[0] {
+			case "true":
+				return 0
+			case "false":
+				return 1
+			}
+		}
+		return 2
+	}
+	less := func(i, j int) bool {
+		ri, rj := rank(succs[i]), rank(succs[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return natsort.Less(succs[i].DOTID(), succs[j].DOTID())
+	}
+	sort.Slice(succs, less)
+	return succs
+}
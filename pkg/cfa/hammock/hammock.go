@@ -7,11 +7,18 @@
 // of the subgraph are collapsed into a single node, and the process is
 // repreated, either until the control flow graph contains a single node, or no
 // more subgraph isomorphisms may be located.
+//
+// The cannonical subgraphs of the built-in primitives are specified by
+// data-driven primitive templates in Graphviz DOT format (see Template), and
+// additional primitives may be located by loading further templates.
 package hammock
 
 import (
+	"strings"
+
 	"github.com/mewmew/lnp/pkg/cfa"
 	"github.com/mewmew/lnp/pkg/cfa/primitive"
+	"github.com/mewmew/lnp/pkg/cfg"
 	"github.com/pkg/errors"
 )
 
@@ -20,11 +27,62 @@ import (
 // are invoked if non-nil before and after merging the nodes of located
 // primitives.
 func Analyze(g cfa.Graph, before, after func(g cfa.Graph, prim *primitive.Primitive)) ([]*primitive.Primitive, error) {
+	return AnalyzeTemplates(g, nil, before, after)
+}
+
+// AnalyzeTemplates analyzes the given control flow graph and returns the list
+// of recovered high-level control flow primitives, locating the primitives of
+// the given templates in addition to the built-in primitives. Built-in
+// primitives take precedence, followed by templates in the order given. The
+// before and after functions are invoked if non-nil before and after merging
+// the nodes of located primitives.
+//
+// AnalyzeTemplates maintains a worklist of candidate entry nodes for each kind
+// of primitive, and only re-examines the nodes in the vicinity of the node last
+// merged; the dominator tree is updated incrementally as nodes are merged.
+//
+// Unwind edges are excluded from the analysis, and landing pads are structured
+// as separate regions (see cfg.Regions).
+func AnalyzeTemplates(g cfa.Graph, templates []*Template, before, after func(g cfa.Graph, prim *primitive.Primitive)) ([]*primitive.Primitive, error) {
+	fs := append([]finder{}, finders...)
+	// Built-in primitives span at most two edges from their entry node.
+	radius := 2
+	for _, t := range templates {
+		fs = append(fs, t.FindAt)
+		if t.depth > radius {
+			radius = t.depth
+		}
+	}
 	prims := []*primitive.Primitive{}
+	incomplete := false
+	for _, region := range cfg.Regions(g) {
+		ps, err := analyzeRegion(region, fs, radius, before, after)
+		prims = append(prims, ps...)
+		switch {
+		case err == cfa.ErrIncomplete:
+			incomplete = true
+		case err != nil:
+			return nil, errors.WithStack(err)
+		}
+	}
+	if incomplete {
+		// Return partial results and signal incomplete control flow recovery.
+		return prims, cfa.ErrIncomplete
+	}
+	return prims, nil
+}
+
+// analyzeRegion analyzes the given single-entry region of a control flow graph
+// and returns the list of recovered high-level control flow primitives, using
+// the given primitive finders, which locate primitives spanning at most radius
+// edges from their entry node.
+func analyzeRegion(g cfa.Graph, fs []finder, radius int, before, after func(g cfa.Graph, prim *primitive.Primitive)) ([]*primitive.Primitive, error) {
+	prims := []*primitive.Primitive{}
+	dom := cfa.NewDom(g)
+	work := newWorklist(g, fs, radius)
 	for {
 		// Locate control flow primitive.
-		dom := cfa.NewDom(g)
-		prim, ok := FindPrim(g, dom)
+		prim, ok := work.findPrim(g, dom)
 		if !ok {
 			break
 		}
@@ -32,12 +90,39 @@ func Analyze(g cfa.Graph, before, after func(g cfa.Graph, prim *primitive.Primit
 		if before != nil {
 			before(g, prim)
 		}
+		// Record node IDs of located primitive before merge.
+		var ids []int64
+		var entryID int64
+		for _, dotID := range prim.Nodes {
+			n, ok := g.NodeWithDOTID(dotID)
+			if !ok {
+				return nil, errors.Errorf("unable to locate node with DOT node ID %q in control flow graph %q", dotID, g.DOTID())
+			}
+			ids = append(ids, n.ID())
+			if dotID == prim.Entry {
+				entryID = n.ID()
+			}
+		}
 		// Merge nodes of located primitive.
 		newG, err := cfa.Merge(g, prim)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
 		g = newG
+		if strings.HasPrefix(prim.Prim, "comp_cond_") {
+			setCompCondConds(g, prim)
+		}
+		n, ok := g.NodeWithDOTID(prim.Entry)
+		if !ok {
+			return nil, errors.Errorf("unable to locate merged node with DOT node ID %q in control flow graph %q", prim.Entry, g.DOTID())
+		}
+		// Update dominator tree and worklist.
+		if !dom.Merge(ids, entryID, n) {
+			dom = cfa.NewDom(g)
+			work = newWorklist(g, fs, radius)
+		} else {
+			work.touch(g, n)
+		}
 		if after != nil {
 			after(g, prim)
 		}
@@ -52,29 +137,14 @@ func Analyze(g cfa.Graph, before, after func(g cfa.Graph, prim *primitive.Primit
 // FindPrim returns the first occurrence of a high-level control flow primitive
 // in g, and a boolean indicating if such a primitive was found.
 func FindPrim(g cfa.Graph, dom cfa.DominatorTree) (*primitive.Primitive, bool) {
-	// Locate sequences of two statements.
-	if prim, ok := FindSeq(g, dom); ok {
-		return prim.Prim(), true
-	}
-	// Locate pre-test loops.
-	if prim, ok := FindPreLoop(g, dom); ok {
-		return prim.Prim(), true
-	}
-	// Locate post-test loops.
-	if prim, ok := FindPostLoop(g, dom); ok {
-		return prim.Prim(), true
-	}
-	// Locate 1-way conditionals.
-	if prim, ok := FindIf(g, dom); ok {
-		return prim.Prim(), true
-	}
-	// Locate 1-way conditionals with a body return statements.
-	if prim, ok := FindIfReturn(g, dom); ok {
-		return prim.Prim(), true
-	}
-	// Locate 2-way conditionals.
-	if prim, ok := FindIfElse(g, dom); ok {
-		return prim.Prim(), true
+	nodes := cfa.NodesOf(g.Nodes())
+	sortNodes(nodes)
+	for _, find := range finders {
+		for _, entry := range nodes {
+			if prim, ok := find(g, dom, entry); ok {
+				return prim, true
+			}
+		}
 	}
 	// TODO: Locate n-way conditionals.
 	//if prim, ok := FindSwitch(g, dom); ok {
@@ -0,0 +1,604 @@
+package hammock
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
+	"github.com/mewmew/lnp/pkg/cfg"
+)
+
+// sizes specifies the number of basic blocks of the synthetic control flow
+// graphs used in benchmarks.
+var sizes = []int{100, 1000, 5000}
+
+func TestFindPrim(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		// Sequence of two statements.
+		{
+			in: `digraph f {
+	A [entry=true]
+	A -> B
+}`,
+			want: `
+prim: seq
+nodes:
+   entry: A
+   exit: B
+entry: A
+exit: B`,
+		},
+		// Pre-test loop.
+		{
+			in: `digraph f {
+	A [entry=true]
+	A -> B [cond="true"]
+	A -> C [cond="false"]
+	B -> A
+}`,
+			want: `
+prim: pre_loop
+nodes:
+   body: B
+   cond: A
+   exit: C
+entry: A
+exit: C`,
+		},
+		// Post-test loop.
+		{
+			in: `digraph f {
+	A [entry=true]
+	A -> A [cond="true"]
+	A -> B [cond="false"]
+}`,
+			want: `
+prim: post_loop
+nodes:
+   cond: A
+   exit: B
+entry: A
+exit: B`,
+		},
+		// 1-way conditional.
+		{
+			in: `digraph f {
+	A [entry=true]
+	A -> B [cond="true"]
+	A -> C [cond="false"]
+	B -> C
+}`,
+			want: `
+prim: if
+nodes:
+   body: B
+   cond: A
+   exit: C
+entry: A
+exit: C`,
+		},
+		// 1-way conditional with a body return statement.
+		{
+			in: `digraph f {
+	A [entry=true]
+	A -> B [cond="true"]
+	A -> C [cond="false"]
+}`,
+			want: `
+prim: if_return
+nodes:
+   body: B
+   cond: A
+   exit: C
+entry: A
+exit: C`,
+		},
+		// 2-way conditional; body_true is the target of the true branch.
+		{
+			in: `digraph f {
+	A [entry=true]
+	A -> C [cond="true"]
+	A -> B [cond="false"]
+	B -> D
+	C -> D
+}`,
+			want: `
+prim: if_else
+nodes:
+   body_false: B
+   body_true: C
+   cond: A
+   exit: D
+entry: A
+exit: D`,
+		},
+		// 2-way conditional without edge conditions.
+		{
+			in: `digraph f {
+	A [entry=true]
+	A -> C
+	A -> B
+	B -> D
+	C -> D
+}`,
+			want: `
+prim: if_else
+nodes:
+   body_false: C
+   body_true: B
+   cond: A
+   exit: D
+entry: A
+exit: D`,
+		},
+	}
+	for _, g := range golden {
+		in := cfg.NewGraph()
+		if err := cfg.ParseStringInto(g.in, in); err != nil {
+			t.Errorf("unable to parse control flow graph; %v", err)
+			continue
+		}
+		prim, ok := FindPrim(in, cfa.NewDom(in))
+		if !ok {
+			t.Errorf("unable to locate primitive in control flow graph\n%s", g.in)
+			continue
+		}
+		want := g.want[1:]
+		if got := prim.String(); got != want {
+			t.Errorf("primitive mismatch of control flow graph\n%s\n\nexpected\n%s\n\ngot\n%s", g.in, want, got)
+		}
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	// User-defined template of 3-way conditionals.
+	const src = `digraph switch_3 {
+	cond [entry=true]
+	exit [exit=true]
+	cond -> case_1
+	cond -> case_2
+	cond -> case_3
+	case_1 -> exit
+	case_2 -> exit
+	case_3 -> exit
+}`
+	tmpl, err := ParseTemplate(src)
+	if err != nil {
+		t.Fatalf("unable to parse template; %v", err)
+	}
+	const in = `digraph f {
+	A [entry=true]
+	A -> B [cond="case 1"]
+	A -> C [cond="case 2"]
+	A -> D [cond="case 3"]
+	B -> E
+	C -> E
+	D -> E
+}`
+	g := cfg.NewGraph()
+	if err := cfg.ParseStringInto(in, g); err != nil {
+		t.Fatalf("unable to parse control flow graph; %v", err)
+	}
+	prims, err := AnalyzeTemplates(g, []*Template{tmpl}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to analyze control flow graph; %v", err)
+	}
+	var got []string
+	for _, prim := range prims {
+		got = append(got, prim.Prim)
+	}
+	want := []string{"switch_3"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("primitive mismatch; expected %q, got %q", want, got)
+	}
+	// Edge conditions of templates are restricted to true and false.
+	const invalid = `digraph invalid {
+	cond [entry=true]
+	cond -> exit [cond="case 1"]
+}`
+	if _, err := ParseTemplate(invalid); err == nil {
+		t.Errorf("expected error for invalid edge condition of template, got none")
+	}
+}
+
+func TestCompCond(t *testing.T) {
+	golden := []struct {
+		in   string
+		want []string
+	}{
+		// A && B
+		{
+			in: `digraph f {
+	A [entry=true]
+	B [condnode=true]
+	A -> B [cond="true"]
+	A -> E [cond="false"]
+	B -> T [cond="true"]
+	B -> E [cond="false"]
+	T -> X
+	E -> X
+}`,
+			want: []string{`comp_cond_a_AND_b: "A" AND "B"`, "if_else"},
+		},
+		// A || !B
+		{
+			in: `digraph f {
+	A [entry=true]
+	B [condnode=true]
+	A -> T [cond="true"]
+	A -> B [cond="false"]
+	B -> E [cond="true"]
+	B -> T [cond="false"]
+	T -> X
+	E -> X
+}`,
+			want: []string{`comp_cond_a_OR_NOT_b: "A" OR NOT "B"`, "if_else"},
+		},
+		// (A && !B) && C
+		{
+			in: `digraph f {
+	A [entry=true]
+	B [condnode=true]
+	C [condnode=true]
+	A -> B [cond="true"]
+	A -> E [cond="false"]
+	B -> E [cond="true"]
+	B -> C [cond="false"]
+	C -> T [cond="true"]
+	C -> E [cond="false"]
+	T -> X
+	E -> X
+}`,
+			want: []string{`comp_cond_a_AND_NOT_b: "A" AND NOT "B"`, `comp_cond_a_AND_b: "A" AND "C"`, "if_else"},
+		},
+		// B does not contain only conditional and branching information.
+		{
+			in: `digraph f {
+	A [entry=true]
+	A -> B [cond="true"]
+	A -> E [cond="false"]
+	B -> T [cond="true"]
+	B -> E [cond="false"]
+	T -> X
+	E -> X
+}`,
+			want: nil,
+		},
+	}
+	for _, g := range golden {
+		in := cfg.NewGraph()
+		if err := cfg.ParseStringInto(g.in, in); err != nil {
+			t.Errorf("unable to parse control flow graph; %v", err)
+			continue
+		}
+		prims, _ := Analyze(in, nil, nil)
+		var got []string
+		for _, prim := range prims {
+			if prim.Prim == "seq" {
+				continue
+			}
+			if prim.Cond != nil {
+				got = append(got, fmt.Sprintf("%s: %v", prim.Prim, prim.Cond))
+				continue
+			}
+			got = append(got, prim.Prim)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(g.want) {
+			t.Errorf("primitive mismatch of control flow graph\n%s\n\nexpected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
+
+func TestUnwindRegions(t *testing.T) {
+	const in = `digraph f {
+	A [entry=true]
+	A -> B [cond="normal"]
+	A -> L [cond="unwind"]
+	B -> C [cond="true"]
+	B -> D [cond="false"]
+	C -> D
+	L -> R
+}`
+	// Nodes of each region; the landing pad L is structured separately.
+	regions := map[string]int{"A": 0, "B": 0, "C": 0, "D": 0, "L": 1, "R": 1}
+	g := cfg.NewGraph()
+	if err := cfg.ParseStringInto(in, g); err != nil {
+		t.Fatalf("unable to parse control flow graph; %v", err)
+	}
+	prims, err := Analyze(g, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to analyze control flow graph; %v", err)
+	}
+	landingPad := false
+	for _, prim := range prims {
+		region := regions[prim.Entry]
+		for _, dotID := range prim.Nodes {
+			if regions[dotID] != region {
+				t.Errorf("primitive %q with entry %q spans several regions; nodes %v", prim.Prim, prim.Entry, prim.Nodes)
+			}
+		}
+		if prim.Entry == "L" {
+			landingPad = true
+		}
+	}
+	if !landingPad {
+		t.Errorf("landing pad region not structured; primitives %v", prims)
+	}
+}
+
+func TestAnalyzeRescan(t *testing.T) {
+	golden := []struct {
+		name string
+		g    func() (cfa.Graph, error)
+	}{
+		{
+			name: "synthetic",
+			g: func() (cfa.Graph, error) {
+				return syntheticCFG(100), nil
+			},
+		},
+		{
+			name: "sample",
+			g: func() (cfa.Graph, error) {
+				return cfg.ParseFile("../interval/testdata/sample.dot")
+			},
+		},
+		{
+			name: "cidom",
+			g: func() (cfa.Graph, error) {
+				return cfg.ParseFile("../interval/testdata/cidom.dot")
+			},
+		},
+	}
+	for _, g := range golden {
+		// Analyze and analyzeRescan merge the nodes of the graph in place.
+		in1, err := g.g()
+		if err != nil {
+			t.Errorf("%q: unable to parse control flow graph; %+v", g.name, err)
+			continue
+		}
+		in2, err := g.g()
+		if err != nil {
+			t.Errorf("%q: unable to parse control flow graph; %+v", g.name, err)
+			continue
+		}
+		want, wantErr := analyzeRescan(in1)
+		got, gotErr := Analyze(in2, nil, nil)
+		if wantErr != gotErr {
+			t.Errorf("%q: error mismatch; expected %v, got %v", g.name, wantErr, gotErr)
+			continue
+		}
+		// The order in which primitives are located differs, and so does the
+		// grouping of sequences; compare the recovered structure.
+		if structure(want) != structure(got) {
+			t.Errorf("%q: structure mismatch; expected\n%s\ngot\n%s", g.name, structure(want), structure(got))
+		}
+	}
+}
+
+// structNode is a node of the tree of nested primitives recovered from a
+// control flow graph.
+type structNode struct {
+	// Primitive name; or empty if basic block.
+	prim string
+	// DOT node ID of basic block.
+	dotID string
+	// Node names of the primitive, in sorted order; or nil if sequence.
+	names []string
+	// Nodes of the primitive, in order of node names; or sequence elements.
+	nodes []*structNode
+}
+
+// String returns the string representation of the tree of nested primitives;
+// e.g.
+//
+//    seq(if(body: B2, cond: B1), B3)
+func (n *structNode) String() string {
+	if len(n.prim) == 0 {
+		return n.dotID
+	}
+	var args []string
+	for i, nn := range n.nodes {
+		if n.names != nil {
+			args = append(args, fmt.Sprintf("%s: %v", n.names[i], nn))
+		} else {
+			args = append(args, nn.String())
+		}
+	}
+	return fmt.Sprintf("%s(%s)", n.prim, strings.Join(args, ", "))
+}
+
+// newSeq returns a sequence of the given nodes, flattening nested sequences.
+func newSeq(nodes ...*structNode) *structNode {
+	seq := &structNode{prim: "seq"}
+	for _, n := range nodes {
+		if n.prim == "seq" {
+			seq.nodes = append(seq.nodes, n.nodes...)
+		} else {
+			seq.nodes = append(seq.nodes, n)
+		}
+	}
+	return seq
+}
+
+// structure returns the structure of the control flow graph recovered by the
+// given sequence of primitives, as the trees of nested primitives rooted at the
+// merged nodes. The trees are canonicalized such that the structure does not
+// depend on the order in which primitives are located; sequences are
+// flattened, the exit node of a primitive is moved after the primitive, and
+// statements preceding the entry node of a primitive are moved before the
+// primitive.
+func structure(prims []*primitive.Primitive) string {
+	roots := make(map[string]*structNode)
+	node := func(dotID string) *structNode {
+		if n, ok := roots[dotID]; ok {
+			return n
+		}
+		return &structNode{dotID: dotID}
+	}
+	for _, prim := range prims {
+		var n *structNode
+		if prim.Prim == "seq" {
+			n = newSeq(node(prim.Nodes["entry"]), node(prim.Nodes["exit"]))
+		} else {
+			n = &structNode{prim: prim.Prim}
+			var names []string
+			for name := range prim.Nodes {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			var before, after []*structNode
+			for _, name := range names {
+				nn := node(prim.Nodes[name])
+				switch {
+				case name == "exit":
+					after = append(after, nn)
+					continue
+				case prim.Nodes[name] == prim.Entry && nn.prim == "seq":
+					before = nn.nodes[:len(nn.nodes)-1]
+					nn = nn.nodes[len(nn.nodes)-1]
+				}
+				n.names = append(n.names, name)
+				n.nodes = append(n.nodes, nn)
+			}
+			if len(before) > 0 || len(after) > 0 {
+				n = newSeq(append(append(before, n), after...)...)
+			}
+		}
+		for _, dotID := range prim.Nodes {
+			delete(roots, dotID)
+		}
+		roots[prim.Entry] = n
+	}
+	var trees []string
+	for _, n := range roots {
+		trees = append(trees, n.String())
+	}
+	sort.Strings(trees)
+	return strings.Join(trees, "\n")
+}
+
+func BenchmarkAnalyze(b *testing.B) {
+	for _, size := range sizes {
+		b.Run(fmt.Sprintf("blocks=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				g := syntheticCFG(size)
+				b.StartTimer()
+				if _, err := Analyze(g, nil, nil); err != nil {
+					b.Fatalf("unable to analyze control flow graph of %d blocks; %v", size, err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAnalyzeRescan(b *testing.B) {
+	for _, size := range sizes {
+		b.Run(fmt.Sprintf("blocks=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				g := syntheticCFG(size)
+				b.StartTimer()
+				if _, err := analyzeRescan(g); err != nil {
+					b.Fatalf("unable to analyze control flow graph of %d blocks; %v", size, err)
+				}
+			}
+		})
+	}
+}
+
+// analyzeRescan analyzes the given control flow graph by recomputing the
+// dominator tree and rescanning every node after each merge; as done by Analyze
+// prior to maintaining a worklist and updating the dominator tree
+// incrementally. It is used as baseline in benchmarks.
+func analyzeRescan(g cfa.Graph) ([]*primitive.Primitive, error) {
+	var prims []*primitive.Primitive
+	for {
+		dom := cfa.NewDom(g)
+		prim, ok := FindPrim(g, dom)
+		if !ok {
+			break
+		}
+		prims = append(prims, prim)
+		newG, err := cfa.Merge(g, prim)
+		if err != nil {
+			return nil, err
+		}
+		g = newG
+	}
+	if g.Nodes().Len() > 1 {
+		return prims, cfa.ErrIncomplete
+	}
+	return prims, nil
+}
+
+// syntheticCFG returns a synthetic control flow graph of (at least) the given
+// number of basic blocks, consisting of a sequence of 2-way conditionals, 1-way
+// conditionals, pre-test loops, post-test loops and loops with several exits.
+func syntheticCFG(size int) cfa.Graph {
+	g := cfg.NewGraph()
+	newNode := func() cfa.Node {
+		n := g.NewNode().(cfa.Node)
+		n.SetDOTID(fmt.Sprintf("B%d", g.Nodes().Len()))
+		g.AddNode(n)
+		return n
+	}
+	edge := func(from, to cfa.Node) {
+		g.SetEdge(g.NewEdge(from, to))
+	}
+	entry := newNode()
+	g.SetEntry(entry)
+	cur := entry
+	for i := 0; g.Nodes().Len() < size; i++ {
+		switch i % 5 {
+		case 0:
+			// 2-way conditional.
+			bodyTrue, bodyFalse, exit := newNode(), newNode(), newNode()
+			edge(cur, bodyTrue)
+			edge(cur, bodyFalse)
+			edge(bodyTrue, exit)
+			edge(bodyFalse, exit)
+			cur = exit
+		case 1:
+			// 1-way conditional.
+			body, exit := newNode(), newNode()
+			edge(cur, body)
+			edge(cur, exit)
+			edge(body, exit)
+			cur = exit
+		case 2:
+			// Pre-test loop.
+			cond, body, exit := newNode(), newNode(), newNode()
+			edge(cur, cond)
+			edge(cond, body)
+			edge(body, cond)
+			edge(cond, exit)
+			cur = exit
+		case 3:
+			// Post-test loop.
+			cond, exit := newNode(), newNode()
+			edge(cur, cond)
+			edge(cond, cond)
+			edge(cond, exit)
+			cur = exit
+		case 4:
+			// Loop with several exits.
+			head, body, latch, exit := newNode(), newNode(), newNode(), newNode()
+			edge(cur, head)
+			edge(head, body)
+			edge(head, exit)
+			edge(body, latch)
+			edge(body, exit)
+			edge(latch, head)
+			cur = exit
+		}
+	}
+	return g
+}
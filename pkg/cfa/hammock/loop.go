@@ -0,0 +1,250 @@
+package hammock
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
+	"gonum.org/v1/gonum/graph"
+)
+
+// Loop represents an endless loop, or a loop with several exits to a common
+// follow node (i.e. loops with break statements).
+//
+// Pseudo-code:
+//
+//    for {
+//       A
+//       if (x) {
+//          break
+//       }
+//       B
+//       if (y) {
+//          continue
+//       }
+//       C
+//    }
+//    D
+type Loop struct {
+	// Loop header node (A).
+	Head cfa.Node
+	// Body nodes (B and C), in order of execution.
+	Body []cfa.Node
+	// Follow node (D); or nil for endless loops.
+	Exit cfa.Node
+	// Break edges; from loop nodes to the follow node.
+	Breaks []cfa.Edge
+	// Continue edges; from loop nodes except the latch (i.e. last loop node) to
+	// the loop header.
+	Continues []cfa.Edge
+}
+
+// Prim returns a representation of the high-level control flow primitive, as a
+// mapping from control flow primitive node names to control flow graph node
+// names.
+//
+// Example mapping:
+//
+//    "head":   "A"
+//    "body_1": "B"
+//    "body_2": "C"
+//    "exit":   "D"
+//
+// Example edges:
+//
+//    {"from": "A", "to": "D", "label": "break"}
+//    {"from": "B", "to": "A", "label": "continue"}
+func (prim Loop) Prim() *primitive.Primitive {
+	head := prim.Head.DOTID()
+	p := &primitive.Primitive{
+		Prim: "inf_loop",
+		Nodes: map[string]string{
+			"head": head,
+		},
+		Entry: head,
+	}
+	for i, body := range prim.Body {
+		p.Nodes[fmt.Sprintf("body_%d", i+1)] = body.DOTID()
+	}
+	if prim.Exit != nil {
+		exit := prim.Exit.DOTID()
+		p.Prim = "multi_exit_loop"
+		p.Nodes["exit"] = exit
+		p.Exit = exit
+	}
+	for _, e := range prim.Breaks {
+		p.Edges = append(p.Edges, newEdge(e, "break"))
+	}
+	for _, e := range prim.Continues {
+		p.Edges = append(p.Edges, newEdge(e, "continue"))
+	}
+	return p
+}
+
+// newEdge returns a new annotated primitive edge based on the given control
+// flow graph edge.
+func newEdge(e cfa.Edge, label string) primitive.Edge {
+	return primitive.Edge{
+		From:  e.From().(cfa.Node).DOTID(),
+		To:    e.To().(cfa.Node).DOTID(),
+		Label: label,
+	}
+}
+
+// String returns a string representation of prim in Graphviz DOT format.
+//
+// Example output:
+//
+//    digraph multi_exit_loop {
+//       A -> B
+//       B -> C
+//       C -> A
+//       A -> D [label="break"]
+//       B -> A [label="continue"]
+//    }
+func (prim Loop) String() string {
+	buf := &strings.Builder{}
+	p := prim.Prim()
+	fmt.Fprintf(buf, "digraph %s {\n", p.Prim)
+	nodes := append([]cfa.Node{prim.Head}, prim.Body...)
+	for i, n := range nodes {
+		next := prim.Head
+		if i+1 < len(nodes) {
+			next = nodes[i+1]
+		}
+		fmt.Fprintf(buf, "\t%s -> %s\n", n.DOTID(), next.DOTID())
+	}
+	for _, e := range p.Edges {
+		fmt.Fprintf(buf, "\t%s -> %s [label=%q]\n", e.From, e.To, e.Label)
+	}
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// FindLoop returns the first occurrence of an endless loop or a loop with
+// several exits in g, and a boolean indicating if such a primitive was found.
+func FindLoop(g graph.Directed, dom cfa.DominatorTree) (prim Loop, ok bool) {
+	// Range through head node candidates.
+	for nodes := g.Nodes(); nodes.Next(); {
+		// Note: This run-time type assertion goes away, should Gonum graph start
+		// to leverage generics in Go2.
+		head := nodes.Node().(cfa.Node)
+		if prim, ok := findLoopAt(g, dom, head); ok {
+			return prim, true
+		}
+	}
+	return Loop{}, false
+}
+
+// findLoopAt returns the endless loop or loop with several exits with the given
+// head node in g, and a boolean indicating if such a primitive was found.
+func findLoopAt(g graph.Directed, dom cfa.DominatorTree, head cfa.Node) (prim Loop, ok bool) {
+	// Try to locate endless loop.
+	prim, follows, ok := walkLoop(g, head, nil)
+	if ok {
+		return prim, true
+	}
+	// Try each follow node candidate.
+	for _, follow := range follows {
+		prim, _, ok := walkLoop(g, head, follow)
+		if ok && prim.IsValid(g, dom) {
+			return prim, true
+		}
+	}
+	return Loop{}, false
+}
+
+// walkLoop walks the chain of loop nodes from the given loop header to the
+// latch node, where each loop node has one successor in the chain and
+// optionally a break edge to the follow node or a continue edge to the loop
+// header. The latch node has an edge to the loop header and optionally a break
+// edge to the follow node.
+//
+// The follow node is nil for endless loops, in which case the successors which
+// may be the follow node of a loop with several exits are returned as follow
+// node candidates; most likely candidates first.
+func walkLoop(g graph.Directed, head, follow cfa.Node) (prim Loop, follows []cfa.Node, ok bool) {
+	prim.Head = head
+	prim.Exit = follow
+	inLoop := map[int64]bool{head.ID(): true}
+	// Candidate follow nodes reached through continue edge candidates.
+	var conts []cfa.Node
+	for n := head; ; {
+		// Verify that n has one or two successors.
+		succs := cfa.NodesOf(g.From(n.ID()))
+		sortNodes(succs)
+		if len(succs) < 1 || len(succs) > 2 {
+			return Loop{}, conts, false
+		}
+		// Classify the outgoing edges of n.
+		var next []cfa.Node
+		back := false
+		for _, succ := range succs {
+			switch {
+			case succ.ID() == head.ID():
+				back = true
+			case follow != nil && succ.ID() == follow.ID():
+				prim.Breaks = append(prim.Breaks, g.Edge(n.ID(), succ.ID()).(cfa.Edge))
+			case inLoop[succ.ID()]:
+				return Loop{}, conts, false
+			default:
+				next = append(next, succ)
+			}
+		}
+		switch len(next) {
+		case 0:
+			// Latch node reached.
+			return prim, conts, back
+		case 1:
+			if back {
+				// The edge to next may be a break edge from the latch node.
+				conts = append(conts, next[0])
+				prim.Continues = append(prim.Continues, g.Edge(n.ID(), head.ID()).(cfa.Edge))
+			}
+		default:
+			// Either successor may be the follow node.
+			return Loop{}, append(next, conts...), false
+		}
+		// Verify that the next node in chain has one predecessor (n).
+		n = next[0]
+		if g.To(n.ID()).Len() != 1 {
+			return Loop{}, conts, false
+		}
+		inLoop[n.ID()] = true
+		prim.Body = append(prim.Body, n)
+	}
+}
+
+// IsValid reports whether the head, body and exit node candidates of prim form
+// a valid loop with several exits in g.
+//
+// Control flow graph:
+//
+//    head ← ←
+//    ↓   ↘   ↑
+//    ↓    body
+//    ↓   ↙
+//    exit
+func (prim Loop) IsValid(g graph.Directed, dom cfa.DominatorTree) bool {
+	if prim.Exit == nil {
+		// Endless loop.
+		return true
+	}
+	// Verify that every predecessor of exit is a loop node.
+	exit := prim.Exit
+	exitPreds := g.To(exit.ID())
+	if len(prim.Breaks) == 0 || exitPreds.Len() != len(prim.Breaks) {
+		return false
+	}
+	// Dominator sanity check; exit is immediately dominated by a loop node.
+	if dom.Dominates(prim.Head.ID(), exit.ID()) {
+		return true
+	}
+	for _, n := range prim.Body {
+		if dom.Dominates(n.ID(), exit.ID()) {
+			return true
+		}
+	}
+	return false
+}
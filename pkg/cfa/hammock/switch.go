@@ -109,30 +109,39 @@ func FindSwitch(g graph.Directed, dom cfa.DominatorTree) (prim Switch, ok bool)
 		// Note: This run-time type assertion goes away, should Gonum graph start
 		// to leverage generics in Go2.
 		cond := nodes.Node().(cfa.Node)
-		// Verify that cond has at least one successor (default).
-		condSuccs := cfa.NodesOf(g.From(cond.ID()))
-		if len(condSuccs) < 1 {
-			continue
-		}
-		prim.Cond = cond
-		// Select cases and default node candidates.
-		// TODO: try each combination for default node.
-		prim.Cases = condSuccs[:len(condSuccs)-1]
-		prim.Default = condSuccs[len(condSuccs)-1]
-		// Verify that default has one successor (exit).
-		defaultSuccs := cfa.NodesOf(g.From(prim.Default.ID()))
-		if len(defaultSuccs) != 1 {
-			continue
-		}
-		// Select exit node candidate.
-		prim.Exit = defaultSuccs[0]
-		if prim.IsValid(g, dom) {
+		if prim, ok := findSwitchAt(g, dom, cond); ok {
 			return prim, true
 		}
 	}
 	return Switch{}, false
 }
 
+// findSwitchAt returns the n-way conditional statement with the given cond node
+// in g, and a boolean indicating if such a primitive was found.
+func findSwitchAt(g graph.Directed, dom cfa.DominatorTree, cond cfa.Node) (prim Switch, ok bool) {
+	// Verify that cond has at least one successor (default).
+	condSuccs := cfa.NodesOf(g.From(cond.ID()))
+	if len(condSuccs) < 1 {
+		return Switch{}, false
+	}
+	prim.Cond = cond
+	// Select cases and default node candidates.
+	// TODO: try each combination for default node.
+	prim.Cases = condSuccs[:len(condSuccs)-1]
+	prim.Default = condSuccs[len(condSuccs)-1]
+	// Verify that default has one successor (exit).
+	defaultSuccs := cfa.NodesOf(g.From(prim.Default.ID()))
+	if len(defaultSuccs) != 1 {
+		return Switch{}, false
+	}
+	// Select exit node candidate.
+	prim.Exit = defaultSuccs[0]
+	if prim.IsValid(g, dom) {
+		return prim, true
+	}
+	return Switch{}, false
+}
+
 // IsValid reports whether the cond, case_B, case_C, case_D, ..., default and
 // exit node candidates of prim form a valid n-way conditional statement in g.
 //
@@ -0,0 +1,460 @@
+package hammock
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
+	"github.com/mewmew/lnp/pkg/cfg"
+	"github.com/pkg/errors"
+	"github.com/rickypai/natsort"
+	"gonum.org/v1/gonum/graph"
+)
+
+// Template is a data-driven canonical subgraph representation of a high-level
+// control flow primitive, as loaded from Graphviz DOT format.
+//
+// The graph ID of the DOT graph specifies the name of the primitive, and each
+// node of the DOT graph specifies a node role of the primitive. For instance,
+// the template of 1-way conditionals is
+//
+//    digraph if {
+//       cond [entry=true]
+//       exit [exit=true]
+//       cond -> body
+//       cond -> exit
+//       body -> exit
+//    }
+//
+// The following node attributes are recognized:
+//
+//    entry=true   entry node of the primitive (required).
+//    exit=true    exit node of the primitive.
+//    in=N         required in-degree of the node, or "*" for any in-degree.
+//    out=N        required out-degree of the node, or "*" for any out-degree.
+//    idom=ROLE    required immediate dominator of the node, or "*" for any
+//                 immediate dominator.
+//    loop=false   the entry node may not have predecessors immediately
+//                 dominated by the entry node (i.e. loop back edges).
+//
+// The following edge attributes are recognized:
+//
+//    cond=true    the edge is the true branch of a conditional edge.
+//    cond=false   the edge is the false branch of a conditional edge.
+//
+// Edge conditions are only enforced for edges of the control flow graph with a
+// true or false condition.
+//
+// By default, the in-degree and out-degree of each node in the control flow
+// graph must match the template, except for the in-degree of the entry node and
+// the out-degree of the exit node, which are unconstrained. Every node except
+// the entry node must be immediately dominated by the entry node by default.
+type Template struct {
+	// Primitive name; e.g.
+	//
+	//    "if", "pre_loop", ...
+	Name string
+	// Node roles of the template, in matching order; the entry node role is
+	// first and every subsequent node role is a successor of a preceding node
+	// role.
+	roles []*role
+	// Entry node role.
+	entry *role
+	// Exit node role; or nil if not present.
+	exit *role
+	// Maximum number of edges spanned from the entry node.
+	depth int
+	// Canonical subgraph of the template.
+	g *cfg.Graph
+}
+
+// role is a node role of a template.
+type role struct {
+	// Node role name; e.g. "cond".
+	name string
+	// Index of the node role in the matching order.
+	index int
+	// Index of the node role from which the node role is located during
+	// matching; or -1 for the entry node role.
+	parent int
+	// Number of edges from the entry node role.
+	depth int
+	// Indices of the successor node roles.
+	succs []int
+	// Required condition of the edge to each successor node role; or the empty
+	// string if unconstrained.
+	conds []string
+	// Required in-degree; or -1 if unconstrained.
+	in int
+	// Required out-degree; or -1 if unconstrained.
+	out int
+	// Index of the required immediate dominator node role; or -1 if
+	// unconstrained.
+	idom int
+	// The node may not have predecessors immediately dominated by the node.
+	noLoop bool
+}
+
+// ParseTemplate parses the given primitive template in Graphviz DOT format,
+// reading from s.
+func ParseTemplate(s string) (*Template, error) {
+	g := cfg.NewGraph()
+	if err := cfg.ParseStringInto(s, g); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return newTemplate(g)
+}
+
+// ParseTemplateFile parses the given primitive template in Graphviz DOT format,
+// reading from dotPath.
+func ParseTemplateFile(dotPath string) (*Template, error) {
+	g := cfg.NewGraph()
+	if err := cfg.ParseFileInto(dotPath, g); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	t, err := newTemplate(g)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to parse template %q", dotPath)
+	}
+	return t, nil
+}
+
+// LoadTemplates parses the primitive templates (*.dot) of the given directory,
+// in natural sort order of their file names.
+func LoadTemplates(dir string) ([]*Template, error) {
+	dotPaths, err := filepath.Glob(filepath.Join(dir, "*.dot"))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	less := func(i, j int) bool {
+		return natsort.Less(dotPaths[i], dotPaths[j])
+	}
+	sort.Slice(dotPaths, less)
+	var ts []*Template
+	for _, dotPath := range dotPaths {
+		t, err := ParseTemplateFile(dotPath)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+// newTemplate returns a new primitive template based on the given canonical
+// subgraph.
+func newTemplate(g *cfg.Graph) (*Template, error) {
+	t := &Template{
+		Name: g.DOTID(),
+		g:    g,
+	}
+	if len(t.Name) == 0 {
+		return nil, errors.New("missing primitive name (graph ID) of template")
+	}
+	// Order node roles in breadth-first order from the entry node.
+	entry := g.Entry()
+	index := map[int64]int{entry.ID(): 0}
+	nodes := []cfa.Node{entry}
+	t.roles = append(t.roles, &role{name: entry.DOTID(), index: 0, parent: -1})
+	for i := 0; i < len(nodes); i++ {
+		for _, succ := range condSuccs(g, nodes[i]) {
+			if _, ok := index[succ.ID()]; ok {
+				continue
+			}
+			index[succ.ID()] = len(nodes)
+			nodes = append(nodes, succ)
+			depth := t.roles[i].depth + 1
+			t.roles = append(t.roles, &role{name: succ.DOTID(), index: len(t.roles), parent: i, depth: depth})
+			if depth > t.depth {
+				t.depth = depth
+			}
+		}
+	}
+	if len(nodes) != g.Nodes().Len() {
+		return nil, errors.Errorf("invalid template %q; node roles unreachable from entry node %q", t.Name, entry.DOTID())
+	}
+	// Parse node role constraints.
+	for i, n := range nodes {
+		r := t.roles[i]
+		for _, succ := range condSuccs(g, n) {
+			r.succs = append(r.succs, index[succ.ID()])
+			// Note: This run-time type assertion goes away, should Gonum graph
+			// start to leverage generics in Go2.
+			e := g.Edge(n.ID(), succ.ID()).(cfa.Edge)
+			var cond string
+			switch conds := cfa.EdgeConds(e); {
+			case len(conds) == 0:
+				// Unconstrained.
+			case len(conds) == 1 && (conds[0] == "true" || conds[0] == "false"):
+				cond = conds[0]
+			default:
+				return nil, errors.Errorf("invalid condition %q of edge from node %q to node %q in template %q; expected true or false", strings.Join(conds, ", "), r.name, succ.DOTID(), t.Name)
+			}
+			r.conds = append(r.conds, cond)
+		}
+		r.in, r.out = g.To(n.ID()).Len(), g.From(n.ID()).Len()
+		r.idom = 0
+		if i == 0 {
+			t.entry = r
+			r.in, r.idom = -1, -1
+		}
+		if v, ok := n.Attribute("exit"); ok && v == "true" {
+			if t.exit != nil {
+				return nil, errors.Errorf("invalid template %q; multiple exit nodes %q and %q", t.Name, t.exit.name, r.name)
+			}
+			t.exit = r
+			r.out = -1
+		}
+		for _, key := range []string{"in", "out"} {
+			v, ok := n.Attribute(key)
+			if !ok {
+				continue
+			}
+			degree := -1
+			if v != "*" {
+				d, err := strconv.Atoi(v)
+				if err != nil || d < 0 {
+					return nil, errors.Errorf("invalid %s-degree %q of node %q in template %q", key, v, r.name, t.Name)
+				}
+				degree = d
+			}
+			if key == "in" {
+				r.in = degree
+			} else {
+				r.out = degree
+			}
+		}
+		if v, ok := n.Attribute("idom"); ok {
+			switch v {
+			case "*":
+				r.idom = -1
+			default:
+				idom, ok := g.NodeWithDOTID(v)
+				if !ok {
+					return nil, errors.Errorf("invalid immediate dominator %q of node %q in template %q", v, r.name, t.Name)
+				}
+				r.idom = index[idom.ID()]
+			}
+		}
+		if v, ok := n.Attribute("loop"); ok && v == "false" {
+			r.noLoop = true
+		}
+	}
+	return t, nil
+}
+
+// Find returns the first occurrence of the primitive template in g, and a
+// boolean indicating if such a primitive was found.
+func (t *Template) Find(g graph.Directed, dom cfa.DominatorTree) (*primitive.Primitive, bool) {
+	nodes := cfa.NodesOf(g.Nodes())
+	sortNodes(nodes)
+	for _, entry := range nodes {
+		if prim, ok := t.FindAt(g, dom, entry); ok {
+			return prim, true
+		}
+	}
+	return nil, false
+}
+
+// FindAt returns the occurrence of the primitive template with the given entry
+// node in g, and a boolean indicating if such a primitive was found.
+func (t *Template) FindAt(g graph.Directed, dom cfa.DominatorTree, entry cfa.Node) (*primitive.Primitive, bool) {
+	// Map from node role index to node of g.
+	nodes := make([]cfa.Node, len(t.roles))
+	used := make(map[int64]bool)
+	// match assigns nodes of g to the node roles with index i and above, and
+	// reports whether a valid assignment was found.
+	var match func(i int) bool
+	match = func(i int) bool {
+		if i == len(t.roles) {
+			return t.isValid(g, dom, nodes)
+		}
+		r := t.roles[i]
+		for _, n := range condSuccs(g, nodes[r.parent]) {
+			if used[n.ID()] || !r.hasDegree(g, n) {
+				continue
+			}
+			nodes[i] = n
+			used[n.ID()] = true
+			if match(i + 1) {
+				return true
+			}
+			delete(used, n.ID())
+		}
+		nodes[i] = nil
+		return false
+	}
+	if !t.entry.hasDegree(g, entry) {
+		return nil, false
+	}
+	nodes[0] = entry
+	used[entry.ID()] = true
+	if !match(1) {
+		return nil, false
+	}
+	return t.prim(nodes), true
+}
+
+// hasDegree reports whether the node n of g has the required in- and
+// out-degree of the node role.
+func (r *role) hasDegree(g graph.Directed, n cfa.Node) bool {
+	if r.in != -1 && g.To(n.ID()).Len() != r.in {
+		return false
+	}
+	if r.out != -1 && g.From(n.ID()).Len() != r.out {
+		return false
+	}
+	return true
+}
+
+// isValid reports whether the given nodes of g, indexed by node role, form a
+// valid occurrence of the primitive template.
+func (t *Template) isValid(g graph.Directed, dom cfa.DominatorTree, nodes []cfa.Node) bool {
+	for i, r := range t.roles {
+		n := nodes[i]
+		// Verify edges.
+		for j, succ := range r.succs {
+			e, ok := g.Edge(n.ID(), nodes[succ].ID()).(cfa.Edge)
+			if !ok {
+				return false
+			}
+			// Verify edge condition.
+			if cond := r.conds[j]; len(cond) > 0 {
+				conds := cfa.EdgeConds(e)
+				if len(conds) == 1 && (conds[0] == "true" || conds[0] == "false") && conds[0] != cond {
+					return false
+				}
+			}
+		}
+		// Dominator sanity check.
+		if r.idom != -1 && !dom.Dominates(nodes[r.idom].ID(), n.ID()) {
+			return false
+		}
+		// Verify that the node has no predecessors dominated by the node, as that
+		// would indicate a loop construct.
+		if r.noLoop {
+			for preds := g.To(n.ID()); preds.Next(); {
+				if dom.Dominates(n.ID(), preds.Node().ID()) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// prim returns a representation of the high-level control flow primitive
+// located by the template, as a mapping from node roles to control flow graph
+// node names.
+func (t *Template) prim(nodes []cfa.Node) *primitive.Primitive {
+	prim := &primitive.Primitive{
+		Prim:  t.Name,
+		Nodes: make(map[string]string),
+		Entry: nodes[t.entry.index].DOTID(),
+	}
+	for i, r := range t.roles {
+		prim.Nodes[r.name] = nodes[i].DOTID()
+	}
+	if t.exit != nil {
+		prim.Exit = nodes[t.exit.index].DOTID()
+	}
+	return prim
+}
+
+// String returns a string representation of the template in Graphviz DOT
+// format.
+func (t *Template) String() string {
+	return t.g.String()
+}
+
+// --- [ Built-in templates ] --------------------------------------------------
+
+// builtinTemplates specifies the templates of the built-in primitives, in the
+// order of precedence of FindPrim. Compound conditions and loops of arbitrary
+// length are located by hand-written finders (see finders), as their
+// constraints cannot be expressed by templates.
+var builtinTemplates = []string{
+	// Sequence of two statements.
+	`
+digraph seq {
+	entry [entry=true]
+	exit [exit=true]
+	entry -> exit
+}`,
+	// Pre-test loop.
+	`
+digraph pre_loop {
+	cond [entry=true]
+	exit [exit=true]
+	cond -> body
+	body -> cond
+	cond -> exit
+}`,
+	// Post-test loop.
+	`
+digraph post_loop {
+	cond [entry=true]
+	exit [exit=true]
+	cond -> cond
+	cond -> exit
+}`,
+	// 1-way conditional.
+	`
+digraph if {
+	cond [entry=true]
+	exit [exit=true]
+	cond -> body
+	cond -> exit
+	body -> exit
+}`,
+	// 1-way conditional with a body return statement.
+	`
+digraph if_return {
+	cond [entry=true, loop=false]
+	exit [exit=true]
+	cond -> body
+	cond -> exit
+}`,
+	// 2-way conditional.
+	`
+digraph if_else {
+	cond [entry=true]
+	exit [exit=true]
+	cond -> body_true [cond=true]
+	cond -> body_false [cond=false]
+	body_true -> exit
+	body_false -> exit
+}`,
+}
+
+// builtins maps from primitive name to the template of each built-in
+// primitive.
+var builtins = builtinsByName()
+
+// builtinsByName returns the templates of the built-in primitives, indexed by
+// primitive name.
+func builtinsByName() map[string]*Template {
+	m := make(map[string]*Template)
+	for _, t := range BuiltinTemplates() {
+		m[t.Name] = t
+	}
+	return m
+}
+
+// BuiltinTemplates returns the templates of the built-in primitives located by
+// FindPrim, in order of precedence.
+func BuiltinTemplates() []*Template {
+	var ts []*Template
+	for _, s := range builtinTemplates {
+		t, err := ParseTemplate(s[1:])
+		if err != nil {
+			panic(fmt.Errorf("unable to parse built-in template; %+v", err))
+		}
+		ts = append(ts, t)
+	}
+	return ts
+}
@@ -0,0 +1,199 @@
+package hammock
+
+import (
+	"sort"
+
+	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
+	"github.com/rickypai/natsort"
+	"gonum.org/v1/gonum/graph"
+)
+
+// finder locates a high-level control flow primitive with the given entry node
+// in g, and returns a boolean indicating if such a primitive was found.
+type finder func(g graph.Directed, dom cfa.DominatorTree, entry cfa.Node) (*primitive.Primitive, bool)
+
+// finders lists the finders of each kind of high-level control flow primitive,
+// in the order of precedence of FindPrim.
+var finders = []finder{
+	// Sequences of two statements.
+	builtins["seq"].FindAt,
+	// Compound conditions.
+	func(g graph.Directed, dom cfa.DominatorTree, entry cfa.Node) (*primitive.Primitive, bool) {
+		if prim, ok := findCompCondAt(g, dom, entry); ok {
+			return prim.Prim(), true
+		}
+		return nil, false
+	},
+	// Pre-test loops.
+	builtins["pre_loop"].FindAt,
+	// Post-test loops.
+	builtins["post_loop"].FindAt,
+	// 1-way conditionals.
+	builtins["if"].FindAt,
+	// 1-way conditionals with a body return statements.
+	builtins["if_return"].FindAt,
+	// 2-way conditionals.
+	builtins["if_else"].FindAt,
+	// Endless loops and loops with several exits.
+	func(g graph.Directed, dom cfa.DominatorTree, entry cfa.Node) (*primitive.Primitive, bool) {
+		if prim, ok := findLoopAt(g, dom, entry); ok {
+			return prim.Prim(), true
+		}
+		return nil, false
+	},
+}
+
+// worklist tracks the candidate entry nodes of each kind of high-level control
+// flow primitive. Nodes not present in the worklist of a given kind are known
+// not to be the entry node of a primitive of that kind, as neither the nodes in
+// their vicinity nor their dominators have changed since last examined.
+type worklist struct {
+	// Finders of each kind of primitive, in order of precedence.
+	finders []finder
+	// Queue of candidate entry nodes for each kind of primitive; indexed by
+	// finder.
+	queues []*queue
+	// Maximum number of edges spanned by a primitive from its entry node.
+	radius int
+}
+
+// newWorklist returns a new worklist with every node of g as candidate entry
+// node for each kind of primitive located by the given finders. The radius
+// specifies the maximum number of edges spanned by a primitive from its entry
+// node.
+func newWorklist(g cfa.Graph, finders []finder, radius int) *worklist {
+	nodes := cfa.NodesOf(g.Nodes())
+	sortNodes(nodes)
+	w := &worklist{
+		finders: finders,
+		queues:  make([]*queue, len(finders)),
+		radius:  radius,
+	}
+	for i := range w.queues {
+		w.queues[i] = newQueue()
+		for _, n := range nodes {
+			w.queues[i].push(n)
+		}
+	}
+	return w
+}
+
+// findPrim returns the first occurrence of a high-level control flow primitive
+// in g with an entry node in the worklist, and a boolean indicating if such a
+// primitive was found. Kinds of primitives are located in the order of
+// precedence of the finders.
+//
+// Candidates examined are removed from the worklist.
+func (w *worklist) findPrim(g cfa.Graph, dom cfa.DominatorTree) (*primitive.Primitive, bool) {
+	for i, find := range w.finders {
+		q := w.queues[i]
+		for !q.empty() {
+			n := q.pop()
+			if g.Node(n.ID()) != graph.Node(n) {
+				// Skip merged nodes.
+				continue
+			}
+			if prim, ok := find(g, dom, n); ok {
+				return prim, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// touch adds the nodes in the vicinity of the merged node n to the worklist of
+// each kind of primitive. As primitives span at most w.radius edges from their
+// entry node, the candidates are the nodes within w.radius edges backwards of n
+// or of its immediate predecessors and successors, the degree of which may have
+// changed.
+func (w *worklist) touch(g cfa.Graph, n cfa.Node) {
+	seen := map[int64]bool{n.ID(): true}
+	nodes := []cfa.Node{n}
+	add := func(n cfa.Node) {
+		if !seen[n.ID()] {
+			seen[n.ID()] = true
+			nodes = append(nodes, n)
+		}
+	}
+	for _, pred := range cfa.NodesOf(g.To(n.ID())) {
+		add(pred)
+	}
+	for _, succ := range cfa.NodesOf(g.From(n.ID())) {
+		add(succ)
+	}
+	for dist := 0; dist < w.radius; dist++ {
+		for _, n := range nodes {
+			for _, pred := range cfa.NodesOf(g.To(n.ID())) {
+				add(pred)
+			}
+		}
+	}
+	// Loops span an arbitrary number of edges from their loop header. Add the
+	// loop header candidates located by walking backwards along chains of nodes
+	// with one predecessor.
+	for _, n := range nodes {
+		visited := map[int64]bool{n.ID(): true}
+		for g.To(n.ID()).Len() == 1 {
+			pred := cfa.NodesOf(g.To(n.ID()))[0]
+			if visited[pred.ID()] {
+				break
+			}
+			visited[pred.ID()] = true
+			n = pred
+		}
+		add(n)
+	}
+	sortNodes(nodes)
+	for _, q := range w.queues {
+		for _, n := range nodes {
+			q.push(n)
+		}
+	}
+}
+
+// sortNodes sorts the nodes in natural sort order of their DOT node IDs.
+func sortNodes(nodes []cfa.Node) {
+	less := func(i, j int) bool {
+		return natsort.Less(nodes[i].DOTID(), nodes[j].DOTID())
+	}
+	sort.Slice(nodes, less)
+}
+
+// --- [ Queue ] ---------------------------------------------------------------
+
+// queue is a FIFO queue of nodes, which holds each node at most once.
+type queue struct {
+	// List of nodes in queue.
+	ns []cfa.Node
+	// Set of nodes in queue.
+	in map[cfa.Node]bool
+}
+
+// newQueue returns a new FIFO queue of nodes.
+func newQueue() *queue {
+	return &queue{
+		in: make(map[cfa.Node]bool),
+	}
+}
+
+// push appends the node to the end of the queue, unless already in the queue.
+func (q *queue) push(n cfa.Node) {
+	if !q.in[n] {
+		q.in[n] = true
+		q.ns = append(q.ns, n)
+	}
+}
+
+// pop pops the node at the front of the queue.
+func (q *queue) pop() cfa.Node {
+	n := q.ns[0]
+	q.ns = q.ns[1:]
+	delete(q.in, n)
+	return n
+}
+
+// empty reports whether the queue is empty.
+func (q *queue) empty() bool {
+	return len(q.ns) == 0
+}
@@ -4,6 +4,7 @@ import (
 	"sort"
 
 	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
 	"github.com/mewmew/lnp/pkg/cfg"
 	"github.com/rickypai/natsort"
 	"gonum.org/v1/gonum/graph"
@@ -30,6 +31,48 @@ func (g *Graph) NewNode() graph.Node {
 	}
 }
 
+// Clone returns a deep copy of the control flow graph, including its nodes,
+// edges, attributes and structuring information.
+func (g *Graph) Clone() cfa.Graph {
+	dst := NewGraph()
+	nodes := cfg.Copy(dst, g)
+	// clone returns the copy of the given node; or nil if not present in dst.
+	clone := func(n *Node) *Node {
+		if n == nil {
+			return nil
+		}
+		nn, ok := nodes[n.ID()]
+		if !ok {
+			return nil
+		}
+		return nn.(*Node)
+	}
+	for id, nn := range nodes {
+		n := g.Node(id).(*Node)
+		c := nn.(*Node)
+		c.PreNum = n.PreNum
+		c.RevPostNum = n.RevPostNum
+		c.LoopHead = clone(n.LoopHead)
+		c.LoopType = n.LoopType
+		c.LoopFollow = clone(n.LoopFollow)
+		c.IsLoopLatch = n.IsLoopLatch
+		c.Follow = clone(n.Follow)
+		c.IsCondNode = n.IsCondNode
+		c.CompCond = n.CompCond
+	}
+	return dst
+}
+
+// Snapshot returns a copy-on-write snapshot of the control flow graph.
+//
+// Note, modifications of the structuring information of nodes do not trigger
+// the copy of the snapshot.
+func (g *Graph) Snapshot() *cfa.Snapshot {
+	s := cfa.NewSnapshot(g)
+	g.Graph.(*cfg.Graph).AddSnapshot(s)
+	return s
+}
+
 // NodesOf returns it.Len() nodes from it.
 func NodesOf(nodes graph.Nodes) []*Node {
 	var ns []*Node
@@ -72,14 +115,11 @@ type Node struct {
 	// instructions.
 	IsCondNode bool
 	// Compound conditional used to represent short-circuit evaluation in the
-	// control flow graph.
-	// TODO: figure out a better representation; perhaps struct with two fields,
-	// merged condnode name and boolean operation.
-	//    NOT n AND t
-	//    n OR t
-	//    n AND e
-	//    NOT n OR e
-	CompCond string
+	// control flow graph; or nil if not a compound conditional. The leaves of
+	// the boolean expression tree are the names of the merged nodes; e.g.
+	//
+	//    "n" AND NOT "t"
+	CompCond *primitive.Cond
 }
 
 // SetAttribute implements encoding.AttributeSetter for Node.
@@ -93,41 +133,42 @@ func (n *Node) SetAttribute(attr encoding.Attribute) error {
 
 // initDFSOrder initializes the DFS visit order of the control flow graph.
 func initDFSOrder(g cfa.Graph) {
-	preNum := 1
-	pre := func(n *Node) {
-		n.PreNum = preNum
-		preNum++
+	preOrder, revPostOrder := cfa.DFSOrder(g, succs(g))
+	for i, id := range preOrder {
+		g.Node(id).(*Node).PreNum = i + 1
 	}
-	n := g.Nodes().Len()
-	revPostNum := n
-	post := func(n *Node) {
-		n.RevPostNum = revPostNum
-		revPostNum--
+	for i, id := range revPostOrder {
+		g.Node(id).(*Node).RevPostNum = i + 1
 	}
-	DFS(g, pre, post)
 }
 
 // DFS performs a depth-first search of the control flow graph, invoking non-nil
 // pre and post during pre- and post-order visit, respectively.
 func DFS(g cfa.Graph, pre, post func(n *Node)) {
-	visited := make(map[int64]bool)
-	var dfs func(n *Node)
-	dfs = func(n *Node) {
-		if visited[n.ID()] {
-			return
+	var v cfa.Visitor
+	if pre != nil {
+		v.Pre = func(n cfa.Node) {
+			pre(n.(*Node))
 		}
-		visited[n.ID()] = true
-		if pre != nil {
-			pre(n)
+	}
+	if post != nil {
+		v.Post = func(n cfa.Node) {
+			post(n.(*Node))
 		}
+	}
+	cfa.Walk(g, succs(g), v)
+}
+
+// succs returns a function which returns the immediate successors of a node in
+// the control flow graph, in the order of successors.
+func succs(g cfa.Graph) func(n cfa.Node) []cfa.Node {
+	return func(n cfa.Node) []cfa.Node {
+		var ns []cfa.Node
 		for _, succ := range successors(g, n.ID()) {
-			dfs(succ)
-		}
-		if post != nil {
-			post(n)
+			ns = append(ns, succ)
 		}
+		return ns
 	}
-	dfs(g.Entry().(*Node))
 }
 
 // successors returns the immediate successors of the node with the given ID in
@@ -141,12 +182,14 @@ func successors(g cfa.Graph, id int64) []*Node {
 		nj := nodes[j]
 		ei := g.Edge(id, ni.ID()).(cfa.Edge)
 		ej := g.Edge(id, nj.ID()).(cfa.Edge)
-		ci, ok := ei.Attribute("cond")
+		// Note, parallel edges merged into a single edge (e.g. switch cases
+		// sharing a target) are ordered based on their first condition.
+		ci, ok := firstCond(ei)
 		if !ok {
 			// Fall-back to sorting on DOTID, to make output deterministic.
 			return natsort.Less(ni.DOTID(), nj.DOTID())
 		}
-		cj, ok := ej.Attribute("cond")
+		cj, ok := firstCond(ej)
 		if !ok {
 			// Fall-back to sorting on DOTID, to make output deterministic.
 			return natsort.Less(ni.DOTID(), nj.DOTID())
@@ -165,6 +208,16 @@ func successors(g cfa.Graph, id int64) []*Node {
 	return nodes
 }
 
+// firstCond returns the first condition of the given edge. The boolean return
+// value indicates success.
+func firstCond(e cfa.Edge) (string, bool) {
+	conds := cfa.EdgeConds(e)
+	if len(conds) == 0 {
+		return "", false
+	}
+	return conds[0], true
+}
+
 // descRevPostOrder returns the nodes in descending reverse post-order. In
 // particular, the returned list contains innermost nodes before outmost nodes.
 func descRevPostOrder(nodes []*Node) []*Node {
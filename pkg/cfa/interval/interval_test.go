@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
 	"github.com/mewmew/lnp/pkg/cfg"
 	"gonum.org/v1/gonum/graph"
 )
@@ -98,6 +99,67 @@ func TestCommonImmedDom(t *testing.T) {
 	}
 }
 
+func TestClone(t *testing.T) {
+	const path = "testdata/sample.dot"
+	g := NewGraph()
+	if err := cfg.ParseFileInto(path, g); err != nil {
+		t.Fatalf("%q: unable to parse file; %v", path, err)
+	}
+	// Record structuring information.
+	node := func(g cfa.Graph, dotID string) *Node {
+		n, ok := g.NodeWithDOTID(dotID)
+		if !ok {
+			t.Fatalf("%q: unable to locate node %q", path, dotID)
+		}
+		return n.(*Node)
+	}
+	b2 := node(g, "B2")
+	b2.PreNum = 2
+	b2.RevPostNum = 3
+	b2.LoopHead = node(g, "B1")
+	b2.LoopType = LoopTypePreTest
+	b2.LoopFollow = node(g, "B5")
+	b2.IsLoopLatch = true
+	b2.Follow = node(g, "B4")
+	b2.IsCondNode = true
+	b2.CompCond = primitive.And(primitive.Leaf("B2"), primitive.Not(primitive.Leaf("B3")))
+	h := g.Clone()
+	if got := h.DOTID(); got != g.DOTID() {
+		t.Errorf("%q: DOT graph ID mismatch; expected %q, got %q", path, g.DOTID(), got)
+	}
+	if entry := h.Entry(); entry == nil || entry.DOTID() != "B1" {
+		t.Errorf("%q: entry node mismatch; expected %q, got %v", path, "B1", entry)
+	}
+	if got, want := h.Nodes().Len(), g.Nodes().Len(); got != want {
+		t.Errorf("%q: number of nodes mismatch; expected %d, got %d", path, want, got)
+	}
+	c2 := node(h, "B2")
+	if c2 == b2 {
+		t.Fatalf("%q: node %q shared between clone and original graph", path, "B2")
+	}
+	if c2.PreNum != b2.PreNum || c2.RevPostNum != b2.RevPostNum || c2.LoopType != b2.LoopType || c2.IsLoopLatch != b2.IsLoopLatch || c2.IsCondNode != b2.IsCondNode {
+		t.Errorf("%q: structuring information mismatch; expected %+v, got %+v", path, b2, c2)
+	}
+	if c2.CompCond.String() != b2.CompCond.String() {
+		t.Errorf("%q: compound condition mismatch; expected %v, got %v", path, b2.CompCond, c2.CompCond)
+	}
+	// Node references refer to the nodes of the clone.
+	refs := []struct {
+		name string
+		got  *Node
+		want string
+	}{
+		{name: "LoopHead", got: c2.LoopHead, want: "B1"},
+		{name: "LoopFollow", got: c2.LoopFollow, want: "B5"},
+		{name: "Follow", got: c2.Follow, want: "B4"},
+	}
+	for _, ref := range refs {
+		if ref.got != node(h, ref.want) {
+			t.Errorf("%q: %s mismatch; expected node %q of clone, got %v", path, ref.name, ref.want, ref.got)
+		}
+	}
+}
+
 // containsString reports whether the slice contains the given string.
 func containsString(ss []string, s string) bool {
 	for _, t := range ss {
@@ -17,6 +17,7 @@ import (
 	"github.com/mewkiz/pkg/term"
 	"github.com/mewmew/lnp/pkg/cfa"
 	"github.com/mewmew/lnp/pkg/cfa/primitive"
+	"github.com/mewmew/lnp/pkg/cfg"
 )
 
 var (
@@ -32,7 +33,20 @@ var (
 // recovered high-level control flow primitives. The before and after functions
 // are invoked if non-nil before and after merging the nodes of located
 // primitives.
+//
+// Unwind edges are excluded from the analysis, and landing pads are structured
+// as separate regions (see cfg.Regions).
 func Analyze(g cfa.Graph, before, after func(g cfa.Graph, prim *primitive.Primitive)) []*primitive.Primitive {
+	prims := []*primitive.Primitive{}
+	for _, region := range cfg.Regions(g) {
+		prims = append(prims, analyzeRegion(region, before, after)...)
+	}
+	return prims
+}
+
+// analyzeRegion analyzes the given single-entry region of a control flow graph
+// and returns the list of recovered high-level control flow primitives.
+func analyzeRegion(g cfa.Graph, before, after func(g cfa.Graph, prim *primitive.Primitive)) []*primitive.Primitive {
 	prims := []*primitive.Primitive{}
 	// Initialize depth-first search visit order.
 	initDFSOrder(g)
@@ -1,8 +1,6 @@
 package interval
 
 import (
-	"fmt"
-
 	"github.com/mewmew/lnp/pkg/cfa"
 	"github.com/mewmew/lnp/pkg/cfa/primitive"
 	"github.com/mewmew/lnp/pkg/cfg"
@@ -54,9 +52,8 @@ loop:
 						// if (n && !t)
 						// modifyGraph(\lnot n \land t)
 						// Wrong in Cifuentes', which states NOT n AND t. Should be n AND NOT t.
-						compCond := fmt.Sprintf("%q AND NOT %q", n.DOTID(), t.DOTID())
-						n.CompCond = compCond
-						prim := modifyGraph(g, n, t, tSuccs[1], "comp_cond_a_AND_NOT_b", before, after)
+						n.CompCond = primitive.And(primitive.Leaf(n.DOTID()), primitive.Not(primitive.Leaf(t.DOTID())))
+						prim := modifyGraph(g, n, t, tSuccs[1], "comp_cond_a_AND_NOT_b", n.CompCond, before, after)
 						prims = append(prims, prim)
 						// change = True
 						change = true
@@ -66,9 +63,8 @@ loop:
 						// if (n && t)
 						// modifyGraph(n \lor t)
 						// Wrong in Cifuentes', which states n OR t. Should be n AND t.
-						compCond := fmt.Sprintf("%q AND %q", n.DOTID(), t.DOTID())
-						n.CompCond = compCond
-						prim := modifyGraph(g, n, t, tSuccs[0], "comp_cond_a_AND_b", before, after)
+						n.CompCond = primitive.And(primitive.Leaf(n.DOTID()), primitive.Leaf(t.DOTID()))
+						prim := modifyGraph(g, n, t, tSuccs[0], "comp_cond_a_AND_b", n.CompCond, before, after)
 						prims = append(prims, prim)
 						// change = True
 						change = true
@@ -82,9 +78,8 @@ loop:
 						// if (n || e)
 						// modifyGraph(n \land e)
 						// Wrong in Cifuentes', which states n AND e. Should be n OR e.
-						compCond := fmt.Sprintf("%q OR %q", n.DOTID(), e.DOTID())
-						n.CompCond = compCond
-						prim := modifyGraph(g, n, e, eSuccs[1], "comp_cond_a_OR_b", before, after)
+						n.CompCond = primitive.Or(primitive.Leaf(n.DOTID()), primitive.Leaf(e.DOTID()))
+						prim := modifyGraph(g, n, e, eSuccs[1], "comp_cond_a_OR_b", n.CompCond, before, after)
 						prims = append(prims, prim)
 						// change = True
 						change = true
@@ -93,9 +88,8 @@ loop:
 					case eSuccs[1].ID() == t.ID():
 						// modifyGraph(\lnot n \lor e)
 						// Wrong in Cifuentes', which states NOT n OR e. Should be n OR NOT e.
-						compCond := fmt.Sprintf("%q OR NOT %q", n.DOTID(), e.DOTID())
-						n.CompCond = compCond
-						prim := modifyGraph(g, n, e, eSuccs[0], "comp_cond_a_OR_NOT_b", before, after)
+						n.CompCond = primitive.Or(primitive.Leaf(n.DOTID()), primitive.Not(primitive.Leaf(e.DOTID())))
+						prim := modifyGraph(g, n, e, eSuccs[0], "comp_cond_a_OR_NOT_b", n.CompCond, before, after)
 						prims = append(prims, prim)
 						// change = True
 						change = true
@@ -109,7 +103,7 @@ loop:
 }
 
 // modifyGraph modifies the control flow graph to merge the compound condition
-func modifyGraph(g cfa.Graph, n, c, follow cfa.Node, compCond string, before, after func(g cfa.Graph, prim *primitive.Primitive)) *primitive.Primitive {
+func modifyGraph(g cfa.Graph, n, c, follow cfa.Node, compCond string, cond *primitive.Cond, before, after func(g cfa.Graph, prim *primitive.Primitive)) *primitive.Primitive {
 	// Create primitive.
 	prim := &primitive.Primitive{
 		Prim:  compCond,
@@ -119,6 +113,7 @@ func modifyGraph(g cfa.Graph, n, c, follow cfa.Node, compCond string, before, af
 			"b": c.DOTID(),
 			//"follow": follow.DOTID(),
 		},
+		Cond: cond,
 	}
 	if before != nil {
 		before(g, prim)
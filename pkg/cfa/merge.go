@@ -8,6 +8,10 @@ import (
 
 // Merge merges the nodes of the primitive into a single node, which is
 // assigned the basic block label of the entry node.
+//
+// Edges from a predecessor to several merged nodes (or from several merged
+// nodes to a successor) become parallel edges of the new node, which are merged
+// by g.SetEdge into a single edge recording the conditions of each edge.
 func Merge(g Graph, prim *primitive.Primitive) (Graph, error) {
 	// Set of nodes marked for removal; indexed by DOT node ID.
 	primNodes := make(map[string]bool)
@@ -18,6 +22,9 @@ func Merge(g Graph, prim *primitive.Primitive) (Graph, error) {
 	// deleted.
 	var removeIDs []int64
 	entry := false
+	// The new node contains only conditional and branching information if each
+	// merged node does.
+	condNode := true
 	for dotID := range primNodes {
 		n, ok := g.NodeWithDOTID(dotID)
 		if !ok {
@@ -27,6 +34,9 @@ func Merge(g Graph, prim *primitive.Primitive) (Graph, error) {
 		if isEntry(n) {
 			entry = true
 		}
+		if v, ok := n.Attribute("condnode"); !ok || v != "true" {
+			condNode = false
+		}
 	}
 
 	// Create new node.
@@ -39,6 +49,10 @@ func Merge(g Graph, prim *primitive.Primitive) (Graph, error) {
 		attr := encoding.Attribute{Key: "entry", Value: "true"}
 		newNode.SetAttribute(attr)
 	}
+	if condNode {
+		attr := encoding.Attribute{Key: "condnode", Value: "true"}
+		newNode.SetAttribute(attr)
+	}
 
 	// Connect incoming edges of nodes being deleted to new node.
 	var newEdges []Edge
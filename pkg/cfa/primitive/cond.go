@@ -0,0 +1,105 @@
+package primitive
+
+import (
+	"fmt"
+)
+
+// CondOp is the operator of a compound condition expression.
+type CondOp string
+
+// Compound condition expression operators.
+const (
+	// Logical AND of two operands, evaluated using short-circuit evaluation.
+	CondAnd CondOp = "and"
+	// Logical OR of two operands, evaluated using short-circuit evaluation.
+	CondOr CondOp = "or"
+	// Logical NOT of one operand.
+	CondNot CondOp = "not"
+	// Branch condition of a control flow graph node.
+	CondLeaf CondOp = "leaf"
+)
+
+// Cond is a compound condition (i.e. short-circuit evaluation of conditions in
+// the control flow graph) represented as a boolean expression tree; e.g.
+//
+//    {"op": "and", "args": [
+//       {"op": "leaf", "node": "3"},
+//       {"op": "not", "args": [{"op": "leaf", "node": "4"}]}
+//    ]}
+//
+// The leaves of the tree denote the condition under which the true branch of
+// the given node is taken.
+type Cond struct {
+	// Expression operator.
+	Op CondOp `json:"op"`
+	// Operands; two for AND and OR, and one for NOT.
+	Args []*Cond `json:"args,omitempty"`
+	// Node name of leaf.
+	Node string `json:"node,omitempty"`
+	// (optional) Condition of the outgoing edge of the leaf node (e.g. a switch
+	// case); the leaf denotes the condition under which the edge is taken. If
+	// empty, the leaf denotes the condition under which the true branch of the
+	// node is taken.
+	Case string `json:"case,omitempty"`
+}
+
+// And returns the logical AND of x and y.
+func And(x, y *Cond) *Cond {
+	return &Cond{Op: CondAnd, Args: []*Cond{x, y}}
+}
+
+// Or returns the logical OR of x and y.
+func Or(x, y *Cond) *Cond {
+	return &Cond{Op: CondOr, Args: []*Cond{x, y}}
+}
+
+// Not returns the logical NOT of x.
+func Not(x *Cond) *Cond {
+	return &Cond{Op: CondNot, Args: []*Cond{x}}
+}
+
+// Leaf returns the branch condition of the given node.
+func Leaf(node string) *Cond {
+	return &Cond{Op: CondLeaf, Node: node}
+}
+
+// CaseLeaf returns the condition under which the outgoing edge of the given
+// node with the given edge condition (e.g. a switch case) is taken.
+func CaseLeaf(node, cond string) *Cond {
+	return &Cond{Op: CondLeaf, Node: node, Case: cond}
+}
+
+// String returns the string representation of the compound condition; e.g.
+//
+//    "3" AND NOT "4"
+//    "5" == "case (x=1)"
+func (c *Cond) String() string {
+	switch c.Op {
+	case CondAnd, CondOr:
+		op := "AND"
+		if c.Op == CondOr {
+			op = "OR"
+		}
+		return fmt.Sprintf("%s %s %s", c.Args[0].operand(), op, c.Args[1].operand())
+	case CondNot:
+		return fmt.Sprintf("NOT %s", c.Args[0].operand())
+	case CondLeaf:
+		if len(c.Case) > 0 {
+			return fmt.Sprintf("%q == %q", c.Node, c.Case)
+		}
+		return fmt.Sprintf("%q", c.Node)
+	default:
+		panic(fmt.Errorf("support for compound condition operator %q not yet implemented", c.Op))
+	}
+}
+
+// operand returns the string representation of the compound condition as an
+// operand, enclosed in parentheses if composed of several terms.
+func (c *Cond) operand() string {
+	switch c.Op {
+	case CondAnd, CondOr:
+		return "(" + c.String() + ")"
+	default:
+		return c.String()
+	}
+}
@@ -26,6 +26,24 @@ type Primitive struct {
 	Entry string `json:"entry"`
 	// Exit node name.
 	Exit string `json:"exit,omitempty"`
+	// Annotated edges of the primitive; e.g. break and continue edges of loops.
+	Edges []Edge `json:"edges,omitempty"`
+	// Compound condition of compound condition primitives; e.g.
+	//
+	//    "3" AND NOT "4"
+	Cond *Cond `json:"cond,omitempty"`
+}
+
+// Edge is an annotated edge of a high-level control flow primitive.
+type Edge struct {
+	// Source node name.
+	From string `json:"from"`
+	// Destination node name.
+	To string `json:"to"`
+	// Edge annotation; e.g.
+	//
+	//    "break", "continue"
+	Label string `json:"label"`
 }
 
 // String returns the string representation of the high-level control flow
@@ -46,5 +64,20 @@ func (p *Primitive) String() string {
 	if len(p.Exit) > 0 {
 		fmt.Fprintf(buf, "exit: %s", p.Exit)
 	}
+	if len(p.Edges) > 0 {
+		if len(p.Exit) > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("edges:")
+		for _, e := range p.Edges {
+			fmt.Fprintf(buf, "\n   %s -> %s: %s", e.From, e.To, e.Label)
+		}
+	}
+	if p.Cond != nil {
+		if len(p.Exit) > 0 || len(p.Edges) > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(buf, "cond: %v", p.Cond)
+	}
 	return buf.String()
 }
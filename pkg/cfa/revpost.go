@@ -3,7 +3,8 @@ package cfa
 // RevPostOrder returns the nodes of the graph in reverse post-order; as
 // computed by performing a depth-first traversal of the control flow graph --
 // starting at the entry node -- and storing nodes in post-order, and finally
-// reversing the list of stored nodes.
+// reversing the list of stored nodes. Only nodes reachable from the entry node
+// are included, and the entry node is always the first node of the list.
 //
 // The benefit with reverse post-order is that it guarantees that each node of
 // the list is present before any of its successors (not taking cycles into
@@ -0,0 +1,43 @@
+package cfa
+
+// Snapshot is a copy-on-write snapshot of a control flow graph. The snapshot
+// shares the nodes and edges of the control flow graph until the graph is next
+// modified, at which point the graph is cloned into the snapshot.
+type Snapshot struct {
+	// Control flow graph of which the snapshot was taken.
+	g Graph
+	// Copy of the control flow graph at the time of the snapshot; or nil if
+	// the graph has not been modified since.
+	copy Graph
+}
+
+// NewSnapshot returns a new copy-on-write snapshot of the given control flow
+// graph. The control flow graph must invoke Copy on the snapshot before it is
+// next modified.
+func NewSnapshot(g Graph) *Snapshot {
+	return &Snapshot{g: g}
+}
+
+// Copy clones the control flow graph into the snapshot, unless already cloned.
+func (s *Snapshot) Copy() {
+	if s.copy == nil {
+		s.copy = s.g.Clone()
+	}
+}
+
+// Graph returns the control flow graph at the time of the snapshot. The
+// returned graph is shared with the control flow graph of which the snapshot
+// was taken if not modified since, and should therefore be treated as
+// read-only.
+func (s *Snapshot) Graph() Graph {
+	if s.copy != nil {
+		return s.copy
+	}
+	return s.g
+}
+
+// String returns the string representation of the snapshot in Graphviz DOT
+// format.
+func (s *Snapshot) String() string {
+	return s.Graph().String()
+}
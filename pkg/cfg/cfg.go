@@ -25,6 +25,9 @@ type Graph struct {
 	dotID string
 	// nodes maps from DOT node ID to associated node.
 	nodes map[string]cfa.Node
+	// snapshots holds the copy-on-write snapshots sharing the nodes and edges of
+	// the graph, which are cloned before the graph is next modified.
+	snapshots []*cfa.Snapshot
 }
 
 // NewGraph returns a new control flow graph.
@@ -84,6 +87,7 @@ func ParseBytesInto(data []byte, dst cfa.Graph) error {
 	if err := dot.Unmarshal(data, dst); err != nil {
 		return errors.WithStack(err)
 	}
+	mergeParallelEdges(dst)
 	// Locate entry node.
 	for nodes := dst.Nodes(); nodes.Next(); {
 		n := nodes.Node().(cfa.Node)
@@ -101,6 +105,25 @@ func ParseBytesInto(data []byte, dst cfa.Graph) error {
 	return nil
 }
 
+// mergeParallelEdges merges the conditions of parallel edges parsed into the
+// control flow graph g. The DOT decoder sets the attributes of an edge after
+// adding it to the graph, thus overwriting the conditions of parallel edges
+// merged into the edge by SetEdge.
+func mergeParallelEdges(g cfa.Graph) {
+	for nodes := g.Nodes(); nodes.Next(); {
+		from := nodes.Node()
+		for succs := g.From(from.ID()); succs.Next(); {
+			to := succs.Node()
+			e, ok := g.Edge(from.ID(), to.ID()).(*Edge)
+			if !ok || len(e.merged) == 0 {
+				continue
+			}
+			SetConds(e, unionConds(e.merged, cfa.EdgeConds(e)))
+			e.merged = nil
+		}
+	}
+}
+
 // ParseString parses the given Graphviz DOT file into a control flow graph, reading
 // from s.
 func ParseString(s string) (*Graph, error) {
@@ -118,6 +141,7 @@ func (g *Graph) NewNode() graph.Node {
 	return &Node{
 		Node:  g.DirectedGraph.NewNode(),
 		Attrs: make(Attrs),
+		g:     g,
 	}
 }
 
@@ -126,6 +150,7 @@ func (g *Graph) NewEdge(from, to graph.Node) graph.Edge {
 	return &Edge{
 		Edge:  g.DirectedGraph.NewEdge(from, to),
 		Attrs: make(Attrs),
+		g:     g,
 	}
 }
 
@@ -136,6 +161,7 @@ func (g *Graph) DOTID() string {
 
 // SetDOTID implements the dot.DOTIDSetter interface for Graph.
 func (g *Graph) SetDOTID(dotID string) {
+	g.copySnapshots()
 	g.dotID = dotID
 }
 
@@ -153,6 +179,7 @@ func (g *Graph) Entry() cfa.Node {
 
 // SetEntry sets the entry node of the control flow graph to entry.
 func (g *Graph) SetEntry(entry cfa.Node) {
+	g.copySnapshots()
 	entry.SetAttribute(encoding.Attribute{Key: "entry", Value: "true"})
 	g.entry = entry
 }
@@ -167,6 +194,7 @@ func (g *Graph) NodeWithDOTID(dotID string) (cfa.Node, bool) {
 // AddNode adds a node to the graph. AddNode panics if the added node ID matches
 // an existing node ID.
 func (g *Graph) AddNode(n graph.Node) {
+	g.copySnapshots()
 	nn := n.(cfa.Node)
 	dotID := nn.DOTID()
 	if prev, ok := g.nodes[dotID]; ok {
@@ -183,6 +211,7 @@ func (g *Graph) AddNode(n graph.Node) {
 // RemoveNode removes the node with the given ID from the graph, as well as any
 // edges attached to it. If the node is not in the graph it is a no-op.
 func (g *Graph) RemoveNode(id int64) {
+	g.copySnapshots()
 	n := g.Node(id).(cfa.Node)
 	if _, ok := n.Attribute("entry"); ok {
 		// Remove entry node.
@@ -193,6 +222,118 @@ func (g *Graph) RemoveNode(id int64) {
 	g.DirectedGraph.RemoveNode(id)
 }
 
+// SetEdge adds an edge from one node to another. If the graph already holds an
+// edge between the nodes (e.g. switch cases or conditional branch targets
+// sharing a basic block), the edges are merged into a single edge, which
+// records the conditions of both edges.
+func (g *Graph) SetEdge(e graph.Edge) {
+	g.copySnapshots()
+	from, to := e.From().ID(), e.To().ID()
+	if prev, ok := g.DirectedGraph.Edge(from, to).(cfa.Edge); ok && prev != e {
+		prevConds := cfa.EdgeConds(prev)
+		if p, ok := prev.(*Edge); ok {
+			prevConds = unionConds(p.merged, prevConds)
+		}
+		ee := e.(cfa.Edge)
+		if ee, ok := ee.(*Edge); ok {
+			// Record conditions of merged edges, should the attributes of the
+			// edge be set after adding it to the graph (e.g. by the DOT decoder).
+			ee.merged = prevConds
+		}
+		SetConds(ee, unionConds(prevConds, cfa.EdgeConds(ee)))
+	}
+	g.DirectedGraph.SetEdge(e)
+}
+
+// unionConds returns the union of the given conditions, in order of
+// occurrence.
+func unionConds(a, b []string) []string {
+	var conds []string
+	seen := make(map[string]bool)
+	for _, cond := range append(append([]string(nil), a...), b...) {
+		if !seen[cond] {
+			seen[cond] = true
+			conds = append(conds, cond)
+		}
+	}
+	return conds
+}
+
+// RemoveEdge removes the edge with the given end point IDs from the graph,
+// leaving the terminal nodes. If the edge does not exist it is a no-op.
+func (g *Graph) RemoveEdge(fid, tid int64) {
+	g.copySnapshots()
+	g.DirectedGraph.RemoveEdge(fid, tid)
+}
+
+// Clone returns a deep copy of the control flow graph, including its nodes,
+// edges and attributes.
+func (g *Graph) Clone() cfa.Graph {
+	dst := NewGraph()
+	Copy(dst, g)
+	return dst
+}
+
+// Copy copies the nodes, edges and attributes of the control flow graph src
+// into the empty control flow graph dst. Nodes and edges of dst are created by
+// dst.NewNode and dst.NewEdge. The returned map maps from node IDs of src to
+// the corresponding nodes of dst.
+func Copy(dst, src cfa.Graph) map[int64]cfa.Node {
+	dst.SetDOTID(src.DOTID())
+	srcNodes := cfa.NodesOf(src.Nodes())
+	nodes := make(map[int64]cfa.Node)
+	for _, n := range srcNodes {
+		nn := dst.NewNode().(cfa.Node)
+		nn.SetDOTID(n.DOTID())
+		for _, attr := range n.Attributes() {
+			nn.SetAttribute(attr)
+		}
+		// Note, AddNode updates the entry node of dst.
+		dst.AddNode(nn)
+		nodes[n.ID()] = nn
+	}
+	for _, n := range srcNodes {
+		for succs := src.From(n.ID()); succs.Next(); {
+			succ := succs.Node()
+			e := src.Edge(n.ID(), succ.ID()).(cfa.Edge)
+			ee := dst.NewEdge(nodes[n.ID()], nodes[succ.ID()]).(cfa.Edge)
+			for _, attr := range e.Attributes() {
+				ee.SetAttribute(attr)
+			}
+			dst.SetEdge(ee)
+		}
+	}
+	return nodes
+}
+
+// Snapshot returns a copy-on-write snapshot of the control flow graph.
+func (g *Graph) Snapshot() *cfa.Snapshot {
+	s := cfa.NewSnapshot(g)
+	g.AddSnapshot(s)
+	return s
+}
+
+// AddSnapshot adds a copy-on-write snapshot sharing the nodes and edges of the
+// graph, which is cloned before the graph is next modified. AddSnapshot is used
+// by graphs embedding Graph to take snapshots of the embedding graph.
+func (g *Graph) AddSnapshot(s *cfa.Snapshot) {
+	g.snapshots = append(g.snapshots, s)
+}
+
+// copySnapshots clones the graph into its pending copy-on-write snapshots.
+// copySnapshots is invoked before the graph, or the attributes of its nodes
+// and edges, are modified.
+func (g *Graph) copySnapshots() {
+	if g == nil || len(g.snapshots) == 0 {
+		return
+	}
+	snapshots := g.snapshots
+	g.snapshots = nil
+	for _, s := range snapshots {
+		s.Copy()
+	}
+}
+
 // String returns the string representation of the control flow graph in
 // Graphviz DOT format.
 func (g *Graph) String() string {
@@ -213,6 +354,9 @@ type Node struct {
 	Attrs
 	// DOT node ID.
 	dotID string
+	// Control flow graph of the node; or nil if not created by a control flow
+	// graph.
+	g *Graph
 }
 
 // DOTID implements the dot.Node interface for Node.
@@ -222,9 +366,22 @@ func (n *Node) DOTID() string {
 
 // SetDOTID implements the dot.DOTIDSetter interface for Node.
 func (n *Node) SetDOTID(dotID string) {
+	n.g.copySnapshots()
 	n.dotID = dotID
 }
 
+// SetAttribute implements encoding.AttributeSetter for Node.
+func (n *Node) SetAttribute(attr encoding.Attribute) error {
+	n.g.copySnapshots()
+	return n.Attrs.SetAttribute(attr)
+}
+
+// DelAttribute deletes the attribute with the given key.
+func (n *Node) DelAttribute(key string) {
+	n.g.copySnapshots()
+	n.Attrs.DelAttribute(key)
+}
+
 // --- [ Edge ] ----------------------------------------------------------------
 
 // Edge is an edge of the control flow graph.
@@ -233,6 +390,23 @@ type Edge struct {
 	graph.Edge
 	// Edge attributes.
 	Attrs
+	// Control flow graph of the edge; or nil if not created by a control flow
+	// graph.
+	g *Graph
+	// Conditions of parallel edges merged into the edge by SetEdge.
+	merged []string
+}
+
+// SetAttribute implements encoding.AttributeSetter for Edge.
+func (e *Edge) SetAttribute(attr encoding.Attribute) error {
+	e.g.copySnapshots()
+	return e.Attrs.SetAttribute(attr)
+}
+
+// DelAttribute deletes the attribute with the given key.
+func (e *Edge) DelAttribute(key string) {
+	e.g.copySnapshots()
+	e.Attrs.DelAttribute(key)
 }
 
 // --- [ Attributes ] ----------------------------------------------------------
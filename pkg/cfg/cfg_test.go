@@ -0,0 +1,250 @@
+package cfg
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
+	"gonum.org/v1/gonum/graph/encoding"
+)
+
+func TestParseParallelEdges(t *testing.T) {
+	const in = `
+digraph f {
+	A [entry=true]
+	A -> B [cond="case (%x=1)"]
+	A -> B [cond="case (%x=2)"]
+	A -> B [cond="case (%x=3)"]
+	A -> C [cond="x, y"]
+	A -> C [cond="z"]
+	A -> D [cond="default case"]
+}`
+	golden := []struct {
+		from, to string
+		want     []string
+	}{
+		{from: "A", to: "B", want: []string{"case (%x=1)", "case (%x=2)", "case (%x=3)"}},
+		// Conditions containing commas.
+		{from: "A", to: "C", want: []string{"x, y", "z"}},
+		{from: "A", to: "D", want: []string{"default case"}},
+	}
+	g, err := ParseString(in[1:])
+	if err != nil {
+		t.Fatalf("unable to parse control flow graph; %+v", err)
+	}
+	// Parse the control flow graph a second time, to verify that the conditions
+	// of merged edges survive a round-trip through the DOT format.
+	h, err := ParseString(g.String())
+	if err != nil {
+		t.Fatalf("unable to parse control flow graph; %+v", err)
+	}
+	for _, graph := range []*Graph{g, h} {
+		for _, gg := range golden {
+			got := edgeConds(t, graph, gg.from, gg.to)
+			if !reflect.DeepEqual(gg.want, got) {
+				t.Errorf("%s -> %s: conditions mismatch; expected %q, got %q", gg.from, gg.to, gg.want, got)
+			}
+		}
+	}
+}
+
+func TestMergeParallelEdges(t *testing.T) {
+	const in = `
+digraph f {
+	A [entry=true]
+	A -> B [cond="true"]
+	A -> C [cond="false"]
+	B -> D
+	C -> D
+}`
+	g, err := ParseString(in[1:])
+	if err != nil {
+		t.Fatalf("unable to parse control flow graph; %+v", err)
+	}
+	prim := &primitive.Primitive{
+		Prim:  "merged",
+		Nodes: map[string]string{"b": "B", "c": "C"},
+		Entry: "B",
+	}
+	if _, err := cfa.Merge(g, prim); err != nil {
+		t.Fatalf("unable to merge primitive; %+v", err)
+	}
+	// The order of merged edges depends on the order of merged nodes.
+	got := edgeConds(t, g, "A", "B")
+	sort.Strings(got)
+	want := []string{"false", "true"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("A -> B: conditions mismatch; expected %q, got %q", want, got)
+	}
+	if got := edgeConds(t, g, "B", "D"); len(got) != 0 {
+		t.Errorf("B -> D: conditions mismatch; expected none, got %q", got)
+	}
+}
+
+// edgeConds returns the conditions of the edge between the nodes with the given
+// DOT node IDs in g.
+func edgeConds(t *testing.T, g cfa.Graph, from, to string) []string {
+	f, ok := g.NodeWithDOTID(from)
+	if !ok {
+		t.Fatalf("unable to locate node %q", from)
+	}
+	tt, ok := g.NodeWithDOTID(to)
+	if !ok {
+		t.Fatalf("unable to locate node %q", to)
+	}
+	e, ok := g.Edge(f.ID(), tt.ID()).(cfa.Edge)
+	if !ok {
+		t.Fatalf("unable to locate edge %s -> %s", from, to)
+	}
+	return cfa.EdgeConds(e)
+}
+
+func TestSnapshot(t *testing.T) {
+	const in = `
+digraph f {
+	A [entry=true]
+	A -> B [cond="true"]
+	A -> C [cond="false"]
+	B -> D
+	C -> D
+}`
+	golden := []struct {
+		name string
+		// Modification of the control flow graph after taking the snapshot.
+		modify func(t *testing.T, g *Graph)
+	}{
+		{
+			name: "SetEdge",
+			modify: func(t *testing.T, g *Graph) {
+				b := nodeWithDOTID(t, g, "B")
+				c := nodeWithDOTID(t, g, "C")
+				g.SetEdge(g.NewEdge(b, c))
+			},
+		},
+		{
+			name: "RemoveNode",
+			modify: func(t *testing.T, g *Graph) {
+				g.RemoveNode(nodeWithDOTID(t, g, "C").ID())
+			},
+		},
+		{
+			name: "SetAttribute node",
+			modify: func(t *testing.T, g *Graph) {
+				nodeWithDOTID(t, g, "D").SetAttribute(encoding.Attribute{Key: "label", Value: "exit"})
+			},
+		},
+		{
+			name: "SetAttribute edge",
+			modify: func(t *testing.T, g *Graph) {
+				a := nodeWithDOTID(t, g, "A")
+				b := nodeWithDOTID(t, g, "B")
+				g.Edge(a.ID(), b.ID()).(cfa.Edge).SetAttribute(encoding.Attribute{Key: "cond", Value: "x"})
+			},
+		},
+	}
+	for _, gg := range golden {
+		g, err := ParseString(in[1:])
+		if err != nil {
+			t.Fatalf("unable to parse control flow graph; %+v", err)
+		}
+		want := graphString(g)
+		s := g.Snapshot()
+		gg.modify(t, g)
+		if got := graphString(s.Graph()); got != want {
+			t.Errorf("%q: snapshot mismatch; expected `%s`, got `%s`", gg.name, want, got)
+		}
+		if graphString(g) == want {
+			t.Errorf("%q: control flow graph not modified", gg.name)
+		}
+		// Subsequent modifications are not reflected in the snapshot.
+		g.RemoveNode(nodeWithDOTID(t, g, "D").ID())
+		if got := graphString(s.Graph()); got != want {
+			t.Errorf("%q: snapshot mismatch after subsequent modification; expected `%s`, got `%s`", gg.name, want, got)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	const in = `
+digraph f {
+	A [entry=true label="a"]
+	A -> B [cond="true"]
+	A -> C [cond="false"]
+	B -> D
+	C -> D
+}`
+	g, err := ParseString(in[1:])
+	if err != nil {
+		t.Fatalf("unable to parse control flow graph; %+v", err)
+	}
+	want := graphString(g)
+	h := g.Clone()
+	if got := graphString(h); got != want {
+		t.Errorf("clone mismatch; expected `%s`, got `%s`", want, got)
+	}
+	if got := h.DOTID(); got != "f" {
+		t.Errorf("DOT graph ID mismatch; expected %q, got %q", "f", got)
+	}
+	// Nodes of the clone are distinct from the nodes of the original graph.
+	for _, n := range cfa.NodesOf(g.Nodes()) {
+		nn, ok := h.NodeWithDOTID(n.DOTID())
+		if !ok {
+			t.Errorf("unable to locate node %q in clone", n.DOTID())
+			continue
+		}
+		if nn == n {
+			t.Errorf("node %q shared between clone and original graph", n.DOTID())
+		}
+		if !reflect.DeepEqual(n.Attributes(), nn.Attributes()) {
+			t.Errorf("node %q: attributes mismatch; expected %v, got %v", n.DOTID(), n.Attributes(), nn.Attributes())
+		}
+	}
+	entry := h.Entry()
+	if entry == nil || entry.DOTID() != "A" {
+		t.Fatalf("entry node mismatch; expected %q, got %v", "A", entry)
+	}
+	if entry == g.Entry() {
+		t.Errorf("entry node shared between clone and original graph")
+	}
+	if got := edgeConds(t, h, "A", "C"); !reflect.DeepEqual(got, []string{"false"}) {
+		t.Errorf("A -> C: conditions mismatch; expected %q, got %q", []string{"false"}, got)
+	}
+	// Modifications of the clone are not reflected in the original graph.
+	h.RemoveNode(entry.ID())
+	nodeWithDOTID(t, h, "D").SetAttribute(encoding.Attribute{Key: "label", Value: "exit"})
+	if got := graphString(g); got != want {
+		t.Errorf("original graph modified through clone; expected `%s`, got `%s`", want, got)
+	}
+	if g.Entry() == nil {
+		t.Errorf("entry node of original graph removed through clone")
+	}
+}
+
+// nodeWithDOTID returns the node with the given DOT node ID in g.
+func nodeWithDOTID(t *testing.T, g cfa.Graph, dotID string) cfa.Node {
+	n, ok := g.NodeWithDOTID(dotID)
+	if !ok {
+		t.Fatalf("unable to locate node %q", dotID)
+	}
+	return n
+}
+
+// graphString returns a string representation of the nodes and edges of g and
+// their attributes, independent of node order.
+func graphString(g cfa.Graph) string {
+	var lines []string
+	for _, n := range cfa.NodesOf(g.Nodes()) {
+		lines = append(lines, fmt.Sprintf("%s %v", n.DOTID(), n.Attributes()))
+		for succs := g.From(n.ID()); succs.Next(); {
+			succ := succs.Node().(cfa.Node)
+			e := g.Edge(n.ID(), succ.ID()).(cfa.Edge)
+			lines = append(lines, fmt.Sprintf("%s -> %s %v", n.DOTID(), succ.DOTID(), e.Attributes()))
+		}
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
@@ -2,6 +2,7 @@ package cfg
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/llir/llvm/ir"
 	"github.com/mewmew/lnp/pkg/cfa"
@@ -37,6 +38,9 @@ func NewGraphFromFunc(f *ir.Func) *Graph {
 			targetFalse := nodeWithName(g, term.TargetFalse.Name())
 			edgeWithLabel(g, from, targetTrue, "true")
 			edgeWithLabel(g, from, targetFalse, "false")
+			if isCondBlock(block) {
+				from.SetAttribute(encoding.Attribute{Key: "condnode", Value: "true"})
+			}
 		case *ir.TermSwitch:
 			for _, c := range term.Cases {
 				to := nodeWithName(g, c.Target.Name())
@@ -46,11 +50,33 @@ func NewGraphFromFunc(f *ir.Func) *Graph {
 			to := nodeWithName(g, term.TargetDefault.Name())
 			edgeWithLabel(g, from, to, "default case")
 		//case *ir.TermIndirectBr:
-		//case *ir.TermInvoke:
-		//case *ir.TermResume:
-		//case *ir.TermCatchSwitch:
-		//case *ir.TermCatchRet:
-		//case *ir.TermCleanupRet:
+		case *ir.TermInvoke:
+			// The unwind edge leads to the landing pad, which is the entry of a
+			// separate region reached through exceptional control flow.
+			succs := term.Succs()
+			normal := nodeWithName(g, succs[0].Name())
+			unwind := nodeWithName(g, succs[1].Name())
+			edgeWithLabel(g, from, normal, "normal")
+			edgeWithLabel(g, from, unwind, "unwind")
+		case *ir.TermResume:
+			// nothing to do.
+		case *ir.TermCatchSwitch:
+			for _, succ := range term.Succs() {
+				to := nodeWithName(g, succ.Name())
+				edgeWithLabel(g, from, to, "")
+			}
+		case *ir.TermCatchRet:
+			for _, succ := range term.Succs() {
+				to := nodeWithName(g, succ.Name())
+				edgeWithLabel(g, from, to, "")
+			}
+		case *ir.TermCleanupRet:
+			// Note, cleanupret without unwind target continues unwinding to the
+			// caller.
+			for _, succ := range term.Succs() {
+				to := nodeWithName(g, succ.Name())
+				edgeWithLabel(g, from, to, "unwind")
+			}
 		case *ir.TermUnreachable:
 			// nothing to do.
 		default:
@@ -63,25 +89,65 @@ func NewGraphFromFunc(f *ir.Func) *Graph {
 // ### [ Helper functions ] ####################################################
 
 // edgeWithLabel adds a directed edge between the specified nodes and assignes
-// it the given label.
+// it the given label. Parallel edges are merged into a single edge, which
+// records the labels of each merged edge.
 func edgeWithLabel(g cfa.Graph, from, to cfa.Node, label string) cfa.Edge {
 	e := g.NewEdge(from, to).(cfa.Edge)
 	if len(label) > 0 {
-		// Skip label for true and false, just colour edge.
-		switch label {
-		case "true":
-			e.SetAttribute(encoding.Attribute{Key: "color", Value: "darkgreen"})
-		case "false":
-			e.SetAttribute(encoding.Attribute{Key: "color", Value: "red"})
-		default:
-			e.SetAttribute(encoding.Attribute{Key: "label", Value: label})
-		}
-		e.SetAttribute(encoding.Attribute{Key: "cond", Value: label})
+		SetConds(e, []string{label})
 	}
 	g.SetEdge(e)
 	return e
 }
 
+// SetConds sets the "cond" attributes of the given edge to the given
+// conditions, and assigns it a matching label or colour.
+func SetConds(e cfa.Edge, conds []string) {
+	e.DelAttribute("color")
+	e.DelAttribute("label")
+	e.DelAttribute("style")
+	for i := range cfa.EdgeConds(e) {
+		e.DelAttribute(cfa.CondKey(i))
+	}
+	if len(conds) == 0 {
+		return
+	}
+	for i, cond := range conds {
+		e.SetAttribute(encoding.Attribute{Key: cfa.CondKey(i), Value: cond})
+	}
+	// Note, the label is only used for presentation; the conditions of the edge
+	// are recorded by its "cond" attributes.
+	label := strings.Join(conds, ", ")
+	// Skip label for true and false, just colour edge.
+	switch label {
+	case "true":
+		e.SetAttribute(encoding.Attribute{Key: "color", Value: "darkgreen"})
+	case "false":
+		e.SetAttribute(encoding.Attribute{Key: "color", Value: "red"})
+	case "normal":
+		// Skip label for normal return of invoke.
+	case "unwind":
+		e.SetAttribute(encoding.Attribute{Key: "style", Value: "dashed"})
+	default:
+		e.SetAttribute(encoding.Attribute{Key: "label", Value: label})
+	}
+}
+
+// isCondBlock reports whether the given basic block contains only conditional
+// and branching information (i.e. comparison instructions and a conditional
+// branch terminator), and may thus be evaluated as part of a compound condition.
+func isCondBlock(block *ir.Block) bool {
+	for _, inst := range block.Insts {
+		switch inst.(type) {
+		case *ir.InstICmp, *ir.InstFCmp:
+			// comparison instructions have no side effects.
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // nodeWithName returns the node of the given name. A new node is created if not
 // yet present in the control flow graph.
 func nodeWithName(g cfa.Graph, name string) cfa.Node {
@@ -93,3 +159,14 @@ func nodeWithName(g cfa.Graph, name string) cfa.Node {
 	g.AddNode(n)
 	return n
 }
+
+// IsUnwindEdge reports whether the given edge is an unwind edge of an invoke
+// terminator (or cleanupret), leading to a landing pad.
+func IsUnwindEdge(e cfa.Edge) bool {
+	for _, cond := range cfa.EdgeConds(e) {
+		if cond == "unwind" {
+			return true
+		}
+	}
+	return false
+}
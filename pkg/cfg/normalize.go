@@ -0,0 +1,102 @@
+package cfg
+
+import (
+	"fmt"
+
+	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/graph/encoding"
+)
+
+// Normalize normalizes the control flow graph in place, to simplify control
+// flow recovery. Nodes inserted by Normalize are not present in the original
+// function, and are marked by a "virtual" attribute.
+//
+// Normalize performs the following transformations:
+//
+//    1. Nodes unreachable from the entry node are pruned.
+//
+//    2. A pre-header is inserted before an entry node with predecessors (e.g.
+//       a loop header), so that the entry node of the control flow graph has
+//       no predecessors.
+//
+//    3. A unique virtual exit node is added as the successor of every exit
+//       node, if the control flow graph has more than one exit node.
+//
+//    4. Critical edges (i.e. edges from nodes with several successors to nodes
+//       with several predecessors) are split by inserting an empty node.
+func Normalize(g cfa.Graph) error {
+	entry := g.Entry()
+	if entry == nil {
+		return errors.Errorf("unable to locate entry node of control flow graph %q", g.DOTID())
+	}
+	// Prune unreachable nodes.
+	reachable := Reachable(g)
+	for _, n := range cfa.NodesOf(g.Nodes()) {
+		if !reachable[n.ID()] {
+			g.RemoveNode(n.ID())
+		}
+	}
+	// Insert pre-header before entry node with predecessors.
+	if g.To(entry.ID()).Len() > 0 {
+		preHeader := addVirtualNode(g, entry.DOTID()+"_preheader")
+		entry.DelAttribute("entry")
+		g.SetEntry(preHeader)
+		edgeWithLabel(g, preHeader, entry, "")
+	}
+	// Add unique virtual exit node.
+	if exits := exitNodes(g); len(exits) > 1 {
+		exit := addVirtualNode(g, "exit")
+		for _, n := range exits {
+			edgeWithLabel(g, n, exit, "")
+		}
+	}
+	// Split critical edges.
+	for _, n := range cfa.NodesOf(g.Nodes()) {
+		succs := cfa.NodesOf(g.From(n.ID()))
+		if len(succs) < 2 {
+			continue
+		}
+		for _, succ := range succs {
+			if g.To(succ.ID()).Len() < 2 {
+				continue
+			}
+			splitEdge(g, n, succ)
+		}
+	}
+	return nil
+}
+
+// splitEdge splits the edge from -> to of the control flow graph by inserting
+// an empty node, which retains the attributes of the edge.
+//
+//    from -> from_to -> to
+func splitEdge(g cfa.Graph, from, to cfa.Node) {
+	e := g.Edge(from.ID(), to.ID()).(cfa.Edge)
+	mid := addVirtualNode(g, fmt.Sprintf("%s_%s", from.DOTID(), to.DOTID()))
+	g.RemoveEdge(from.ID(), to.ID())
+	ee := g.NewEdge(from, mid).(cfa.Edge)
+	for _, attr := range e.Attributes() {
+		ee.SetAttribute(attr)
+	}
+	g.SetEdge(ee)
+	edgeWithLabel(g, mid, to, "")
+}
+
+// addVirtualNode adds a new virtual node to the control flow graph, with a
+// unique DOT node ID based on the given name.
+func addVirtualNode(g cfa.Graph, name string) cfa.Node {
+	dotID := name
+	for i := 1; ; i++ {
+		if _, ok := g.NodeWithDOTID(dotID); !ok {
+			break
+		}
+		dotID = fmt.Sprintf("%s_%d", name, i)
+	}
+	n := g.NewNode().(cfa.Node)
+	n.SetDOTID(dotID)
+	n.SetAttribute(encoding.Attribute{Key: "virtual", Value: "true"})
+	n.SetAttribute(encoding.Attribute{Key: "style", Value: "dashed"})
+	g.AddNode(n)
+	return n
+}
@@ -0,0 +1,105 @@
+package cfg
+
+import (
+	"sort"
+
+	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/rickypai/natsort"
+)
+
+// Regions splits the given control flow graph into single-entry regions
+// separated by unwind edges, which lead to landing pads reached through
+// exceptional control flow.
+//
+// The first region is rooted at the entry node of g, and each subsequent region
+// is rooted at a landing pad not part of a previous region. A region contains
+// the nodes reachable from its root through non-unwind edges which are not part
+// of a previous region; edges leaving the region are omitted. A control flow
+// graph without unwind edges forms a single region, and is returned unmodified.
+func Regions(g cfa.Graph) []cfa.Graph {
+	if !hasUnwindEdge(g) {
+		return []cfa.Graph{g}
+	}
+	// region maps from node ID to the index of the region of the node.
+	region := make(map[int64]int)
+	var roots []cfa.Node
+	queue := []cfa.Node{g.Entry()}
+	for len(queue) > 0 {
+		root := queue[0]
+		queue = queue[1:]
+		if _, ok := region[root.ID()]; ok {
+			continue
+		}
+		i := len(roots)
+		roots = append(roots, root)
+		region[root.ID()] = i
+		stack := []cfa.Node{root}
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			succs := cfa.NodesOf(g.From(n.ID()))
+			sort.Slice(succs, func(i, j int) bool {
+				return natsort.Less(succs[i].DOTID(), succs[j].DOTID())
+			})
+			for _, succ := range succs {
+				// Note: This run-time type assertion goes away, should Gonum graph
+				// start to leverage generics in Go2.
+				e := g.Edge(n.ID(), succ.ID()).(cfa.Edge)
+				if IsUnwindEdge(e) {
+					// Landing pad.
+					queue = append(queue, succ)
+					continue
+				}
+				if _, ok := region[succ.ID()]; ok {
+					continue
+				}
+				region[succ.ID()] = i
+				stack = append(stack, succ)
+			}
+		}
+	}
+	var regions []cfa.Graph
+	for i, root := range roots {
+		r := g.Clone()
+		for _, n := range cfa.NodesOf(g.Nodes()) {
+			if j, ok := region[n.ID()]; !ok || j != i {
+				// Note, node IDs of the clone may differ from node IDs of g.
+				rn, _ := r.NodeWithDOTID(n.DOTID())
+				r.RemoveNode(rn.ID())
+			}
+		}
+		// Remove unwind edges within the region (e.g. of an invoke terminator
+		// unwinding to a landing pad which dominates the invoke).
+		for _, n := range cfa.NodesOf(r.Nodes()) {
+			for _, succ := range cfa.NodesOf(r.From(n.ID())) {
+				if IsUnwindEdge(r.Edge(n.ID(), succ.ID()).(cfa.Edge)) {
+					r.RemoveEdge(n.ID(), succ.ID())
+				}
+			}
+		}
+		if i > 0 {
+			// Landing pads are entry nodes of their regions.
+			entry, _ := r.NodeWithDOTID(root.DOTID())
+			r.SetEntry(entry)
+		}
+		regions = append(regions, r)
+	}
+	return regions
+}
+
+// hasUnwindEdge reports whether the given control flow graph contains unwind
+// edges.
+func hasUnwindEdge(g cfa.Graph) bool {
+	for nodes := g.Nodes(); nodes.Next(); {
+		n := nodes.Node()
+		for succs := g.From(n.ID()); succs.Next(); {
+			succ := succs.Node()
+			// Note: This run-time type assertion goes away, should Gonum graph
+			// start to leverage generics in Go2.
+			if IsUnwindEdge(g.Edge(n.ID(), succ.ID()).(cfa.Edge)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package cfg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mewmew/lnp/pkg/cfa"
+	"github.com/rickypai/natsort"
+)
+
+//go:generate stringer -linecomment -type DiagKind
+
+// DiagKind is the set of control flow graph problems located by Validate.
+type DiagKind uint8
+
+// Control flow graph problems.
+const (
+	// Missing entry node.
+	DiagNoEntry DiagKind = iota // no_entry
+	// Nodes unreachable from the entry node.
+	DiagUnreachable // unreachable
+	// Edges to nodes not present in the control flow graph (i.e. end points of
+	// edges added implicitly by SetEdge, which are not located by DOT node ID).
+	DiagMissingNode // missing_node
+	// More than one exit node (i.e. node without successors).
+	DiagMultipleExits // multiple_exits
+	// No exit node.
+	DiagNoExit // no_exit
+	// Entry node with predecessors (e.g. self-loop on the entry node).
+	DiagEntryLoop // entry_loop
+)
+
+// Diagnostic is a problem of a control flow graph located by Validate.
+type Diagnostic struct {
+	// Kind of problem.
+	Kind DiagKind
+	// DOT node IDs of the nodes involved, in natural sort order.
+	Nodes []string
+}
+
+// Error returns the string representation of the diagnostic.
+func (d *Diagnostic) Error() string {
+	var msg string
+	switch d.Kind {
+	case DiagNoEntry:
+		msg = "unable to locate entry node"
+	case DiagUnreachable:
+		msg = "nodes unreachable from entry node"
+	case DiagMissingNode:
+		msg = "edges to nodes not present in control flow graph"
+	case DiagMultipleExits:
+		msg = "multiple exit nodes"
+	case DiagNoExit:
+		msg = "unable to locate exit node"
+	case DiagEntryLoop:
+		msg = "entry node with predecessors"
+	default:
+		panic(fmt.Errorf("support for diagnostic kind %v not yet implemented", d.Kind))
+	}
+	if len(d.Nodes) > 0 {
+		return fmt.Sprintf("%s: %s", msg, strings.Join(d.Nodes, ", "))
+	}
+	return msg
+}
+
+// Validate validates the control flow graph, and returns the located problems
+// which confuse control flow recovery methods.
+func Validate(g cfa.Graph) []*Diagnostic {
+	var diags []*Diagnostic
+	add := func(kind DiagKind, nodes []string) {
+		less := func(i, j int) bool {
+			return natsort.Less(nodes[i], nodes[j])
+		}
+		sort.Slice(nodes, less)
+		diags = append(diags, &Diagnostic{Kind: kind, Nodes: nodes})
+	}
+	// Edges to missing nodes. The underlying graph implicitly adds the end
+	// points of an edge not present in the graph, bypassing AddNode, so such
+	// nodes are not located by their DOT node ID.
+	var missing []string
+	for _, n := range cfa.NodesOf(g.Nodes()) {
+		if nn, ok := g.NodeWithDOTID(n.DOTID()); !ok || nn.ID() != n.ID() {
+			missing = append(missing, n.DOTID())
+		}
+	}
+	if len(missing) > 0 {
+		add(DiagMissingNode, missing)
+	}
+	entry := g.Entry()
+	if entry == nil {
+		add(DiagNoEntry, nil)
+		return diags
+	}
+	// Unreachable nodes.
+	reachable := Reachable(g)
+	var unreachable []string
+	for _, n := range cfa.NodesOf(g.Nodes()) {
+		if !reachable[n.ID()] {
+			unreachable = append(unreachable, n.DOTID())
+		}
+	}
+	if len(unreachable) > 0 {
+		add(DiagUnreachable, unreachable)
+	}
+	// Exit nodes.
+	exits := exitNodes(g)
+	switch {
+	case len(exits) == 0:
+		add(DiagNoExit, nil)
+	case len(exits) > 1:
+		var nodes []string
+		for _, exit := range exits {
+			nodes = append(nodes, exit.DOTID())
+		}
+		add(DiagMultipleExits, nodes)
+	}
+	// Entry loops.
+	if preds := cfa.NodesOf(g.To(entry.ID())); len(preds) > 0 {
+		var nodes []string
+		for _, pred := range preds {
+			nodes = append(nodes, pred.DOTID())
+		}
+		add(DiagEntryLoop, nodes)
+	}
+	return diags
+}
+
+// Reachable returns the set of nodes reachable from the entry node of the
+// control flow graph, indexed by node ID.
+func Reachable(g cfa.Graph) map[int64]bool {
+	reachable := make(map[int64]bool)
+	pre := func(n cfa.Node) {
+		reachable[n.ID()] = true
+	}
+	cfa.DFS(g, pre, nil)
+	return reachable
+}
+
+// exitNodes returns the exit nodes (i.e. nodes without successors) of the
+// control flow graph, in natural sort order of their DOT node IDs.
+func exitNodes(g cfa.Graph) []cfa.Node {
+	var exits []cfa.Node
+	for _, n := range cfa.NodesOf(g.Nodes()) {
+		if g.From(n.ID()).Len() == 0 {
+			exits = append(exits, n)
+		}
+	}
+	less := func(i, j int) bool {
+		return natsort.Less(exits[i].DOTID(), exits[j].DOTID())
+	}
+	sort.Slice(exits, less)
+	return exits
+}
@@ -0,0 +1,157 @@
+package cfg
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/mewmew/lnp/pkg/cfa"
+)
+
+func TestValidate(t *testing.T) {
+	golden := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{
+			name: "valid",
+			in: `digraph f {
+	A [entry=true]
+	A -> B
+}`,
+			want: nil,
+		},
+		{
+			name: "unreachable",
+			in: `digraph f {
+	A [entry=true]
+	A -> B
+	U -> B
+}`,
+			want: []string{"nodes unreachable from entry node: U"},
+		},
+		{
+			name: "multiple_exits",
+			in: `digraph f {
+	A [entry=true]
+	A -> C [cond="false"]
+	A -> B [cond="true"]
+}`,
+			want: []string{"multiple exit nodes: B, C"},
+		},
+		{
+			name: "no_exit_entry_loop",
+			in: `digraph f {
+	A [entry=true]
+	A -> B
+	B -> A
+}`,
+			want: []string{"unable to locate exit node", "entry node with predecessors: B"},
+		},
+	}
+	for _, g := range golden {
+		graph, err := ParseString(g.in)
+		if err != nil {
+			t.Errorf("%q: unable to parse control flow graph; %+v", g.name, err)
+			continue
+		}
+		got := diagErrors(Validate(graph))
+		if !reflect.DeepEqual(g.want, got) {
+			t.Errorf("%q: diagnostics mismatch; expected %q, got %q", g.name, g.want, got)
+		}
+	}
+}
+
+func TestValidateMissingNode(t *testing.T) {
+	g := NewGraph()
+	a := g.NewNode().(cfa.Node)
+	a.SetDOTID("A")
+	g.AddNode(a)
+	g.SetEntry(a)
+	// Add edge to node B not added to the control flow graph.
+	b := g.NewNode().(cfa.Node)
+	b.SetDOTID("B")
+	g.SetEdge(g.NewEdge(a, b))
+	got := diagErrors(Validate(g))
+	want := []string{"edges to nodes not present in control flow graph: B"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("diagnostics mismatch; expected %q, got %q", want, got)
+	}
+}
+
+func TestValidateNoEntry(t *testing.T) {
+	g := NewGraph()
+	a := g.NewNode().(cfa.Node)
+	a.SetDOTID("A")
+	g.AddNode(a)
+	got := diagErrors(Validate(g))
+	want := []string{"unable to locate entry node"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("diagnostics mismatch; expected %q, got %q", want, got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	const in = `
+digraph f {
+	A [entry=true]
+	A -> B [cond="true"]
+	A -> C [cond="false"]
+	B -> C [cond="true"]
+	B -> D [cond="false"]
+	C -> A [cond="true"]
+	C -> E [cond="false"]
+	U -> C
+}`
+	g, err := ParseString(in[1:])
+	if err != nil {
+		t.Fatalf("unable to parse control flow graph; %+v", err)
+	}
+	if err := Normalize(g); err != nil {
+		t.Fatalf("unable to normalize control flow graph; %+v", err)
+	}
+	if diags := Validate(g); len(diags) > 0 {
+		t.Errorf("invalid normalized control flow graph; %q", diagErrors(diags))
+	}
+	// The unreachable node U is pruned, a pre-header is inserted before the
+	// entry node A, a virtual exit node is added after the exit nodes D and E,
+	// and the critical edges A -> C, B -> C and C -> A are split.
+	var got []string
+	for _, n := range cfa.NodesOf(g.Nodes()) {
+		got = append(got, n.DOTID())
+	}
+	sort.Strings(got)
+	want := []string{"A", "A_C", "A_preheader", "B", "B_C", "C", "C_A", "D", "E", "exit"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("nodes mismatch; expected %q, got %q", want, got)
+	}
+	if entry := g.Entry(); entry == nil || entry.DOTID() != "A_preheader" {
+		t.Errorf("entry node mismatch; expected %q, got %v", "A_preheader", entry)
+	}
+	// Split edges retain their conditions.
+	if got, want := edgeConds(t, g, "A", "A_C"), []string{"false"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("A -> A_C: conditions mismatch; expected %q, got %q", want, got)
+	}
+	// No critical edges remain.
+	for _, n := range cfa.NodesOf(g.Nodes()) {
+		succs := cfa.NodesOf(g.From(n.ID()))
+		if len(succs) < 2 {
+			continue
+		}
+		for _, succ := range succs {
+			if g.To(succ.ID()).Len() > 1 {
+				t.Errorf("critical edge %s -> %s not split", n.DOTID(), succ.DOTID())
+			}
+		}
+	}
+}
+
+// diagErrors returns the error messages of the given diagnostics.
+func diagErrors(diags []*Diagnostic) []string {
+	var errs []string
+	for _, diag := range diags {
+		errs = append(errs, diag.Error())
+	}
+	return errs
+}
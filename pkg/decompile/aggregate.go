@@ -0,0 +1,302 @@
+package decompile
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// indexMultiResultFuncs indexes the LLVM IR functions lifted to Go functions
+// with multiple results.
+//
+// A function is lifted to a Go function with multiple results if its return
+// type is an anonymous struct type, and the function is only ever called
+// directly with a result that is only ever destructured by extractvalue
+// instructions; e.g.
+//
+//    %r = call { i64, i1 } @f()
+//    %a = extractvalue { i64, i1 } %r, 0
+//    %ok = extractvalue { i64, i1 } %r, 1
+//
+// is lifted to
+//
+//    a, ok = f()
+//
+// post-condition: gen.multiResults maps from global identifier (without '@'
+// prefix) to functions lifted to Go functions with multiple results.
+func (gen *Generator) indexMultiResultFuncs() {
+	cands := make(map[*ir.Func]bool)
+	for _, irFunc := range gen.m.Funcs {
+		if isMultiResult(irFunc.Sig.RetType) {
+			cands[irFunc] = true
+		}
+	}
+	if len(cands) == 0 {
+		return
+	}
+	// Index direct calls to candidate functions.
+	calls := make(map[value.Value]*ir.Func)
+	for _, irFunc := range gen.m.Funcs {
+		for _, block := range irFunc.Blocks {
+			for _, inst := range block.Insts {
+				if call, ok := inst.(*ir.InstCall); ok {
+					if callee, ok := call.Callee.(*ir.Func); ok && cands[callee] {
+						calls[call] = callee
+					}
+				}
+			}
+		}
+	}
+	// Discard candidates used other than as callees of call instructions, or
+	// with results used other than by extractvalue instructions.
+	check := func(v value.Value) {
+		checkMultiResultUses(cands, calls, v)
+	}
+	for _, irGlobal := range gen.m.Globals {
+		if irGlobal.Init != nil {
+			check(irGlobal.Init)
+		}
+	}
+	for _, irAlias := range gen.m.Aliases {
+		check(irAlias.Aliasee)
+	}
+	for _, irFunc := range gen.m.Funcs {
+		for _, block := range irFunc.Blocks {
+			for _, inst := range block.Insts {
+				switch inst := inst.(type) {
+				case *ir.InstCall:
+					if _, ok := calls[inst]; !ok {
+						check(inst.Callee)
+					}
+					for _, arg := range inst.Args {
+						check(arg)
+					}
+				case *ir.InstExtractValue:
+					if _, ok := calls[inst.X]; !ok {
+						check(inst.X)
+					}
+				default:
+					for _, op := range instOperands(inst) {
+						check(op)
+					}
+				}
+			}
+			for _, op := range termOperands(block.Term) {
+				check(op)
+			}
+		}
+	}
+	for irFunc := range cands {
+		gen.multiResults[irFunc.Name()] = true
+	}
+}
+
+// checkMultiResultUses removes the candidate functions from cands which are
+// used by v other than as callees of call instructions, or with results used
+// by v.
+func checkMultiResultUses(cands map[*ir.Func]bool, calls map[value.Value]*ir.Func, v value.Value) {
+	if callee, ok := calls[v]; ok {
+		delete(cands, callee)
+		return
+	}
+	switch v := v.(type) {
+	case *ir.Func:
+		delete(cands, v)
+	case constant.Constant:
+		for _, op := range constOperands(v) {
+			checkMultiResultUses(cands, calls, op)
+		}
+	}
+}
+
+// isMultiResult reports whether the given LLVM IR return type is an anonymous
+// struct type with more than one field.
+func isMultiResult(retType types.Type) bool {
+	t, ok := retType.(*types.StructType)
+	return ok && len(t.Name()) == 0 && len(t.Fields) > 1
+}
+
+// goMultiResults returns the Go function results corresponding to the fields
+// of the given Go struct type.
+func goMultiResults(t *gotypes.Struct) *gotypes.Tuple {
+	var results []*gotypes.Var
+	for i := 0; i < t.NumFields(); i++ {
+		result := gotypes.NewVar(0, nil, "", t.Field(i).Type())
+		results = append(results, result)
+	}
+	return gotypes.NewTuple(results...)
+}
+
+// isMultiResultCall reports whether the given LLVM IR value is a direct call to
+// a function lifted to a Go function with multiple results.
+func (gen *Generator) isMultiResultCall(v value.Value) bool {
+	call, ok := v.(*ir.InstCall)
+	if !ok {
+		return false
+	}
+	callee, ok := call.Callee.(*ir.Func)
+	return ok && gen.multiResults[callee.Name()]
+}
+
+// indexResults indexes the names of the results of calls to functions lifted to
+// Go functions with multiple results, within the given LLVM IR function.
+//
+// The first extractvalue instruction extracting a given result is folded into
+// the assignment of the call, and subsequently lifted to nothing. Results which
+// are extracted from but not yet named are assigned to temporary variables.
+//
+// post-condition: fgen.results maps from call instruction to the Go names of
+// its results, where "_" denotes unused results.
+func (fgen *funcGen) indexResults(irFunc *ir.Func) {
+	for _, block := range irFunc.Blocks {
+		for _, inst := range block.Insts {
+			ext, ok := inst.(*ir.InstExtractValue)
+			if !ok || !fgen.gen.isMultiResultCall(ext.X) {
+				continue
+			}
+			call := ext.X.(*ir.InstCall)
+			names := fgen.callResults(call)
+			i := ext.Indices[0]
+			switch {
+			case len(ext.Indices) == 1 && names[i] == "_":
+				names[i] = newName(ext)
+				fgen.folded[ext] = true
+			case names[i] == "_":
+				names[i] = fmt.Sprintf("%s_%d", newName(call), i)
+			}
+		}
+	}
+}
+
+// callResults returns the Go names of the results of the given call to a
+// function lifted to a Go function with multiple results.
+func (fgen *funcGen) callResults(call *ir.InstCall) []string {
+	names, ok := fgen.results[call]
+	if !ok {
+		t := call.Type().(*types.StructType)
+		names = make([]string, len(t.Fields))
+		for i := range names {
+			names[i] = "_"
+		}
+		fgen.results[call] = names
+	}
+	return names
+}
+
+// liftInstExtractValue lifts the LLVM IR extractvalue instruction to Go source
+// code, emitting to f.
+func (fgen *funcGen) liftInstExtractValue(inst *ir.InstExtractValue) {
+	if fgen.folded[inst] {
+		// Folded into the assignment of the call results.
+		return
+	}
+	// Variable name.
+	name := newIdent(inst)
+	// Aggregate value.
+	var x ast.Expr
+	t := inst.X.Type()
+	indices := inst.Indices
+	if call, ok := inst.X.(*ir.InstCall); ok && fgen.gen.isMultiResultCall(call) {
+		// Extract from call result.
+		x = ast.NewIdent(fgen.callResults(call)[indices[0]])
+		t = t.(*types.StructType).Fields[indices[0]]
+		indices = indices[1:]
+	} else {
+		x = fgen.liftValue(inst.X)
+	}
+	elem, err := fgen.gen.aggregateElemExpr(x, t, indices)
+	if err != nil {
+		fgen.gen.eh(err)
+		return
+	}
+	// Append assignment statement.
+	fgen.cur.List = append(fgen.cur.List, assignStmt(name, elem))
+}
+
+// liftInstInsertValue lifts the LLVM IR insertvalue instruction to Go source
+// code, emitting to f.
+//
+//    name = x
+//    name.field0 = elem
+func (fgen *funcGen) liftInstInsertValue(inst *ir.InstInsertValue) {
+	// Variable name.
+	name := newIdent(inst)
+	// Aggregate value.
+	var x ast.Expr
+	switch inst.X.(type) {
+	case *constant.Undef, *constant.ZeroInitializer:
+		goType, err := fgen.gen.goType(inst.X.Type())
+		if err != nil {
+			fgen.gen.eh(err)
+			return
+		}
+		x = zeroValueExpr(goType)
+	default:
+		x = fgen.liftValue(inst.X)
+	}
+	// Element.
+	elem := fgen.liftValue(inst.Elem)
+	dst, err := fgen.gen.aggregateElemExpr(name, inst.X.Type(), inst.Indices)
+	if err != nil {
+		fgen.gen.eh(err)
+		return
+	}
+	// Append assignment statements.
+	fgen.cur.List = append(fgen.cur.List, assignStmt(name, x), assignStmt(dst, elem))
+}
+
+// liftMultiResultCall lifts the LLVM IR call instruction of a function lifted
+// to a Go function with multiple results to Go source code, emitting to f.
+//
+//    a, ok = f()
+func (fgen *funcGen) liftMultiResultCall(inst *ir.InstCall, callExpr *ast.CallExpr) {
+	var lhs []ast.Expr
+	for _, name := range fgen.callResults(inst) {
+		lhs = append(lhs, ast.NewIdent(name))
+	}
+	// Append assignment statement.
+	assignStmt := &ast.AssignStmt{
+		Lhs: lhs,
+		Tok: token.ASSIGN,
+		Rhs: []ast.Expr{callExpr},
+	}
+	fgen.cur.List = append(fgen.cur.List, assignStmt)
+}
+
+// liftMultiResultRet returns the Go results of the LLVM IR return value of a
+// function lifted to a Go function with multiple results.
+//
+//    return x.field0, x.field1
+func (fgen *funcGen) liftMultiResultRet(x value.Value) []ast.Expr {
+	t := x.Type().(*types.StructType)
+	var results []ast.Expr
+	if c, ok := x.(*constant.Struct); ok {
+		for _, field := range c.Fields {
+			results = append(results, fgen.liftValue(field))
+		}
+		return results
+	}
+	var agg ast.Expr
+	switch x.(type) {
+	case *constant.Undef, *constant.ZeroInitializer:
+		goType, err := fgen.gen.goType(t)
+		if err != nil {
+			fgen.gen.eh(err)
+			return nil
+		}
+		agg = zeroValueExpr(goType)
+	default:
+		agg = fgen.liftValue(x)
+	}
+	for i := range t.Fields {
+		result := &ast.SelectorExpr{X: agg, Sel: ast.NewIdent(fieldName(i))}
+		results = append(results, result)
+	}
+	return results
+}
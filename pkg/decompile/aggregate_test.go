@@ -0,0 +1,144 @@
+package decompile
+
+import "testing"
+
+func TestMultiResult(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Struct return value destructured into multiple results.
+		{
+			name: "pair",
+			src: `
+define internal { i32, i32 } @pair(i32 %a, i32 %b) {
+	%1 = insertvalue { i32, i32 } undef, i32 %a, 0
+	%2 = insertvalue { i32, i32 } %1, i32 %b, 1
+	ret { i32, i32 } %2
+}
+
+define i32 @f() {
+	%x = call { i32, i32 } @pair(i32 1, i32 2)
+	%y = extractvalue { i32, i32 } %x, 0
+	%z = extractvalue { i32, i32 } %x, 1
+	%s = add i32 %y, %z
+	ret i32 %s
+}
+`,
+			want: []string{
+				`func pair(a int32, b int32) (int32, int32) {`,
+				`return _2.field0, _2.field1`,
+				`y, z = pair(1, 2)`,
+				`s = y + z`,
+			},
+		},
+		// Constant struct return value.
+		{
+			name: "constant",
+			src: `
+define internal { i32, i32 } @pair() {
+	ret { i32, i32 } { i32 1, i32 2 }
+}
+
+define i32 @f() {
+	%x = call { i32, i32 } @pair()
+	%y = extractvalue { i32, i32 } %x, 1
+	ret i32 %y
+}
+`,
+			want: []string{
+				`func pair() (int32, int32) {`,
+				`return 1, 2`,
+				`_, y = pair()`,
+				`return y`,
+			},
+		},
+		// Struct return value used other than by extractvalue instructions.
+		{
+			name: "escaping",
+			src: `
+declare void @g({ i32, i32 })
+
+define internal { i32, i32 } @pair() {
+	ret { i32, i32 } { i32 1, i32 2 }
+}
+
+define void @f() {
+	%x = call { i32, i32 } @pair()
+	call void @g({ i32, i32 } %x)
+	ret void
+}
+`,
+			want: []string{
+				`func pair() struct {`,
+				`x = pair()`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
+
+func TestAggregateValue(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Struct fields of local variable.
+		{
+			name: "struct",
+			src: `
+define i32 @f(i32 %a, i32 %b) {
+	%1 = insertvalue { i32, i32 } undef, i32 %a, 0
+	%2 = insertvalue { i32, i32 } %1, i32 %b, 1
+	%3 = extractvalue { i32, i32 } %2, 1
+	ret i32 %3
+}
+`,
+			want: []string{
+				`_1.field0 = a`,
+				`_2 = _1`,
+				`_2.field1 = b`,
+				`_3 = _2.field1`,
+			},
+		},
+		// Array elements of local variable.
+		{
+			name: "array",
+			src: `
+define i32 @f(i32 %a) {
+	%1 = insertvalue [2 x i32] zeroinitializer, i32 %a, 1
+	%2 = extractvalue [2 x i32] %1, 1
+	ret i32 %2
+}
+`,
+			want: []string{
+				`_1 = *new([2]int32)`,
+				`_1[1] = a`,
+				`_2 = _1[1]`,
+			},
+		},
+		// Nested aggregate indices.
+		{
+			name: "nested",
+			src: `
+define i32 @f({ i32, [2 x i32] } %x, i32 %a) {
+	%1 = insertvalue { i32, [2 x i32] } %x, i32 %a, 1, 0
+	%2 = extractvalue { i32, [2 x i32] } %1, 1, 0
+	ret i32 %2
+}
+`,
+			want: []string{
+				`_1 = x`,
+				`_1.field1[0] = a`,
+				`_2 = _1.field1[0]`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
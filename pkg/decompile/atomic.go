@@ -0,0 +1,250 @@
+package decompile
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/pkg/errors"
+)
+
+// liftInstFence lifts the LLVM IR fence instruction to Go source code, emitting
+// to f. Go has no stand-alone memory fences, and the fence is therefore lifted
+// to a note in the doc comment of f.
+//
+//    // atomic: fence seq_cst has no Go equivalent
+func (fgen *funcGen) liftInstFence(inst *ir.InstFence) {
+	text := fmt.Sprintf("atomic: fence %v has no Go equivalent; sync/atomic operations are sequentially consistent", inst.Ordering)
+	if len(inst.SyncScope) > 0 {
+		text += fmt.Sprintf(" (syncscope(%q))", inst.SyncScope)
+	}
+	fgen.addDocComment(text)
+}
+
+// liftInstAtomicRMW lifts the LLVM IR atomicrmw instruction to Go source code,
+// emitting to f.
+//
+// The add, sub and xchg operations are lifted to the corresponding sync/atomic
+// functions, while the remaining operations are lifted to compare-and-swap
+// loops.
+//
+//    x = atomic.AddInt32(dst, v) - v
+//
+//    for {
+//       x = atomic.LoadInt32(dst)
+//       if atomic.CompareAndSwapInt32(dst, x, x ^ v) {
+//          break
+//       }
+//    }
+func (fgen *funcGen) liftInstAtomicRMW(inst *ir.InstAtomicRMW) {
+	fgen.emitOrderingComments("atomicrmw", inst.SyncScope, inst.Ordering)
+	// Variable name.
+	name := newIdent(inst)
+	// Destination address and operand.
+	dst := fgen.liftValue(inst.Dst)
+	x := fgen.liftValue(inst.X)
+	t := inst.X.Type()
+	suffix, args, conv, err := fgen.gen.atomicArgs(t, dst, x)
+	if err != nil {
+		fgen.gen.eh(err)
+		return
+	}
+	atomicFunc := func(name string, args ...ast.Expr) ast.Expr {
+		return conv(callExpr(fgen.gen.qualIdent("sync/atomic", name+suffix), args...))
+	}
+	switch inst.Op {
+	case enum.AtomicOpXChg:
+		fgen.cur.List = append(fgen.cur.List, assignStmt(name, atomicFunc("Swap", args...)))
+		return
+	}
+	if suffix == "Pointer" {
+		fgen.gen.Errorf("support for atomicrmw operation %v on pointer operands not yet implemented", inst.Op)
+		return
+	}
+	switch inst.Op {
+	case enum.AtomicOpAdd:
+		// AddT returns the new value.
+		add := &ast.BinaryExpr{X: atomicFunc("Add", args...), Op: token.SUB, Y: x}
+		fgen.cur.List = append(fgen.cur.List, assignStmt(name, add))
+		return
+	case enum.AtomicOpSub:
+		// AddT returns the new value.
+		neg := &ast.UnaryExpr{Op: token.SUB, X: x}
+		sub := &ast.BinaryExpr{X: atomicFunc("Add", dst, neg), Op: token.ADD, Y: x}
+		fgen.cur.List = append(fgen.cur.List, assignStmt(name, sub))
+		return
+	}
+	// Compare-and-swap loop.
+	var newExpr ast.Expr
+	switch inst.Op {
+	case enum.AtomicOpAnd:
+		newExpr, err = fgen.gen.binOpExpr(name, x, token.AND, t, false)
+	case enum.AtomicOpOr:
+		newExpr, err = fgen.gen.binOpExpr(name, x, token.OR, t, false)
+	case enum.AtomicOpXor:
+		newExpr, err = fgen.gen.binOpExpr(name, x, token.XOR, t, false)
+	case enum.AtomicOpNAnd:
+		var and ast.Expr
+		and, err = fgen.gen.binOpExpr(name, x, token.AND, t, false)
+		newExpr = &ast.UnaryExpr{Op: token.XOR, X: &ast.ParenExpr{X: and}}
+	case enum.AtomicOpMax:
+		newExpr, err = fgen.gen.atomicSelectExpr(enum.IPredSGT, name, x, t)
+	case enum.AtomicOpMin:
+		newExpr, err = fgen.gen.atomicSelectExpr(enum.IPredSLT, name, x, t)
+	case enum.AtomicOpUMax:
+		newExpr, err = fgen.gen.atomicSelectExpr(enum.IPredUGT, name, x, t)
+	case enum.AtomicOpUMin:
+		newExpr, err = fgen.gen.atomicSelectExpr(enum.IPredULT, name, x, t)
+	default:
+		err = errors.Errorf("support for atomicrmw operation %v not yet implemented", inst.Op)
+	}
+	if err != nil {
+		fgen.gen.eh(err)
+		return
+	}
+	cas := &ast.IfStmt{
+		Cond: atomicFunc("CompareAndSwap", dst, name, newExpr),
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{&ast.BranchStmt{Tok: token.BREAK}},
+		},
+	}
+	forStmt := &ast.ForStmt{
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{assignStmt(name, atomicFunc("Load", dst)), cas},
+		},
+	}
+	fgen.cur.List = append(fgen.cur.List, forStmt)
+}
+
+// atomicSelectExpr returns the Go expression selecting x if the integer
+// comparison of x and y holds, and y otherwise.
+func (gen *Generator) atomicSelectExpr(pred enum.IPred, x, y ast.Expr, t types.Type) (ast.Expr, error) {
+	goType, err := gen.goType(t)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return condExpr(goType, gen.icmpExpr(pred, x, y, t), x, y), nil
+}
+
+// liftInstCmpXchg lifts the LLVM IR cmpxchg instruction to Go source code,
+// emitting to f.
+//
+// The {old, success} result of cmpxchg is lifted to a Go struct value. As
+// atomic.CompareAndSwapT only reports success, the old value is derived by a
+// compare-and-swap loop; the exchange fails if the atomically loaded value
+// differs from cmp, and succeeds if the loaded value is swapped before being
+// modified.
+//
+//    for {
+//       x.field0 = atomic.LoadInt32(ptr)
+//       if x.field0 != cmp {
+//          x.field1 = false
+//          break
+//       }
+//       if atomic.CompareAndSwapInt32(ptr, cmp, new) {
+//          x.field1 = true
+//          break
+//       }
+//    }
+func (fgen *funcGen) liftInstCmpXchg(inst *ir.InstCmpXchg) {
+	fgen.emitOrderingComments("cmpxchg", inst.SyncScope, inst.SuccessOrdering, inst.FailureOrdering)
+	// Variable name.
+	name := newIdent(inst)
+	// Address and operands.
+	ptr := fgen.liftValue(inst.Ptr)
+	cmp := fgen.liftValue(inst.Cmp)
+	newVal := fgen.liftValue(inst.New)
+	suffix, args, conv, err := fgen.gen.atomicArgs(inst.Cmp.Type(), ptr, cmp, newVal)
+	if err != nil {
+		fgen.gen.eh(err)
+		return
+	}
+	cas := callExpr(fgen.gen.qualIdent("sync/atomic", "CompareAndSwap"+suffix), args...)
+	old := &ast.SelectorExpr{X: name, Sel: ast.NewIdent(fieldName(0))}
+	success := &ast.SelectorExpr{X: name, Sel: ast.NewIdent(fieldName(1))}
+	load := conv(callExpr(fgen.gen.qualIdent("sync/atomic", "Load"+suffix), args[0]))
+	// result returns the statements storing the success flag and breaking the
+	// compare-and-swap loop.
+	result := func(ok bool) *ast.BlockStmt {
+		return &ast.BlockStmt{
+			List: []ast.Stmt{
+				assignStmt(success, ast.NewIdent(fmt.Sprint(ok))),
+				&ast.BranchStmt{Tok: token.BREAK},
+			},
+		}
+	}
+	failStmt := &ast.IfStmt{
+		Cond: &ast.BinaryExpr{X: old, Op: token.NEQ, Y: cmp},
+		Body: result(false),
+	}
+	casStmt := &ast.IfStmt{
+		Cond: cas,
+		Body: result(true),
+	}
+	forStmt := &ast.ForStmt{
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{assignStmt(old, load), failStmt, casStmt},
+		},
+	}
+	fgen.cur.List = append(fgen.cur.List, forStmt)
+}
+
+// atomicArgs returns the name suffix (e.g. Int32) of the sync/atomic functions
+// operating on values of the given LLVM IR type, and the address and operands
+// converted to the Go types expected by sync/atomic. The returned conversion
+// function converts results of sync/atomic functions back to the Go type
+// corresponding to t.
+func (gen *Generator) atomicArgs(t types.Type, addr ast.Expr, vals ...ast.Expr) (string, []ast.Expr, func(ast.Expr) ast.Expr, error) {
+	identity := func(x ast.Expr) ast.Expr { return x }
+	switch t := t.(type) {
+	case *types.IntType:
+		var suffix string
+		switch t.BitSize {
+		case 32:
+			suffix = "Int32"
+		case 64:
+			suffix = "Int64"
+		default:
+			return "", nil, nil, errors.Errorf("support for atomic operations on integer type bit size %d not yet implemented", t.BitSize)
+		}
+		return suffix, append([]ast.Expr{addr}, vals...), identity, nil
+	case *types.PointerType:
+		// (*unsafe.Pointer)(unsafe.Pointer(addr))
+		ptrType := &ast.ParenExpr{X: &ast.StarExpr{X: gen.qualIdent("unsafe", "Pointer")}}
+		args := []ast.Expr{callExpr(ptrType, gen.unsafePointerExpr(addr))}
+		for _, val := range vals {
+			args = append(args, gen.unsafePointerExpr(val))
+		}
+		goType, err := gen.goType(t)
+		if err != nil {
+			return "", nil, nil, errors.WithStack(err)
+		}
+		conv := func(x ast.Expr) ast.Expr { return goConvExpr(goType, x) }
+		return "Pointer", args, conv, nil
+	default:
+		return "", nil, nil, errors.Errorf("support for atomic operations on type %T not yet implemented", t)
+	}
+}
+
+// emitOrderingComments adds notes on the memory orderings and synchronization
+// scope of the given atomic instruction which Go cannot express to the doc
+// comment of f. Operations of sync/atomic are sequentially consistent, and
+// weaker orderings are therefore strengthened.
+//
+//    // atomic: atomicrmw ordering acquire strengthened to seq_cst
+func (fgen *funcGen) emitOrderingComments(instName, syncScope string, orderings ...enum.AtomicOrdering) {
+	for _, ordering := range orderings {
+		if ordering == enum.AtomicOrderingSeqCst {
+			continue
+		}
+		text := fmt.Sprintf("atomic: %s ordering %v strengthened to %v", instName, ordering, enum.AtomicOrderingSeqCst)
+		fgen.addDocComment(text)
+	}
+	if len(syncScope) > 0 {
+		text := fmt.Sprintf("atomic: %s syncscope(%q) not expressible in Go", instName, syncScope)
+		fgen.addDocComment(text)
+	}
+}
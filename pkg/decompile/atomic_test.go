@@ -0,0 +1,59 @@
+package decompile
+
+import "testing"
+
+func TestAtomic(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Bitwise AND lifted to compare-and-swap loop.
+		{
+			name: "atomicrmw and",
+			src: `
+define i32 @f(i32* %p, i32 %v) {
+	%1 = atomicrmw and i32* %p, i32 %v seq_cst
+	ret i32 %1
+}
+`,
+			want: []string{
+				`_1 = atomic.LoadInt32(p)`,
+				`if atomic.CompareAndSwapInt32(p, _1, _1&v) {`,
+			},
+		},
+		// Old value of cmpxchg derived by compare-and-swap loop.
+		{
+			name: "cmpxchg",
+			src: `
+define i32 @f(i32* %p, i32 %cmp, i32 %new) {
+	%1 = cmpxchg i32* %p, i32 %cmp, i32 %new acq_rel monotonic
+	%2 = extractvalue { i32, i1 } %1, 0
+	ret i32 %2
+}
+`,
+			want: []string{
+				`// atomic: cmpxchg ordering acq_rel strengthened to seq_cst`,
+				`_1.field0 = atomic.LoadInt32(p)`,
+				`if _1.field0 != cmp {`,
+				`if atomic.CompareAndSwapInt32(p, cmp, new) {`,
+			},
+		},
+		// Fence lifted to doc comment.
+		{
+			name: "fence",
+			src: `
+define void @f() {
+	fence acquire
+	ret void
+}
+`,
+			want: []string{
+				`// atomic: fence acquire has no Go equivalent`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
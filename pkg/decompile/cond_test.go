@@ -0,0 +1,247 @@
+package decompile
+
+import (
+	"bytes"
+	"go/format"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
+)
+
+func TestCompCond(t *testing.T) {
+	golden := []struct {
+		name  string
+		src   string
+		prims []*primitive.Primitive
+		want  []string
+	}{
+		// A && B.
+		{
+			name: "comp_cond_a_AND_b",
+			src: `
+define void @f(i1 %x, i1 %y, i32* %p) {
+a:
+	br i1 %x, label %b, label %e
+
+b:
+	br i1 %y, label %t, label %e
+
+t:
+	store i32 1, i32* %p
+	br label %exit
+
+e:
+	store i32 2, i32* %p
+	br label %exit
+
+exit:
+	ret void
+}
+`,
+			prims: []*primitive.Primitive{
+				compCondPrim("comp_cond_a_AND_b", "a", "b", primitive.And(primitive.Leaf("a"), primitive.Leaf("b"))),
+				ifElsePrim("a", "t", "e"),
+			},
+			want: []string{
+				"if x && y {\n\t\t*p = 1\n\t} else {\n\t\t*p = 2\n\t}",
+			},
+		},
+		// A && !B; the first successor of B is the target of the false branch.
+		{
+			name: "comp_cond_a_AND_NOT_b",
+			src: `
+define void @f(i1 %x, i1 %y, i32* %p) {
+a:
+	br i1 %x, label %b, label %e
+
+b:
+	br i1 %y, label %e, label %t
+
+t:
+	store i32 1, i32* %p
+	br label %exit
+
+e:
+	store i32 2, i32* %p
+	br label %exit
+
+exit:
+	ret void
+}
+`,
+			prims: []*primitive.Primitive{
+				compCondPrim("comp_cond_a_AND_NOT_b", "a", "b", primitive.And(primitive.Leaf("a"), primitive.Not(primitive.Leaf("b")))),
+				ifElsePrim("a", "e", "t"),
+			},
+			want: []string{
+				"if !(x && !(y)) {\n\t\t*p = 2\n\t} else {\n\t\t*p = 1\n\t}",
+			},
+		},
+		// A || B.
+		{
+			name: "comp_cond_a_OR_b",
+			src: `
+define void @f(i1 %x, i1 %y, i32* %p) {
+a:
+	br i1 %x, label %t, label %b
+
+b:
+	br i1 %y, label %t, label %e
+
+t:
+	store i32 1, i32* %p
+	br label %exit
+
+e:
+	store i32 2, i32* %p
+	br label %exit
+
+exit:
+	ret void
+}
+`,
+			prims: []*primitive.Primitive{
+				compCondPrim("comp_cond_a_OR_b", "a", "b", primitive.Or(primitive.Leaf("a"), primitive.Leaf("b"))),
+				ifElsePrim("a", "t", "e"),
+			},
+			want: []string{
+				"if x || y {\n\t\t*p = 1\n\t} else {\n\t\t*p = 2\n\t}",
+			},
+		},
+		// A || !B; the first successor of B is the target of the false branch.
+		{
+			name: "comp_cond_a_OR_NOT_b",
+			src: `
+define void @f(i1 %x, i1 %y, i32* %p) {
+a:
+	br i1 %x, label %t, label %b
+
+b:
+	br i1 %y, label %e, label %t
+
+t:
+	store i32 1, i32* %p
+	br label %exit
+
+e:
+	store i32 2, i32* %p
+	br label %exit
+
+exit:
+	ret void
+}
+`,
+			prims: []*primitive.Primitive{
+				compCondPrim("comp_cond_a_OR_NOT_b", "a", "b", primitive.Or(primitive.Leaf("a"), primitive.Not(primitive.Leaf("b")))),
+				ifElsePrim("a", "e", "t"),
+			},
+			want: []string{
+				"if !(x || !(y)) {\n\t\t*p = 2\n\t} else {\n\t\t*p = 1\n\t}",
+			},
+		},
+		// (A || B) && C; A of the outer compound condition is itself a compound
+		// condition.
+		{
+			name: "nested",
+			src: `
+define void @f(i1 %x, i1 %y, i1 %z, i32* %p) {
+a:
+	br i1 %x, label %c, label %b
+
+b:
+	br i1 %y, label %c, label %e
+
+c:
+	br i1 %z, label %t, label %e
+
+t:
+	store i32 1, i32* %p
+	br label %exit
+
+e:
+	store i32 2, i32* %p
+	br label %exit
+
+exit:
+	ret void
+}
+`,
+			prims: []*primitive.Primitive{
+				compCondPrim("comp_cond_a_OR_b", "a", "b", primitive.Or(primitive.Leaf("a"), primitive.Leaf("b"))),
+				compCondPrim("comp_cond_a_AND_b", "a", "c", primitive.And(primitive.Leaf("a"), primitive.Leaf("c"))),
+				ifElsePrim("a", "t", "e"),
+			},
+			want: []string{
+				"if (x || y) && z {\n\t\t*p = 1\n\t} else {\n\t\t*p = 2\n\t}",
+			},
+		},
+	}
+	for _, gold := range golden {
+		m, err := asm.Parse("test.ll", strings.NewReader(gold.src))
+		if err != nil {
+			t.Fatalf("%q: unable to parse LLVM IR assembly; %+v", gold.name, err)
+		}
+		var errs []error
+		eh := func(err error) {
+			errs = append(errs, err)
+		}
+		gen := NewGenerator(eh, m)
+		gen.PkgName = "p"
+		prims := gold.prims
+		gen.Prims = func(f *ir.Func) ([]*primitive.Primitive, error) {
+			return prims, nil
+		}
+		file := gen.Decompile()[0]
+		buf := &bytes.Buffer{}
+		if err := format.Node(buf, token.NewFileSet(), file); err != nil {
+			t.Fatalf("%q: unable to format Go source code; %+v", gold.name, err)
+		}
+		got := buf.String()
+		if len(errs) > 0 {
+			t.Errorf("%q: unable to decompile; %v", gold.name, errs)
+			continue
+		}
+		for _, w := range gold.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("%q: output mismatch; expected output containing `%s`, got `%s`", gold.name, w, got)
+			}
+		}
+		if strings.Contains(got, "goto") {
+			t.Errorf("%q: unexpected goto statement in output `%s`", gold.name, got)
+		}
+	}
+}
+
+// compCondPrim returns a compound condition primitive merging the condition
+// blocks a and b into a, with the given compound condition.
+func compCondPrim(prim, a, b string, cond *primitive.Cond) *primitive.Primitive {
+	return &primitive.Primitive{
+		Prim:  prim,
+		Entry: a,
+		Nodes: map[string]string{
+			"a": a,
+			"b": b,
+		},
+		Cond: cond,
+	}
+}
+
+// ifElsePrim returns an if-else primitive with the given cond, body_true and
+// body_false blocks, followed by the exit block "exit".
+func ifElsePrim(cond, bodyTrue, bodyFalse string) *primitive.Primitive {
+	return &primitive.Primitive{
+		Prim:  "if_else",
+		Entry: cond,
+		Nodes: map[string]string{
+			"cond":       cond,
+			"body_true":  bodyTrue,
+			"body_false": bodyFalse,
+			"exit":       "exit",
+		},
+		Exit: "exit",
+	}
+}
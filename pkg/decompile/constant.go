@@ -9,6 +9,8 @@ import (
 
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
 	"github.com/pkg/errors"
 )
 
@@ -60,9 +62,17 @@ func (gen *Generator) liftConst(irConst constant.Constant) (ast.Expr, error) {
 			Elts: elems,
 		}, nil
 	case *constant.CharArray:
-		return &ast.BasicLit{
-			Kind:  token.STRING,
-			Value: string(irConst.X),
+		goType, err := gen.goType(irConst.Type())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var elems []ast.Expr
+		for _, b := range irConst.X {
+			elems = append(elems, goIntLit(int64(int8(b))))
+		}
+		return &ast.CompositeLit{
+			Type: goTypeExpr(goType),
+			Elts: elems,
 		}, nil
 	case *constant.Vector:
 		goType, err := gen.goType(irConst.Type())
@@ -95,13 +105,15 @@ func (gen *Generator) liftConst(irConst constant.Constant) (ast.Expr, error) {
 		}, nil
 	// Global variable and function addresses
 	case *ir.Global:
-		return ast.NewIdent(irConst.Name()), nil
+		// The Go global variable holds the content of the LLVM IR global, and
+		// the LLVM IR global is a pointer to its content.
+		return &ast.UnaryExpr{Op: token.AND, X: gen.globalIdent(irConst.Name())}, nil
 	case *ir.Func:
-		return ast.NewIdent(irConst.Name()), nil
+		return gen.globalIdent(irConst.Name()), nil
 	case *ir.Alias:
-		return ast.NewIdent(irConst.Name()), nil
+		return gen.globalIdent(irConst.Name()), nil
 	case *ir.IFunc:
-		return ast.NewIdent(irConst.Name()), nil
+		return gen.globalIdent(irConst.Name()), nil
 	// Undefined values
 	case *constant.Undef:
 		// TODO: figure out better representation for undefined values.
@@ -117,64 +129,268 @@ func (gen *Generator) liftConst(irConst constant.Constant) (ast.Expr, error) {
 }
 
 // liftConstExpr lifts the LLVM IR constant expression to an equivalent Go
-// expression.
+// expression. Constant expressions are evaluated at decompile time if the
+// result is fully known.
 func (gen *Generator) liftConstExpr(irConst constant.Constant) (ast.Expr, error) {
+	if name, ok := gen.strConst(irConst); ok {
+		return gen.strPtrExpr(name), nil
+	}
+	if folded := foldConst(irConst); folded != irConst {
+		return gen.liftConst(folded)
+	}
 	switch irConst := irConst.(type) {
 	// Binary expressions
-	//case *constant.ExprAdd:
-	//case *constant.ExprFAdd:
-	//case *constant.ExprSub:
-	//case *constant.ExprFSub:
-	//case *constant.ExprMul:
-	//case *constant.ExprFMul:
-	//case *constant.ExprUDiv:
-	//case *constant.ExprSDiv:
-	//case *constant.ExprFDiv:
-	//case *constant.ExprURem:
-	//case *constant.ExprSRem:
-	//case *constant.ExprFRem:
+	case *constant.ExprAdd:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.ADD, false)
+	case *constant.ExprFAdd:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.ADD, false)
+	case *constant.ExprSub:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.SUB, false)
+	case *constant.ExprFSub:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.SUB, false)
+	case *constant.ExprMul:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.MUL, false)
+	case *constant.ExprFMul:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.MUL, false)
+	case *constant.ExprUDiv:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.QUO, true)
+	case *constant.ExprSDiv:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.QUO, false)
+	case *constant.ExprFDiv:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.QUO, false)
+	case *constant.ExprURem:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.REM, true)
+	case *constant.ExprSRem:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.REM, false)
+	case *constant.ExprFRem:
+		x, y, err := gen.liftConstOperands(irConst.X, irConst.Y)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return gen.fremExpr(x, y, irConst.X.Type())
 	// Bitwise expressions
-	//case *constant.ExprShl:
-	//case *constant.ExprLShr:
-	//case *constant.ExprAShr:
-	//case *constant.ExprAnd:
-	//case *constant.ExprOr:
-	//case *constant.ExprXor:
+	case *constant.ExprShl:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.SHL, false)
+	case *constant.ExprLShr:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.SHR, true)
+	case *constant.ExprAShr:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.SHR, false)
+	case *constant.ExprAnd:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.AND, false)
+	case *constant.ExprOr:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.OR, false)
+	case *constant.ExprXor:
+		return gen.liftConstBinOp(irConst.X, irConst.Y, token.XOR, false)
 	// Vector expressions
-	//case *constant.ExprExtractElement:
-	//case *constant.ExprInsertElement:
-	//case *constant.ExprShuffleVector:
+	case *constant.ExprExtractElement:
+		x, index, err := gen.liftConstOperands(irConst.X, irConst.Index)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &ast.IndexExpr{X: x, Index: index}, nil
+	case *constant.ExprInsertElement:
+		x, elem, err := gen.liftConstOperands(irConst.X, irConst.Elem)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		index, err := gen.liftConst(irConst.Index)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		goType, err := gen.goType(irConst.X.Type())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// Assign to copy of vector, as array values are not addressable.
+		//
+		//    func() [N]T {
+		//       v := x
+		//       v[index] = elem
+		//       return v
+		//    }()
+		v := ast.NewIdent("v")
+		return funcLitCall(goType,
+			defineStmt(v, x),
+			assignStmt(&ast.IndexExpr{X: v, Index: index}, elem),
+			&ast.ReturnStmt{Results: []ast.Expr{v}},
+		), nil
+	case *constant.ExprShuffleVector:
+		return gen.liftConstShuffleVector(irConst)
 	// Aggregate expressions
-	//case *constant.ExprExtractValue:
-	//case *constant.ExprInsertValue:
+	case *constant.ExprExtractValue:
+		x, err := gen.liftConst(irConst.X)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return gen.aggregateElemExpr(x, irConst.X.Type(), irConst.Indices)
+	case *constant.ExprInsertValue:
+		x, elem, err := gen.liftConstOperands(irConst.X, irConst.Elem)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		goType, err := gen.goType(irConst.X.Type())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		v := ast.NewIdent("v")
+		lhs, err := gen.aggregateElemExpr(v, irConst.X.Type(), irConst.Indices)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// Assign to copy of aggregate value.
+		//
+		//    func() T {
+		//       v := x
+		//       v.field0 = elem
+		//       return v
+		//    }()
+		return funcLitCall(goType,
+			defineStmt(v, x),
+			assignStmt(lhs, elem),
+			&ast.ReturnStmt{Results: []ast.Expr{v}},
+		), nil
 	// Memory expressions
-	//case *constant.ExprGetElementPtr:
+	case *constant.ExprGetElementPtr:
+		src, err := gen.liftConst(irConst.Src)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var indices []value.Value
+		for _, index := range irConst.Indices {
+			indices = append(indices, index)
+		}
+		return gen.gepExpr(irConst.ElemType, src, indices, gen.liftConstValue)
 	// Conversion expressions
-	//case *constant.ExprTrunc:
-	//case *constant.ExprZExt:
-	//case *constant.ExprSExt:
-	//case *constant.ExprFPTrunc:
-	//case *constant.ExprFPExt:
-	//case *constant.ExprFPToUI:
-	//case *constant.ExprFPToSI:
-	//case *constant.ExprUIToFP:
-	//case *constant.ExprSIToFP:
-	//case *constant.ExprPtrToInt:
-	//case *constant.ExprIntToPtr:
-	//case *constant.ExprBitCast:
-	//case *constant.ExprAddrSpaceCast:
+	case *constant.ExprTrunc:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.intConvExpr)
+	case *constant.ExprZExt:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.zextExpr)
+	case *constant.ExprSExt:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.intConvExpr)
+	case *constant.ExprFPTrunc:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.convExpr)
+	case *constant.ExprFPExt:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.convExpr)
+	case *constant.ExprFPToUI:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.fptouiExpr)
+	case *constant.ExprFPToSI:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.convExpr)
+	case *constant.ExprUIToFP:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.uitofpExpr)
+	case *constant.ExprSIToFP:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.convExpr)
+	case *constant.ExprPtrToInt:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.ptrtointExpr)
+	case *constant.ExprIntToPtr:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.inttoptrExpr)
+	case *constant.ExprBitCast:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.bitcastExpr)
+	case *constant.ExprAddrSpaceCast:
+		return gen.liftConstConv(irConst.From, irConst.To, gen.bitcastExpr)
 	// Other expressions
-	//case *constant.ExprICmp:
-	//case *constant.ExprFCmp:
-	//case *constant.ExprSelect:
+	case *constant.ExprICmp:
+		x, y, err := gen.liftConstOperands(irConst.X, irConst.Y)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return gen.icmpExpr(irConst.Pred, x, y, irConst.X.Type()), nil
+	case *constant.ExprFCmp:
+		x, y, err := gen.liftConstOperands(irConst.X, irConst.Y)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return gen.fcmpExpr(irConst.Pred, x, y, irConst.X.Type())
+	case *constant.ExprSelect:
+		cond, err := gen.liftConst(irConst.Cond)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		x, y, err := gen.liftConstOperands(irConst.X, irConst.Y)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		goType, err := gen.goType(irConst.X.Type())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return condExpr(goType, cond, x, y), nil
 	default:
 		panic(fmt.Errorf("support for constant expression %T not yet implemented", irConst))
 	}
 }
 
+// liftConstOperands lifts the LLVM IR constant operands x and y to equivalent Go
+// expressions.
+func (gen *Generator) liftConstOperands(x, y constant.Constant) (ast.Expr, ast.Expr, error) {
+	goX, err := gen.liftConst(x)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	goY, err := gen.liftConst(y)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	return goX, goY, nil
+}
+
+// liftConstValue lifts the LLVM IR constant value to an equivalent Go
+// expression.
+func (gen *Generator) liftConstValue(v value.Value) (ast.Expr, error) {
+	c, ok := v.(constant.Constant)
+	if !ok {
+		return nil, errors.Errorf("invalid constant operand; expected constant.Constant, got %T", v)
+	}
+	return gen.liftConst(c)
+}
+
+// liftConstBinOp lifts the LLVM IR binary constant expression with the given
+// operands and Go operator to an equivalent Go expression. If unsigned is set,
+// the integer operands are interpreted as unsigned integers.
+func (gen *Generator) liftConstBinOp(x, y constant.Constant, op token.Token, unsigned bool) (ast.Expr, error) {
+	goX, goY, err := gen.liftConstOperands(x, y)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return gen.binOpExpr(goX, goY, op, x.Type(), unsigned)
+}
+
+// liftConstConv lifts the LLVM IR conversion constant expression from the
+// given operand to the given type, using conv to produce the Go conversion
+// expression.
+func (gen *Generator) liftConstConv(from constant.Constant, to types.Type, conv func(x ast.Expr, from, to types.Type) (ast.Expr, error)) (ast.Expr, error) {
+	x, err := gen.liftConst(from)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return conv(x, gen.valueType(from), to)
+}
+
+// liftConstShuffleVector lifts the LLVM IR shufflevector constant expression to
+// an equivalent Go composite literal, which permutes the elements of the
+// operand vectors based on the shuffle mask.
+//
+//    [N]T{x[0], y[1], ...}
+func (gen *Generator) liftConstShuffleVector(irConst *constant.ExprShuffleVector) (ast.Expr, error) {
+	x, y, err := gen.liftConstOperands(irConst.X, irConst.Y)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return gen.shuffleVectorExpr(x, y, irConst.X.Type(), irConst.Mask)
+}
+
 // liftIntConst lifts the LLVM IR integer constant to an equivalent Go basic
-// literal expression.
-func (gen *Generator) liftIntConst(irConst *constant.Int) *ast.BasicLit {
+// literal expression, or boolean identifier for integers of bit size 1.
+func (gen *Generator) liftIntConst(irConst *constant.Int) ast.Expr {
+	if irConst.Typ.BitSize == 1 {
+		// Boolean constant.
+		if irConst.X.Sign() != 0 {
+			return ast.NewIdent("true")
+		}
+		return ast.NewIdent("false")
+	}
+	if isBigInt(irConst.Typ) {
+		return gen.bigIntLit(irConst.X)
+	}
 	return &ast.BasicLit{
 		Kind:  token.INT,
 		Value: irConst.X.String(),
@@ -183,7 +399,7 @@ func (gen *Generator) liftIntConst(irConst *constant.Int) *ast.BasicLit {
 
 // liftFloatConst lifts the LLVM IR floating-point constant to an equivalent Go
 // basic literal expression.
-func (gen *Generator) liftFloatConst(irConst *constant.Float) *ast.BasicLit {
+func (gen *Generator) liftFloatConst(irConst *constant.Float) ast.Expr {
 	if irConst.NaN {
 		nan := math.NaN()
 		if irConst.X.Signbit() {
@@ -194,8 +410,11 @@ func (gen *Generator) liftFloatConst(irConst *constant.Float) *ast.BasicLit {
 			Value: strconv.FormatFloat(nan, 'g', -1, 64),
 		}
 	}
+	if gen.isBigFloat(irConst.Typ) {
+		return gen.bigFloatLit(irConst.X)
+	}
 	return &ast.BasicLit{
 		Kind:  token.FLOAT,
-		Value: irConst.X.String(),
+		Value: irConst.X.Text('g', -1),
 	}
 }
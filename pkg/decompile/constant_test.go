@@ -0,0 +1,295 @@
+package decompile
+
+import "testing"
+
+func TestFoldConst(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Integer arithmetic.
+		{
+			name: "add",
+			src: `
+define i32 @f() {
+	ret i32 add (i32 mul (i32 2, i32 3), i32 1)
+}
+`,
+			want: []string{
+				`return 7`,
+			},
+		},
+		// Arithmetic wraps at the bit size of the type.
+		{
+			name: "add overflow",
+			src: `
+define i8 @f() {
+	ret i8 add (i8 127, i8 1)
+}
+`,
+			want: []string{
+				`return -128`,
+			},
+		},
+		// Unsigned division interprets the operands as unsigned integers.
+		{
+			name: "udiv",
+			src: `
+define i8 @f() {
+	ret i8 udiv (i8 -2, i8 2)
+}
+`,
+			want: []string{
+				`return 127`,
+			},
+		},
+		{
+			name: "sdiv",
+			src: `
+define i8 @f() {
+	ret i8 sdiv (i8 -4, i8 2)
+}
+`,
+			want: []string{
+				`return -2`,
+			},
+		},
+		// Logical and arithmetic shift right.
+		{
+			name: "lshr",
+			src: `
+define i8 @f() {
+	ret i8 lshr (i8 -1, i8 4)
+}
+`,
+			want: []string{
+				`return 15`,
+			},
+		},
+		{
+			name: "ashr",
+			src: `
+define i8 @f() {
+	ret i8 ashr (i8 -128, i8 4)
+}
+`,
+			want: []string{
+				`return -8`,
+			},
+		},
+		// Arbitrary precision integer arithmetic.
+		{
+			name: "i128 add",
+			src: `
+define i128 @f() {
+	ret i128 add (i128 9223372036854775807, i128 1)
+}
+`,
+			want: []string{
+				`SetString("9223372036854775808", 10)`,
+			},
+		},
+		// Integer conversions.
+		{
+			name: "trunc",
+			src: `
+define i8 @f() {
+	ret i8 trunc (i32 257 to i8)
+}
+`,
+			want: []string{
+				`return 1`,
+			},
+		},
+		{
+			name: "zext",
+			src: `
+define i32 @f() {
+	ret i32 zext (i8 -1 to i32)
+}
+`,
+			want: []string{
+				`return 255`,
+			},
+		},
+		{
+			name: "sext",
+			src: `
+define i32 @f() {
+	ret i32 sext (i8 -1 to i32)
+}
+`,
+			want: []string{
+				`return -1`,
+			},
+		},
+		// Signed and unsigned integer comparison.
+		{
+			name: "icmp slt",
+			src: `
+define i1 @f() {
+	ret i1 icmp slt (i32 -1, i32 1)
+}
+`,
+			want: []string{
+				`return true`,
+			},
+		},
+		{
+			name: "icmp ult",
+			src: `
+define i1 @f() {
+	ret i1 icmp ult (i32 -1, i32 1)
+}
+`,
+			want: []string{
+				`return false`,
+			},
+		},
+		// Selection with known condition.
+		{
+			name: "select",
+			src: `
+define i32 @f() {
+	ret i32 select (i1 icmp eq (i32 1, i32 2), i32 3, i32 4)
+}
+`,
+			want: []string{
+				`return 4`,
+			},
+		},
+		// Aggregate element extraction and insertion.
+		{
+			name: "extractvalue",
+			src: `
+define i32 @f() {
+	ret i32 extractvalue ({ i32, i32 } { i32 1, i32 2 }, 1)
+}
+`,
+			want: []string{
+				`return 2`,
+			},
+		},
+		{
+			name: "insertvalue",
+			src: `
+define [2 x i32] @f() {
+	ret [2 x i32] insertvalue ([2 x i32] [i32 1, i32 2], i32 5, 1)
+}
+`,
+			want: []string{
+				`return [2]int32{1, 5}`,
+			},
+		},
+		// Vector element extraction and insertion.
+		{
+			name: "extractelement",
+			src: `
+define i32 @f() {
+	ret i32 extractelement (<2 x i32> <i32 1, i32 2>, i32 1)
+}
+`,
+			want: []string{
+				`return 2`,
+			},
+		},
+		{
+			name: "insertelement",
+			src: `
+define <2 x i32> @f() {
+	ret <2 x i32> insertelement (<2 x i32> <i32 1, i32 2>, i32 5, i32 0)
+}
+`,
+			want: []string{
+				`return [2]int32{5, 2}`,
+			},
+		},
+		// Vector shuffle; mask indices select from the concatenation of both
+		// operands.
+		{
+			name: "shufflevector",
+			src: `
+define <2 x i32> @f() {
+	ret <2 x i32> shufflevector (<2 x i32> <i32 1, i32 2>, <2 x i32> <i32 3, i32 4>, <2 x i32> <i32 3, i32 0>)
+}
+`,
+			want: []string{
+				`return [2]int32{4, 1}`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
+
+func TestLiftConstExpr(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Arithmetic on the address of a global variable.
+		{
+			name: "add ptrtoint",
+			src: `
+@g = internal global i32 0
+
+define i64 @f() {
+	ret i64 add (i64 ptrtoint (i32* @g to i64), i64 1)
+}
+`,
+			want: []string{
+				`return int64(uintptr(unsafe.Pointer(&g))) + 1`,
+			},
+		},
+		// Comparison of addresses.
+		{
+			name: "icmp",
+			src: `
+@g = internal global i32 0
+
+define i1 @f() {
+	ret i1 icmp eq (i32* @g, i32* null)
+}
+`,
+			want: []string{
+				`return &g == nil`,
+			},
+		},
+		// Selection with unknown condition.
+		{
+			name: "select",
+			src: `
+@g = internal global i32 0
+
+define i32 @f() {
+	ret i32 select (i1 icmp eq (i32* @g, i32* null), i32 1, i32 2)
+}
+`,
+			want: []string{
+				`if &g == nil {`,
+				`return 1`,
+				`return 2`,
+			},
+		},
+		// Insertion of unknown element into known vector.
+		{
+			name: "insertelement",
+			src: `
+@g = internal global i32 0
+
+define <2 x i64> @f() {
+	ret <2 x i64> insertelement (<2 x i64> <i64 1, i64 2>, i64 ptrtoint (i32* @g to i64), i32 0)
+}
+`,
+			want: []string{
+				`return [2]int64{int64(uintptr(unsafe.Pointer(&g))), 2}`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
@@ -18,8 +18,26 @@ var (
 	warn = log.New(os.Stderr, term.RedBold("decompile:")+" ", 0)
 )
 
-// Decompile decompiles the LLVM IR module to Go source code.
-func (gen *Generator) Decompile() *ast.File {
+// Decompile decompiles the LLVM IR modules to Go source code, returning one Go
+// source file per module.
+func (gen *Generator) Decompile() []*ast.File {
+	// Index global variables and functions shared between modules.
+	gen.indexPackage()
+
+	var files []*ast.File
+	for _, m := range gen.ms {
+		gen.enterModule(m)
+		file := gen.decompileFile()
+		files = append(files, file)
+	}
+	return files
+}
+
+// decompileFile decompiles the current LLVM IR module to a Go source file.
+func (gen *Generator) decompileFile() *ast.File {
+	// Assign Go identifiers to global identifiers.
+	gen.indexGlobalNames()
+
 	// Resolve type definitions.
 
 	// Index Go type definitions.
@@ -27,6 +45,17 @@ func (gen *Generator) Decompile() *ast.File {
 	// Translate LLVM IR type definitions to Go.
 	gen.translateTypeDefs()
 
+	// Index C string global variables lifted to Go string constants.
+	gen.indexStrGlobals()
+	// Index functions lifted to Go functions with multiple results.
+	gen.indexMultiResultFuncs()
+	// Discard lifting of shared global variables and functions not lifted alike
+	// in every module.
+	gen.resolveShared()
+
+	// Infer types of untyped pointers.
+	gen.inferTypes()
+
 	// Index global identifiers and create scaffolding global variable and
 	// function declarations.
 	gen.createGlobalDecls()
@@ -34,6 +63,9 @@ func (gen *Generator) Decompile() *ast.File {
 	// Decompile LLVM IR module to Go source code.
 	gen.decompileModule()
 
+	// Add import declaration of packages used by the generated Go source code.
+	gen.addImportDecl()
+
 	return gen.file
 }
 
@@ -56,17 +88,29 @@ func (gen *Generator) decompileGlobalDefs() {
 			continue
 		}
 		name := irGlobal.Name()
+		if gen.external[name] {
+			// Skip global definitions emitted elsewhere.
+			continue
+		}
 		global, ok := gen.globals[name]
 		if !ok {
 			gen.Errorf("unable to locate global variable declaration with name %q", name)
 			continue
 		}
+		spec := global.Specs[0].(*ast.ValueSpec)
+		if s, ok := gen.strGlobals[name]; ok {
+			spec.Values = []ast.Expr{strLit(s)}
+			continue
+		}
+		if _, ok := gen.valueTypes[irGlobal]; ok {
+			// Global variable of inferred type initialized to its zero value.
+			continue
+		}
 		init, err := gen.liftConst(irGlobal.Init)
 		if err != nil {
 			gen.eh(err)
 			continue
 		}
-		spec := global.Specs[0].(*ast.ValueSpec)
 		spec.Values = []ast.Expr{init}
 	}
 }
@@ -80,12 +124,16 @@ func (gen *Generator) decompileFuncDefs() {
 			continue
 		}
 		name := irFunc.Name()
+		if gen.external[name] {
+			// Skip function definitions emitted elsewhere.
+			continue
+		}
 		goFunc, ok := gen.funcs[name]
 		if !ok {
 			gen.Errorf("unable to locate function declaration with name %q", name)
 			continue
 		}
 		fgen := gen.newFuncGen(goFunc)
-		fgen.decompileFuncDef(irFunc)
+		fgen.decompileFuncDefOrStub(irFunc)
 	}
 }
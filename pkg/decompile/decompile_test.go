@@ -0,0 +1,71 @@
+package decompile
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
+)
+
+// decompileString decompiles the given LLVM IR assembly to Go source code,
+// returning the Go source code and the errors encountered during
+// decompilation. Control flow is lifted to goto statements, as no control
+// flow primitives are recovered.
+func decompileString(t *testing.T, src string) (string, []error) {
+	outs, errs := decompileStrings(t, src)
+	return outs[0], errs
+}
+
+// decompileStrings decompiles the given LLVM IR modules to the Go source code
+// of a single Go package, returning one Go source file per module and the
+// errors encountered during decompilation.
+func decompileStrings(t *testing.T, srcs ...string) ([]string, []error) {
+	var ms []*ir.Module
+	for i, src := range srcs {
+		m, err := asm.Parse(fmt.Sprintf("test_%d.ll", i), strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("unable to parse LLVM IR assembly; %+v", err)
+		}
+		ms = append(ms, m)
+	}
+	var errs []error
+	eh := func(err error) {
+		errs = append(errs, err)
+	}
+	gen := NewGenerator(eh, ms...)
+	gen.PkgName = "p"
+	gen.Prims = func(f *ir.Func) ([]*primitive.Primitive, error) {
+		return nil, nil
+	}
+	var outs []string
+	for _, file := range gen.Decompile() {
+		buf := &bytes.Buffer{}
+		if err := format.Node(buf, token.NewFileSet(), file); err != nil {
+			t.Fatalf("unable to format Go source code; %+v", err)
+		}
+		outs = append(outs, buf.String())
+	}
+	return outs, errs
+}
+
+// checkDecompile decompiles the given LLVM IR assembly and reports an error if
+// decompilation fails or if the generated Go source code does not contain each
+// of the wanted snippets.
+func checkDecompile(t *testing.T, name, src string, want []string) {
+	got, errs := decompileString(t, src)
+	if len(errs) > 0 {
+		t.Errorf("%q: unable to decompile; %v", name, errs)
+		return
+	}
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("%q: output mismatch; expected output containing `%s`, got `%s`", name, w, got)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package decompile
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/types"
+)
+
+// Exception handling is lifted to panic and recover. An invoke terminator is
+// lifted to a call within a deferred-recover helper function, which records
+// the value passed to panic by the callee. The landing pad is reached through
+// the recover branch, and resume re-panics with the recorded value.
+//
+//    exc, unwound = tryCall(func() {
+//       x = f()
+//    })
+//    if unwound {
+//       // landing pad
+//       panic(exc)
+//    }
+
+const (
+	// tryCallName is the name of the deferred-recover helper function.
+	tryCallName = "tryCall"
+	// excName is the name of the variable holding the value passed to panic.
+	excName = "exc"
+	// unwoundName is the name of the variable specifying whether the call of
+	// the most recent invoke terminator unwound.
+	unwoundName = "unwound"
+)
+
+// liftInvoke lifts the call of the LLVM IR invoke terminator to Go source code,
+// emitting to f. The returned condition is true if the call returned normally.
+//
+//    exc, unwound = tryCall(func() {
+//       x = f()
+//    })
+func (fgen *funcGen) liftInvoke(term *ir.TermInvoke) ast.Expr {
+	fgen.gen.addTryCallHelper()
+	// Callee.
+	callee := fgen.liftCallee(term.Invokee)
+	var args []ast.Expr
+	for i, irArg := range term.Args {
+		arg := fgen.liftCallArg(term.Invokee, i, irArg)
+		args = append(args, arg)
+	}
+	var stmt ast.Stmt = &ast.ExprStmt{X: callExpr(callee, args...)}
+	if !types.Equal(term.Type(), types.Void) {
+		stmt = assignStmt(newIdent(term), callExpr(callee, args...))
+	}
+	body := &ast.FuncLit{
+		Type: &ast.FuncType{Params: &ast.FieldList{}},
+		Body: &ast.BlockStmt{List: []ast.Stmt{stmt}},
+	}
+	tryStmt := &ast.AssignStmt{
+		Lhs: []ast.Expr{ast.NewIdent(excName), ast.NewIdent(unwoundName)},
+		Tok: token.ASSIGN,
+		Rhs: []ast.Expr{callExpr(ast.NewIdent(tryCallName), body)},
+	}
+	fgen.cur.List = append(fgen.cur.List, tryStmt)
+	return &ast.UnaryExpr{Op: token.NOT, X: ast.NewIdent(unwoundName)}
+}
+
+// liftTermInvoke lifts the LLVM IR invoke terminator to Go source code,
+// emitting to f.
+func (fgen *funcGen) liftTermInvoke(term *ir.TermInvoke) {
+	succs := term.Succs()
+	ifStmt := &ast.IfStmt{
+		Cond: fgen.liftInvoke(term),
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{&ast.BranchStmt{Tok: token.GOTO, Label: newIdent(succs[0])}},
+		},
+		Else: &ast.BlockStmt{
+			List: []ast.Stmt{&ast.BranchStmt{Tok: token.GOTO, Label: newIdent(succs[1])}},
+		},
+	}
+	fgen.cur.List = append(fgen.cur.List, ifStmt)
+}
+
+// liftInvokeUnwind lifts the call of the LLVM IR invoke terminator to Go source
+// code, branching to the landing pad if the call unwound, emitting to f. Control
+// flow falls through to the structured normal successor otherwise.
+//
+//    exc, unwound = tryCall(func() {
+//       x = f()
+//    })
+//    if unwound {
+//       goto lpad
+//    }
+func (fgen *funcGen) liftInvokeUnwind(term *ir.TermInvoke) {
+	fgen.liftInvoke(term)
+	ifStmt := &ast.IfStmt{
+		Cond: ast.NewIdent(unwoundName),
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{&ast.BranchStmt{Tok: token.GOTO, Label: newIdent(term.Succs()[1])}},
+		},
+	}
+	fgen.cur.List = append(fgen.cur.List, ifStmt)
+}
+
+// liftInstLandingPad lifts the LLVM IR landingpad instruction to Go source
+// code, emitting to f. The landing pad value is the value passed to panic, and
+// the clauses are documented in the doc comment of f.
+//
+//    // landingpad: cleanup; catch i8* @_ZTIi
+//
+//    x = exc
+func (fgen *funcGen) liftInstLandingPad(inst *ir.InstLandingPad) {
+	var clauses []string
+	if inst.Cleanup {
+		clauses = append(clauses, "cleanup")
+	}
+	for _, clause := range inst.Clauses {
+		clauses = append(clauses, clause.String())
+	}
+	text := fmt.Sprintf("landingpad: %s", strings.Join(clauses, "; "))
+	fgen.addDocComment(text)
+	fgen.cur.List = append(fgen.cur.List, assignStmt(newIdent(inst), ast.NewIdent(excName)))
+}
+
+// liftInstCatchPad lifts the LLVM IR catchpad instruction to Go source code,
+// emitting to f. Catch pads are reached through the recover branch, and are
+// documented in the doc comment of f.
+//
+//    // catchpad: within %cs [i8* null, i32 64, i8* null]
+func (fgen *funcGen) liftInstCatchPad(inst *ir.InstCatchPad) {
+	text := strings.TrimPrefix(inst.LLString(), inst.Ident()+" = ")
+	fgen.addDocComment(strings.Replace(text, "catchpad ", "catchpad: ", 1))
+}
+
+// liftInstCleanupPad lifts the LLVM IR cleanuppad instruction to Go source
+// code, emitting to f. Cleanup pads are reached through the recover branch, and
+// are documented in the doc comment of f.
+//
+//    // cleanuppad: within none []
+func (fgen *funcGen) liftInstCleanupPad(inst *ir.InstCleanupPad) {
+	text := strings.TrimPrefix(inst.LLString(), inst.Ident()+" = ")
+	fgen.addDocComment(strings.Replace(text, "cleanuppad ", "cleanuppad: ", 1))
+}
+
+// liftTermResume lifts the LLVM IR resume terminator to Go source code,
+// emitting to f. Unwinding is resumed by re-panicking with the recovered value.
+//
+//    panic(exc)
+func (fgen *funcGen) liftTermResume(term *ir.TermResume) {
+	fgen.cur.List = append(fgen.cur.List, panicStmt())
+}
+
+// liftTermCatchSwitch lifts the LLVM IR catchswitch terminator to Go source
+// code, emitting to f. As the value passed to panic carries no type information
+// of the originating exception, the first handler catches every exception. The
+// remaining handlers are documented by a TODO comment in the doc comment of f.
+//
+//    // TODO: catchswitch: handlers %h2, %h3 unreachable
+//
+//    goto h1
+func (fgen *funcGen) liftTermCatchSwitch(term *ir.TermCatchSwitch) {
+	if len(term.Handlers) > 1 {
+		var handlers []string
+		for _, handler := range term.Handlers[1:] {
+			handlers = append(handlers, handler.Ident())
+		}
+		fgen.addDocComment(fmt.Sprintf("TODO: catchswitch: handlers %s unreachable", strings.Join(handlers, ", ")))
+	}
+	gotoStmt := &ast.BranchStmt{
+		Tok:   token.GOTO,
+		Label: newIdent(term.Handlers[0]),
+	}
+	fgen.cur.List = append(fgen.cur.List, gotoStmt)
+}
+
+// liftTermCatchRet lifts the LLVM IR catchret terminator to Go source code,
+// emitting to f. The exception is handled, and control flow continues at the
+// target basic block.
+func (fgen *funcGen) liftTermCatchRet(term *ir.TermCatchRet) {
+	gotoStmt := &ast.BranchStmt{
+		Tok:   token.GOTO,
+		Label: newIdent(term.Succs()[0]),
+	}
+	fgen.cur.List = append(fgen.cur.List, gotoStmt)
+}
+
+// liftTermCleanupRet lifts the LLVM IR cleanupret terminator to Go source code,
+// emitting to f. After the cleanup, unwinding continues at the unwind target
+// (if any), or is resumed in the caller by re-panicking with the recovered
+// value.
+func (fgen *funcGen) liftTermCleanupRet(term *ir.TermCleanupRet) {
+	if succs := term.Succs(); len(succs) > 0 {
+		gotoStmt := &ast.BranchStmt{
+			Tok:   token.GOTO,
+			Label: newIdent(succs[0]),
+		}
+		fgen.cur.List = append(fgen.cur.List, gotoStmt)
+		return
+	}
+	fgen.cur.List = append(fgen.cur.List, panicStmt())
+}
+
+// panicStmt returns the Go statement re-panicking with the value passed to
+// panic.
+//
+//    panic(exc)
+func panicStmt() ast.Stmt {
+	return &ast.ExprStmt{
+		X: callExpr(ast.NewIdent("panic"), ast.NewIdent(excName)),
+	}
+}
+
+// addTryCallHelper appends the deferred-recover helper function to the Go
+// source file, if not already present.
+//
+//    // tryCall calls f, and returns the value passed to panic if f panics.
+//    func tryCall(f func()) (exc interface{}, unwound bool) {
+//       defer func() {
+//          if e := recover(); e != nil {
+//             exc, unwound = e, true
+//          }
+//       }()
+//       f()
+//       return nil, false
+//    }
+func (gen *Generator) addTryCallHelper() {
+	if gen.helpers[tryCallName] {
+		return
+	}
+	gen.helpers[tryCallName] = true
+	e := ast.NewIdent("e")
+	recoverStmt := &ast.IfStmt{
+		Init: &ast.AssignStmt{
+			Lhs: []ast.Expr{e},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{callExpr(ast.NewIdent("recover"))},
+		},
+		Cond: &ast.BinaryExpr{X: e, Op: token.NEQ, Y: ast.NewIdent("nil")},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.AssignStmt{
+					Lhs: []ast.Expr{ast.NewIdent(excName), ast.NewIdent(unwoundName)},
+					Tok: token.ASSIGN,
+					Rhs: []ast.Expr{e, ast.NewIdent("true")},
+				},
+			},
+		},
+	}
+	deferStmt := &ast.DeferStmt{
+		Call: callExpr(&ast.FuncLit{
+			Type: &ast.FuncType{Params: &ast.FieldList{}},
+			Body: &ast.BlockStmt{List: []ast.Stmt{recoverStmt}},
+		}),
+	}
+	f := ast.NewIdent("f")
+	funcDecl := &ast.FuncDecl{
+		Name: ast.NewIdent(tryCallName),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{{
+					Names: []*ast.Ident{f},
+					Type:  &ast.FuncType{Params: &ast.FieldList{}},
+				}},
+			},
+			Results: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{ast.NewIdent(excName)}, Type: &ast.InterfaceType{Methods: &ast.FieldList{}}},
+					{Names: []*ast.Ident{ast.NewIdent(unwoundName)}, Type: ast.NewIdent("bool")},
+				},
+			},
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				deferStmt,
+				&ast.ExprStmt{X: callExpr(f)},
+				&ast.ReturnStmt{Results: []ast.Expr{ast.NewIdent("nil"), ast.NewIdent("false")}},
+			},
+		},
+	}
+	gen.file.Decls = append(gen.file.Decls, funcDecl)
+}
@@ -0,0 +1,114 @@
+package decompile
+
+import "testing"
+
+func TestExceptionHandling(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Landing pad reached through unwinding invoke terminator, resuming
+		// unwinding in the caller.
+		{
+			name: "landingpad",
+			src: `
+declare i32 @g(i32)
+declare i32 @__gxx_personality_v0(...)
+
+define i32 @f(i32 %x) personality i32 (...)* @__gxx_personality_v0 {
+entry:
+	%y = invoke i32 @g(i32 %x)
+		to label %ok unwind label %lpad
+
+ok:
+	ret i32 %y
+
+lpad:
+	%e = landingpad { i8*, i32 }
+		cleanup
+	resume { i8*, i32 } %e
+}
+`,
+			want: []string{
+				`// landingpad: cleanup`,
+				`exc, unwound = tryCall(func() {`,
+				`y = g(x)`,
+				`if !unwound {`,
+				`goto ok`,
+				`goto lpad`,
+				`e = exc`,
+				`panic(exc)`,
+				`func tryCall(f func()) (exc interface{}, unwound bool) {`,
+			},
+		},
+		// Catch switch with multiple handlers; the first handler catches every
+		// exception.
+		{
+			name: "catchswitch",
+			src: `
+declare void @g()
+declare i32 @__CxxFrameHandler3(...)
+
+define void @f() personality i32 (...)* @__CxxFrameHandler3 {
+entry:
+	invoke void @g()
+		to label %exit unwind label %dispatch
+
+dispatch:
+	%cs = catchswitch within none [label %h1, label %h2] unwind to caller
+
+h1:
+	%p1 = catchpad within %cs [i8* null, i32 64, i8* null]
+	catchret from %p1 to label %exit
+
+h2:
+	%p2 = catchpad within %cs [i8* null, i32 64, i8* null]
+	catchret from %p2 to label %exit
+
+exit:
+	ret void
+}
+`,
+			want: []string{
+				`// TODO: catchswitch: handlers %h2 unreachable`,
+				`// catchpad: within %cs`,
+				`exc, unwound = tryCall(func() {`,
+				`goto dispatch`,
+				`goto h1`,
+				`goto exit`,
+			},
+		},
+		// Local variables named as the results of the deferred-recover helper
+		// function.
+		{
+			name: "reserved",
+			src: `
+declare i32 @g(i32)
+declare i32 @__gxx_personality_v0(...)
+
+define i32 @f(i32 %exc, i32 %unwound) personality i32 (...)* @__gxx_personality_v0 {
+entry:
+	%y = invoke i32 @g(i32 %exc)
+		to label %ok unwind label %lpad
+
+ok:
+	ret i32 %unwound
+
+lpad:
+	%e = landingpad { i8*, i32 }
+		cleanup
+	resume { i8*, i32 } %e
+}
+`,
+			want: []string{
+				`y = g(exc_)`,
+				`return unwound_`,
+				`panic(exc)`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
@@ -1,6 +1,12 @@
 package decompile
 
-import "github.com/pkg/errors"
+import (
+	"fmt"
+	"go/ast"
+
+	"github.com/llir/llvm/ir"
+	"github.com/pkg/errors"
+)
 
 // Errorf formats according to a format specifier and returns the string as a
 // value that satisfies error. The error is also passed to the error handler of
@@ -10,3 +16,137 @@ func (gen *Generator) Errorf(format string, a ...interface{}) error {
 	gen.eh(err)
 	return err
 }
+
+// Error is an error encountered during decompilation, annotated with the
+// location of the LLVM IR construct which failed to decompile.
+type Error struct {
+	// Source file name of the LLVM IR module.
+	Module string `json:"module,omitempty"`
+	// Name of the LLVM IR function (without '@' prefix); or empty if outside of
+	// function.
+	Func string `json:"func,omitempty"`
+	// LLVM IR assembly of the instruction or terminator; or empty if outside of
+	// instruction.
+	Inst string `json:"inst,omitempty"`
+	// Error message.
+	Msg string `json:"error"`
+	// Underlying error.
+	err error
+}
+
+// Error returns the error message of the decompilation error, prefixed by its
+// location.
+func (e *Error) Error() string {
+	msg := e.Msg
+	if len(e.Inst) > 0 {
+		msg = fmt.Sprintf("%s: %s", e.Inst, msg)
+	}
+	if len(e.Func) > 0 {
+		msg = fmt.Sprintf("function %q: %s", e.Func, msg)
+	}
+	return msg
+}
+
+// Cause returns the underlying error of the decompilation error.
+func (e *Error) Cause() error {
+	return e.err
+}
+
+// Format implements fmt.Formatter, printing the stack trace of the underlying
+// error with the %+v verb.
+func (e *Error) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "%s\n%+v", e.Error(), e.err)
+		return
+	}
+	fmt.Fprint(s, e.Error())
+}
+
+// handleError annotates the given error with the location of the LLVM IR
+// construct being decompiled, and passes it to the error handler eh.
+func (gen *Generator) handleError(eh func(error), err error) {
+	gen.nerrs++
+	e := &Error{
+		Func: gen.curFunc,
+		Inst: gen.curInst,
+		Msg:  err.Error(),
+		err:  err,
+	}
+	if gen.m != nil {
+		e.Module = gen.m.SourceFilename
+	}
+	if len(gen.curFunc) > 0 && len(gen.curInst) == 0 && gen.funcErr == nil {
+		gen.funcErr = err
+	}
+	eh(e)
+}
+
+// recoverError returns the error of the given value recovered from a panic.
+func recoverError(e interface{}) error {
+	if err, ok := e.(error); ok {
+		return errors.WithStack(err)
+	}
+	return errors.Errorf("%v", e)
+}
+
+// liftStmt invokes lift to lift the given LLVM IR instruction or terminator to
+// Go source code, emitting to f. On error, the statements emitted by lift are
+// discarded, a TODO comment holding the LLVM IR assembly of the instruction or
+// terminator is added to the doc comment of f, and decompilation continues with
+// the next instruction.
+//
+//    // TODO: %5 = fneg float %4
+func (fgen *funcGen) liftStmt(inst interface{ LLString() string }, lift func()) {
+	cur := fgen.cur
+	n := len(cur.List)
+	nerrs := fgen.gen.nerrs
+	prev := fgen.gen.curInst
+	fgen.gen.curInst = inst.LLString()
+	func() {
+		defer func() {
+			if e := recover(); e != nil {
+				fgen.gen.eh(recoverError(e))
+			}
+		}()
+		lift()
+	}()
+	fgen.gen.curInst = prev
+	if fgen.gen.nerrs > nerrs {
+		fgen.cur = cur
+		cur.List = cur.List[:n]
+		fgen.addDocComment("TODO: " + inst.LLString())
+	}
+}
+
+// decompileFuncDefOrStub decompiles the LLVM IR function definition to Go
+// source code, emitting to f. If decompilation of the function fails (i.e. an
+// error is encountered outside of its instructions and terminators), its body
+// is replaced by a stub which panics with the error message.
+//
+//    panic("decompile: unable to decompile function f: ...")
+func (fgen *funcGen) decompileFuncDefOrStub(irFunc *ir.Func) {
+	fgen.gen.curFunc = irFunc.Name()
+	fgen.gen.funcErr = nil
+	defer func() {
+		if e := recover(); e != nil {
+			err := recoverError(e)
+			fgen.gen.eh(err)
+			fgen.stub(irFunc, err)
+		} else if err := fgen.gen.funcErr; err != nil {
+			fgen.stub(irFunc, err)
+		}
+		fgen.gen.curFunc = ""
+		fgen.gen.funcErr = nil
+	}()
+	fgen.decompileFuncDef(irFunc)
+}
+
+// stub replaces the body of the Go function of the given LLVM IR function by a
+// stub which panics with the given error message.
+func (fgen *funcGen) stub(irFunc *ir.Func, err error) {
+	msg := fmt.Sprintf("decompile: unable to decompile function %s: %v", irFunc.Name(), err)
+	stub := &ast.ExprStmt{
+		X: callExpr(ast.NewIdent("panic"), strLit(msg)),
+	}
+	fgen.f.Body = &ast.BlockStmt{List: []ast.Stmt{stub}}
+}
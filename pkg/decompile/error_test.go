@@ -0,0 +1,85 @@
+package decompile
+
+import (
+	"bytes"
+	"go/format"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
+	"github.com/pkg/errors"
+)
+
+func TestStub(t *testing.T) {
+	const src = `
+define i32 @f(i32 %x) {
+entry:
+	br label %exit
+
+exit:
+	ret i32 %x
+}
+`
+	golden := []struct {
+		name  string
+		prims []*primitive.Primitive
+		err   error
+		// Specifies whether the function is replaced by a stub.
+		stub bool
+	}{
+		// Error while recovering control flow primitives.
+		{
+			name: "invalid_prim",
+			prims: []*primitive.Primitive{
+				{Prim: "seq", Entry: "entry", Nodes: map[string]string{"entry": "entry", "exit": "missing"}},
+			},
+			stub: true,
+		},
+		// Unsupported primitives (e.g. of user-defined templates) are rejected,
+		// and the control flow is lifted using goto statements.
+		{
+			name: "unknown_prim",
+			prims: []*primitive.Primitive{
+				{Prim: "switch_3", Entry: "entry", Nodes: map[string]string{"cond": "entry", "exit": "exit"}},
+			},
+			stub: false,
+		},
+		// Incomplete control flow recovery is lifted using goto statements.
+		{
+			name: "prims_error",
+			err:  errors.New("incomplete control flow recovery"),
+			stub: false,
+		},
+	}
+	for _, g := range golden {
+		m, err := asm.Parse("test.ll", strings.NewReader(src))
+		if err != nil {
+			t.Fatalf("unable to parse LLVM IR assembly; %+v", err)
+		}
+		var errs []error
+		eh := func(err error) {
+			errs = append(errs, err)
+		}
+		gen := NewGenerator(eh, m)
+		gen.PkgName = "p"
+		gen.Prims = func(f *ir.Func) ([]*primitive.Primitive, error) {
+			return g.prims, g.err
+		}
+		file := gen.Decompile()[0]
+		buf := &bytes.Buffer{}
+		if err := format.Node(buf, token.NewFileSet(), file); err != nil {
+			t.Fatalf("unable to format Go source code; %+v", err)
+		}
+		got := buf.String()
+		if len(errs) == 0 {
+			t.Errorf("%q: expected error, got none", g.name)
+		}
+		stub := strings.Contains(got, `panic("decompile: unable to decompile function f: `)
+		if stub != g.stub {
+			t.Errorf("%q: stub mismatch; expected %v, got %v; output `%s`", g.name, g.stub, stub, got)
+		}
+	}
+}
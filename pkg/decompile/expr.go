@@ -0,0 +1,577 @@
+package decompile
+
+import (
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// This file contains Go expression generators shared by the lifting of LLVM IR
+// instructions and constant expressions.
+
+// --- [ Binary expressions ] --------------------------------------------------
+
+// binOpExpr returns the Go expression of the binary operation op on the
+// operands x and y of the given LLVM IR type. If unsigned is set, the integer
+// operands are interpreted as unsigned integers.
+func (gen *Generator) binOpExpr(x, y ast.Expr, op token.Token, t types.Type, unsigned bool) (ast.Expr, error) {
+	switch t := t.(type) {
+	case *types.IntType:
+		if t.BitSize == 1 {
+			// Boolean operands.
+			switch op {
+			case token.AND, token.MUL:
+				op = token.LAND
+			case token.OR:
+				op = token.LOR
+			case token.XOR, token.ADD, token.SUB:
+				op = token.NEQ
+			default:
+				return nil, errors.Errorf("support for binary operation %v on boolean operands not yet implemented", op)
+			}
+			return &ast.BinaryExpr{X: x, Op: op, Y: y}, nil
+		}
+		if isBigInt(t) {
+			return gen.bigIntBinOpExpr(x, y, op, t, unsigned)
+		}
+		goType, err := gen.goType(t)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if op == token.SHL || op == token.SHR {
+			// Use unsigned shift count.
+			y = goConvExpr(gotypes.Typ[gotypes.Uint64], y)
+			if unsigned {
+				// Logical shift right.
+				//
+				//    intN(uintN(x) >> uint64(y))
+				ux := gen.uintExpr(x, t)
+				return wrapIntExpr(goConvExpr(goType, &ast.BinaryExpr{X: ux, Op: op, Y: y}), t), nil
+			}
+			return wrapIntExpr(&ast.BinaryExpr{X: x, Op: op, Y: y}, t), nil
+		}
+		if unsigned {
+			//    intN(uintN(x) / uintN(y))
+			ux := gen.uintExpr(x, t)
+			uy := gen.uintExpr(y, t)
+			return wrapIntExpr(goConvExpr(goType, &ast.BinaryExpr{X: ux, Op: op, Y: uy}), t), nil
+		}
+		return wrapIntExpr(&ast.BinaryExpr{X: x, Op: op, Y: y}, t), nil
+	case *types.FloatType:
+		if gen.isBigFloat(t) {
+			return gen.bigBinOpExpr(x, y, op, "Float")
+		}
+		return &ast.BinaryExpr{X: x, Op: op, Y: y}, nil
+	case *types.VectorType:
+		return gen.vectorOpExpr(x, y, t, func(x, y ast.Expr, elemType types.Type) (ast.Expr, error) {
+			return gen.binOpExpr(x, y, op, elemType, unsigned)
+		})
+	default:
+		return nil, errors.Errorf("support for binary operation %v on operands of type %T not yet implemented", op, t)
+	}
+}
+
+// fremExpr returns the Go expression of the floating-point remainder of the
+// operands x and y of the given LLVM IR type.
+func (gen *Generator) fremExpr(x, y ast.Expr, t types.Type) (ast.Expr, error) {
+	if vt, ok := t.(*types.VectorType); ok {
+		return gen.vectorOpExpr(x, y, vt, gen.fremExpr)
+	}
+	ft, ok := t.(*types.FloatType)
+	if !ok {
+		return nil, errors.Errorf("support for floating-point remainder on operands of type %T not yet implemented", t)
+	}
+	mod := gen.qualIdent("math", "Mod")
+	if ft.Kind == types.FloatKindFloat {
+		//    float32(math.Mod(float64(x), float64(y)))
+		float64Type := gotypes.Typ[gotypes.Float64]
+		call := callExpr(mod, goConvExpr(float64Type, x), goConvExpr(float64Type, y))
+		return goConvExpr(gotypes.Typ[gotypes.Float32], call), nil
+	}
+	return callExpr(mod, x, y), nil
+}
+
+// --- [ Conversion expressions ] ----------------------------------------------
+
+// convExpr returns the Go conversion expression of x from the given LLVM IR
+// type to the given LLVM IR type.
+func (gen *Generator) convExpr(x ast.Expr, from, to types.Type) (ast.Expr, error) {
+	switch {
+	case isBigInt(from):
+		// sitofp
+		return gen.bigIntToFloatExpr(x, from.(*types.IntType), to, false)
+	case isBigInt(to):
+		// fptosi
+		return gen.floatToBigIntExpr(x, from, to.(*types.IntType)), nil
+	}
+	if gen.isBigFloat(from) || gen.isBigFloat(to) {
+		return gen.bigFloatConvExpr(x, from, to)
+	}
+	goType, err := gen.goType(to)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return wrapIntExpr(goConvExpr(goType, x), to), nil
+}
+
+// intConvExpr returns the Go expression of the sign-preserving conversion of
+// the integer x from the given LLVM IR type to the given LLVM IR type (i.e.
+// trunc and sext).
+func (gen *Generator) intConvExpr(x ast.Expr, from, to types.Type) (ast.Expr, error) {
+	switch {
+	case isBigInt(from) && isBigInt(to):
+		toType := to.(*types.IntType)
+		if toType.BitSize < from.(*types.IntType).BitSize {
+			//    wrapBigInt(x, 128, false)
+			return gen.wrapBigIntExpr(x, toType, false), nil
+		}
+		return x, nil
+	case isBigInt(to):
+		//    big.NewInt(int64(x))
+		if isBool(from) {
+			x = condExpr(gotypes.Typ[gotypes.Int64], x, goIntLit(-1), goIntLit(0))
+		}
+		return callExpr(gen.qualIdent("math/big", "NewInt"), goConvExpr(gotypes.Typ[gotypes.Int64], x)), nil
+	case isBigInt(from):
+		// Truncate to 64 bits.
+		//
+		//    wrapBigInt(x, 64, false).Int64()
+		x = callExpr(&ast.SelectorExpr{X: gen.wrapBigIntExpr(x, types.I64, false), Sel: ast.NewIdent("Int64")})
+		from = types.I64
+	}
+	if isBool(to) {
+		//    x&1 != 0
+		lsb := &ast.BinaryExpr{X: x, Op: token.AND, Y: goIntLit(1)}
+		return &ast.BinaryExpr{X: lsb, Op: token.NEQ, Y: goIntLit(0)}, nil
+	}
+	goType, err := gen.goType(to)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if isBool(from) {
+		// Sign extend boolean.
+		return condExpr(goType, x, goIntLit(-1), goIntLit(0)), nil
+	}
+	return wrapIntExpr(goConvExpr(goType, x), to), nil
+}
+
+// zextExpr returns the Go expression of the zero extension of the integer x
+// from the given LLVM IR type to the given LLVM IR type.
+func (gen *Generator) zextExpr(x ast.Expr, from, to types.Type) (ast.Expr, error) {
+	switch {
+	case isBigInt(from):
+		//    wrapBigInt(x, 128, true)
+		return gen.wrapBigIntExpr(x, from.(*types.IntType), true), nil
+	case isBigInt(to):
+		//    new(big.Int).SetUint64(uint64(uintN(x)))
+		if isBool(from) {
+			x = condExpr(gotypes.Typ[gotypes.Uint64], x, goIntLit(1), goIntLit(0))
+		} else if fromType, ok := from.(*types.IntType); ok {
+			x = gen.uintExpr(x, fromType)
+		}
+		z := callExpr(ast.NewIdent("new"), gen.qualIdent("math/big", "Int"))
+		setUint64 := &ast.SelectorExpr{X: z, Sel: ast.NewIdent("SetUint64")}
+		return callExpr(setUint64, goConvExpr(gotypes.Typ[gotypes.Uint64], x)), nil
+	}
+	goType, err := gen.goType(to)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if isBool(from) {
+		return condExpr(goType, x, goIntLit(1), goIntLit(0)), nil
+	}
+	fromType, ok := from.(*types.IntType)
+	if !ok {
+		return nil, errors.Errorf("invalid zext operand type; expected *types.IntType, got %T", from)
+	}
+	//    intM(uintN(x))
+	return goConvExpr(goType, gen.uintExpr(x, fromType)), nil
+}
+
+// fptouiExpr returns the Go expression of the conversion of the floating-point
+// value x from the given LLVM IR type to the given unsigned LLVM IR integer
+// type.
+func (gen *Generator) fptouiExpr(x ast.Expr, from, to types.Type) (ast.Expr, error) {
+	toType, ok := to.(*types.IntType)
+	if !ok {
+		return nil, errors.Errorf("invalid fptoui result type; expected *types.IntType, got %T", to)
+	}
+	if isBigInt(toType) {
+		return gen.floatToBigIntExpr(x, from, toType), nil
+	}
+	goType, err := gen.goType(toType)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//    intN(uintN(x))
+	return wrapIntExpr(goConvExpr(goType, goConvExpr(gen.goUintType(toType), x)), toType), nil
+}
+
+// uitofpExpr returns the Go expression of the conversion of the unsigned
+// integer x from the given LLVM IR type to the given floating-point LLVM IR
+// type.
+func (gen *Generator) uitofpExpr(x ast.Expr, from, to types.Type) (ast.Expr, error) {
+	if fromType, ok := from.(*types.IntType); ok && isBigInt(fromType) {
+		return gen.bigIntToFloatExpr(x, fromType, to, true)
+	}
+	goType, err := gen.goType(to)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if isBool(from) {
+		return condExpr(goType, x, goIntLit(1), goIntLit(0)), nil
+	}
+	fromType, ok := from.(*types.IntType)
+	if !ok {
+		return nil, errors.Errorf("invalid uitofp operand type; expected *types.IntType, got %T", from)
+	}
+	//    float64(uintN(x))
+	return goConvExpr(goType, gen.uintExpr(x, fromType)), nil
+}
+
+// ptrtointExpr returns the Go expression of the conversion of the pointer x
+// from the given LLVM IR type to the given LLVM IR integer type.
+func (gen *Generator) ptrtointExpr(x ast.Expr, from, to types.Type) (ast.Expr, error) {
+	goType, err := gen.goType(to)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if isFuncPtr(from) {
+		goFrom, err := gen.goType(from)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		addr := gen.funcPtrCastExpr(x, goFrom, gotypes.Typ[gotypes.Uintptr])
+		return goConvExpr(goType, addr), nil
+	}
+	//    intN(uintptr(unsafe.Pointer(x)))
+	addr := goConvExpr(gotypes.Typ[gotypes.Uintptr], gen.unsafePointerExpr(x))
+	return goConvExpr(goType, addr), nil
+}
+
+// inttoptrExpr returns the Go expression of the conversion of the integer x
+// from the given LLVM IR type to the given LLVM IR pointer type.
+func (gen *Generator) inttoptrExpr(x ast.Expr, from, to types.Type) (ast.Expr, error) {
+	goType, err := gen.goType(to)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	//    (*T)(unsafe.Pointer(uintptr(x)))
+	addr := goConvExpr(gotypes.Typ[gotypes.Uintptr], x)
+	if isFuncPtr(to) {
+		return gen.funcPtrCastExpr(addr, gotypes.Typ[gotypes.Uintptr], goType), nil
+	}
+	return goConvExpr(goType, gen.unsafePointerExpr(addr)), nil
+}
+
+// bitcastExpr returns the Go expression of the reinterpretation of the bits of
+// x from the given LLVM IR type as the given LLVM IR type.
+func (gen *Generator) bitcastExpr(x ast.Expr, from, to types.Type) (ast.Expr, error) {
+	if types.Equal(from, to) {
+		return x, nil
+	}
+	goType, err := gen.goType(to)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if isFuncPtr(from) || isFuncPtr(to) {
+		return gen.funcPtrBitcastExpr(x, from, to)
+	}
+	switch from := from.(type) {
+	case *types.PointerType:
+		if _, ok := to.(*types.PointerType); ok {
+			//    (*T)(unsafe.Pointer(x))
+			return goConvExpr(goType, gen.unsafePointerExpr(x)), nil
+		}
+	case *types.IntType:
+		if to, ok := to.(*types.FloatType); ok {
+			switch to.Kind {
+			case types.FloatKindFloat:
+				//    math.Float32frombits(uint32(x))
+				bits := goConvExpr(gotypes.Typ[gotypes.Uint32], x)
+				return callExpr(gen.qualIdent("math", "Float32frombits"), bits), nil
+			case types.FloatKindDouble:
+				//    math.Float64frombits(uint64(x))
+				bits := goConvExpr(gotypes.Typ[gotypes.Uint64], x)
+				return callExpr(gen.qualIdent("math", "Float64frombits"), bits), nil
+			}
+		}
+	case *types.FloatType:
+		if _, ok := to.(*types.IntType); ok {
+			switch from.Kind {
+			case types.FloatKindFloat:
+				//    int32(math.Float32bits(x))
+				return goConvExpr(goType, callExpr(gen.qualIdent("math", "Float32bits"), x)), nil
+			case types.FloatKindDouble:
+				//    int64(math.Float64bits(x))
+				return goConvExpr(goType, callExpr(gen.qualIdent("math", "Float64bits"), x)), nil
+			}
+		}
+	}
+	return nil, errors.Errorf("support for bitcast from %v to %v not yet implemented", from, to)
+}
+
+// --- [ Comparison expressions ] ----------------------------------------------
+
+// icmpExpr returns the Go expression of the integer comparison of the operands
+// x and y of the given LLVM IR type, based on the given predicate.
+func (gen *Generator) icmpExpr(pred enum.IPred, x, y ast.Expr, t types.Type) ast.Expr {
+	if t, ok := t.(*types.IntType); ok && isBigInt(t) {
+		if isUnsignedIPred(pred) {
+			x = gen.wrapBigIntExpr(x, t, true)
+			y = gen.wrapBigIntExpr(y, t, true)
+		}
+		//    x.Cmp(y) < 0
+		return bigCmpExpr(x, y, ipred(pred))
+	}
+	switch pred {
+	case enum.IPredEQ, enum.IPredNE:
+		// Equality comparison is independent of sign.
+	default:
+		switch t := t.(type) {
+		case *types.PointerType:
+			// Compare addresses.
+			x = goConvExpr(gotypes.Typ[gotypes.Uintptr], gen.unsafePointerExpr(x))
+			y = goConvExpr(gotypes.Typ[gotypes.Uintptr], gen.unsafePointerExpr(y))
+		case *types.IntType:
+			if isUnsignedIPred(pred) && t.BitSize > 1 {
+				x = gen.uintExpr(x, t)
+				y = gen.uintExpr(y, t)
+			}
+		}
+	}
+	return &ast.BinaryExpr{X: x, Op: ipred(pred), Y: y}
+}
+
+// isUnsignedIPred reports whether the given integer comparison predicate
+// interprets its operands as unsigned integers.
+func isUnsignedIPred(pred enum.IPred) bool {
+	switch pred {
+	case enum.IPredUGE, enum.IPredUGT, enum.IPredULE, enum.IPredULT:
+		return true
+	default:
+		return false
+	}
+}
+
+// fcmpExpr returns the Go expression of the floating-point comparison of the
+// operands x and y of the given LLVM IR type, based on the given predicate.
+//
+// Go comparison operators on floating-point values are ordered (i.e. false if
+// either operand is NaN), except for != which is unordered. The unordered
+// predicates are thus expressed as the negation of the inverse ordered
+// comparison (e.g. ult is !(x >= y)).
+func (gen *Generator) fcmpExpr(pred enum.FPred, x, y ast.Expr, t types.Type) (ast.Expr, error) {
+	if gen.isBigFloat(t) {
+		return bigFcmpExpr(pred, x, y)
+	}
+	switch pred {
+	case enum.FPredFalse:
+		return ast.NewIdent("false"), nil
+	case enum.FPredTrue:
+		return ast.NewIdent("true"), nil
+	// Ordered predicates.
+	case enum.FPredOEQ:
+		return &ast.BinaryExpr{X: x, Op: token.EQL, Y: y}, nil
+	case enum.FPredOGT:
+		return &ast.BinaryExpr{X: x, Op: token.GTR, Y: y}, nil
+	case enum.FPredOGE:
+		return &ast.BinaryExpr{X: x, Op: token.GEQ, Y: y}, nil
+	case enum.FPredOLT:
+		return &ast.BinaryExpr{X: x, Op: token.LSS, Y: y}, nil
+	case enum.FPredOLE:
+		return &ast.BinaryExpr{X: x, Op: token.LEQ, Y: y}, nil
+	case enum.FPredONE:
+		//    x < y || x > y
+		lss := &ast.BinaryExpr{X: x, Op: token.LSS, Y: y}
+		gtr := &ast.BinaryExpr{X: x, Op: token.GTR, Y: y}
+		return &ast.BinaryExpr{X: lss, Op: token.LOR, Y: gtr}, nil
+	case enum.FPredORD:
+		//    !math.IsNaN(x) && !math.IsNaN(y)
+		notNaNX := &ast.UnaryExpr{Op: token.NOT, X: gen.isNaNExpr(x, t)}
+		notNaNY := &ast.UnaryExpr{Op: token.NOT, X: gen.isNaNExpr(y, t)}
+		return &ast.BinaryExpr{X: notNaNX, Op: token.LAND, Y: notNaNY}, nil
+	// Unordered predicates.
+	case enum.FPredUEQ:
+		//    !(x < y || x > y)
+		lss := &ast.BinaryExpr{X: x, Op: token.LSS, Y: y}
+		gtr := &ast.BinaryExpr{X: x, Op: token.GTR, Y: y}
+		return notExpr(&ast.BinaryExpr{X: lss, Op: token.LOR, Y: gtr}), nil
+	case enum.FPredUGT:
+		return notExpr(&ast.BinaryExpr{X: x, Op: token.LEQ, Y: y}), nil
+	case enum.FPredUGE:
+		return notExpr(&ast.BinaryExpr{X: x, Op: token.LSS, Y: y}), nil
+	case enum.FPredULT:
+		return notExpr(&ast.BinaryExpr{X: x, Op: token.GEQ, Y: y}), nil
+	case enum.FPredULE:
+		return notExpr(&ast.BinaryExpr{X: x, Op: token.GTR, Y: y}), nil
+	case enum.FPredUNE:
+		// Note, != is unordered in Go.
+		return &ast.BinaryExpr{X: x, Op: token.NEQ, Y: y}, nil
+	case enum.FPredUNO:
+		//    math.IsNaN(x) || math.IsNaN(y)
+		return &ast.BinaryExpr{X: gen.isNaNExpr(x, t), Op: token.LOR, Y: gen.isNaNExpr(y, t)}, nil
+	default:
+		return nil, errors.Errorf("support for floating-point comparison predicate %v not yet implemented", pred)
+	}
+}
+
+// bigFcmpExpr returns the Go expression of the floating-point comparison of the
+// arbitrary precision operands x and y, based on the given predicate. As
+// big.Float has no NaN values, the ordered and unordered predicates coincide.
+//
+//    x.Cmp(y) < 0
+func bigFcmpExpr(pred enum.FPred, x, y ast.Expr) (ast.Expr, error) {
+	switch pred {
+	case enum.FPredFalse, enum.FPredUNO:
+		return ast.NewIdent("false"), nil
+	case enum.FPredTrue, enum.FPredORD:
+		return ast.NewIdent("true"), nil
+	case enum.FPredOEQ, enum.FPredUEQ:
+		return bigCmpExpr(x, y, token.EQL), nil
+	case enum.FPredOGT, enum.FPredUGT:
+		return bigCmpExpr(x, y, token.GTR), nil
+	case enum.FPredOGE, enum.FPredUGE:
+		return bigCmpExpr(x, y, token.GEQ), nil
+	case enum.FPredOLT, enum.FPredULT:
+		return bigCmpExpr(x, y, token.LSS), nil
+	case enum.FPredOLE, enum.FPredULE:
+		return bigCmpExpr(x, y, token.LEQ), nil
+	case enum.FPredONE, enum.FPredUNE:
+		return bigCmpExpr(x, y, token.NEQ), nil
+	default:
+		return nil, errors.Errorf("support for floating-point comparison predicate %v not yet implemented", pred)
+	}
+}
+
+// isNaNExpr returns the Go expression reporting whether the floating-point
+// value x of the given LLVM IR type is NaN.
+func (gen *Generator) isNaNExpr(x ast.Expr, t types.Type) ast.Expr {
+	if ft, ok := t.(*types.FloatType); ok && ft.Kind == types.FloatKindFloat {
+		x = goConvExpr(gotypes.Typ[gotypes.Float64], x)
+	}
+	return callExpr(gen.qualIdent("math", "IsNaN"), x)
+}
+
+// --- [ Address expressions ] -------------------------------------------------
+
+// gepExpr returns the Go expression of the address computed by a getelementptr
+// operation with the given element type, source address and indices. The
+// liftIndex function is used to lift index operands to Go expressions.
+func (gen *Generator) gepExpr(elemType types.Type, src ast.Expr, indices []value.Value, liftIndex func(v value.Value) (ast.Expr, error)) (ast.Expr, error) {
+	if len(indices) == 0 {
+		return src, nil
+	}
+	ptr := src
+	if i, ok := constIndex(indices[0]); !ok || i != 0 {
+		// Pointer arithmetic on the source address.
+		//
+		//    (*T)(unsafe.Pointer(uintptr(unsafe.Pointer(src)) + uintptr(index)*unsafe.Sizeof(*src)))
+		index, err := liftIndex(indices[0])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		goElemType, err := gen.goType(elemType)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		uintptrType := gotypes.Typ[gotypes.Uintptr]
+		size := callExpr(gen.qualIdent("unsafe", "Sizeof"), derefExpr(src))
+		offset := &ast.BinaryExpr{X: goConvExpr(uintptrType, index), Op: token.MUL, Y: size}
+		addr := &ast.BinaryExpr{X: goConvExpr(uintptrType, gen.unsafePointerExpr(src)), Op: token.ADD, Y: offset}
+		ptr = goConvExpr(gotypes.NewPointer(goElemType), gen.unsafePointerExpr(addr))
+	}
+	obj := derefExpr(ptr)
+	t := elemType
+	for _, index := range indices[1:] {
+		switch tt := t.(type) {
+		case *types.ArrayType:
+			idx, err := liftIndex(index)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			obj = &ast.IndexExpr{X: autoDerefExpr(obj), Index: idx}
+			t = tt.ElemType
+		case *types.VectorType:
+			idx, err := liftIndex(index)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			obj = &ast.IndexExpr{X: autoDerefExpr(obj), Index: idx}
+			t = tt.ElemType
+		case *types.StructType:
+			i, ok := constIndex(index)
+			if !ok || i >= uint64(len(tt.Fields)) {
+				return nil, errors.Errorf("invalid struct field index %v of struct type %v", index, tt)
+			}
+			obj = &ast.SelectorExpr{X: autoDerefExpr(obj), Sel: ast.NewIdent(fieldName(int(i)))}
+			t = tt.Fields[i]
+		default:
+			return nil, errors.Errorf("support for getelementptr indexing into type %T not yet implemented", t)
+		}
+	}
+	return addrExpr(obj), nil
+}
+
+// aggregateElemExpr returns the Go expression of the element at the given
+// indices of the aggregate value x of the given LLVM IR type.
+func (gen *Generator) aggregateElemExpr(x ast.Expr, t types.Type, indices []uint64) (ast.Expr, error) {
+	for _, index := range indices {
+		switch tt := t.(type) {
+		case *types.ArrayType:
+			x = &ast.IndexExpr{X: x, Index: goUintLit(index)}
+			t = tt.ElemType
+		case *types.StructType:
+			if index >= uint64(len(tt.Fields)) {
+				return nil, errors.Errorf("invalid struct field index %d of struct type %v", index, tt)
+			}
+			x = &ast.SelectorExpr{X: x, Sel: ast.NewIdent(fieldName(int(index)))}
+			t = tt.Fields[index]
+		default:
+			return nil, errors.Errorf("support for aggregate indexing into type %T not yet implemented", t)
+		}
+	}
+	return x, nil
+}
+
+// constIndex returns the integer value of the given constant index operand.
+// The boolean return value indicates success.
+func constIndex(index value.Value) (uint64, bool) {
+	c, ok := index.(constant.Constant)
+	if !ok {
+		return 0, false
+	}
+	i, ok := foldConst(c).(*constant.Int)
+	if !ok || !i.X.IsUint64() {
+		return 0, false
+	}
+	return i.X.Uint64(), true
+}
+
+// unsafePointerExpr returns the Go expression converting the pointer x to
+// unsafe.Pointer.
+func (gen *Generator) unsafePointerExpr(x ast.Expr) ast.Expr {
+	return callExpr(gen.qualIdent("unsafe", "Pointer"), x)
+}
+
+// isBool reports whether the given LLVM IR type is a boolean (i1) type.
+func isBool(t types.Type) bool {
+	if t, ok := t.(*types.IntType); ok {
+		return t.BitSize == 1
+	}
+	return false
+}
+
+// binOp returns the element operation of the binary operation op. If unsigned
+// is set, the integer operands are interpreted as unsigned integers.
+func (gen *Generator) binOp(op token.Token, unsigned bool) elemOp {
+	return func(x, y ast.Expr, t types.Type) (ast.Expr, error) {
+		return gen.binOpExpr(x, y, op, t, unsigned)
+	}
+}
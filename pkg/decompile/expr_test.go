@@ -0,0 +1,171 @@
+package decompile
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFcmp(t *testing.T) {
+	golden := []struct {
+		pred string
+		typ  string
+		want string
+	}{
+		{pred: "false", typ: "double", want: `_1 = false`},
+		{pred: "true", typ: "double", want: `_1 = true`},
+		// Ordered predicates are false if either operand is NaN, as are the
+		// corresponding Go comparison operators.
+		{pred: "oeq", typ: "double", want: `_1 = x == y`},
+		{pred: "ogt", typ: "double", want: `_1 = x > y`},
+		{pred: "oge", typ: "double", want: `_1 = x >= y`},
+		{pred: "olt", typ: "double", want: `_1 = x < y`},
+		{pred: "ole", typ: "double", want: `_1 = x <= y`},
+		{pred: "one", typ: "double", want: `_1 = x < y || x > y`},
+		{pred: "ord", typ: "double", want: `_1 = !math.IsNaN(x) && !math.IsNaN(y)`},
+		// Unordered predicates are true if either operand is NaN, and are lifted
+		// to the negation of the inverse ordered comparison.
+		{pred: "ueq", typ: "double", want: `_1 = !(x < y || x > y)`},
+		{pred: "ugt", typ: "double", want: `_1 = !(x <= y)`},
+		{pred: "uge", typ: "double", want: `_1 = !(x < y)`},
+		{pred: "ult", typ: "double", want: `_1 = !(x >= y)`},
+		{pred: "ule", typ: "double", want: `_1 = !(x > y)`},
+		{pred: "une", typ: "double", want: `_1 = x != y`},
+		{pred: "uno", typ: "double", want: `_1 = math.IsNaN(x) || math.IsNaN(y)`},
+		// math.IsNaN takes float64 operands.
+		{pred: "ord", typ: "float", want: `_1 = !math.IsNaN(float64(x)) && !math.IsNaN(float64(y))`},
+		{pred: "uno", typ: "float", want: `_1 = math.IsNaN(float64(x)) || math.IsNaN(float64(y))`},
+	}
+	for _, gold := range golden {
+		name := fmt.Sprintf("fcmp %s %s", gold.pred, gold.typ)
+		src := fmt.Sprintf(`
+define i1 @f(%[2]s %%x, %[2]s %%y) {
+	%%1 = fcmp %[1]s %[2]s %%x, %%y
+	ret i1 %%1
+}
+`, gold.pred, gold.typ)
+		checkDecompile(t, name, src, []string{gold.want})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Select on boolean parameter.
+		{
+			name: "select",
+			src: `
+define i32 @f(i1 %c, i32 %x, i32 %y) {
+	%1 = select i1 %c, i32 %x, i32 %y
+	ret i32 %1
+}
+`,
+			want: []string{
+				`if c {`,
+				`_1 = x`,
+				`} else {`,
+				`_1 = y`,
+			},
+		},
+		// Select on unordered floating-point comparison; e.g. fmax.
+		{
+			name: "fcmp",
+			src: `
+define double @f(double %x, double %y) {
+	%1 = fcmp ugt double %x, %y
+	%2 = select i1 %1, double %x, double %y
+	ret double %2
+}
+`,
+			want: []string{
+				`_1 = !(x <= y)`,
+				`if _1 {`,
+				`_2 = x`,
+				`_2 = y`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
+
+func TestGetElementPtr(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Struct field and array element.
+		{
+			name: "struct array",
+			src: `
+define i32* @f({ i32, [4 x i32] }* %p, i64 %i) {
+	%1 = getelementptr { i32, [4 x i32] }, { i32, [4 x i32] }* %p, i64 0, i32 1, i64 %i
+	ret i32* %1
+}
+`,
+			want: []string{
+				`_1 = &p.field1[i]`,
+			},
+		},
+		// Struct field of array element.
+		{
+			name: "array struct",
+			src: `
+define i32* @f([2 x { i32, i32 }]* %p) {
+	%1 = getelementptr [2 x { i32, i32 }], [2 x { i32, i32 }]* %p, i64 0, i64 1, i32 0
+	ret i32* %1
+}
+`,
+			want: []string{
+				`_1 = &p[1].field0`,
+			},
+		},
+		// Pointer arithmetic on the source address.
+		{
+			name: "pointer arithmetic",
+			src: `
+define i32* @f(i32* %p, i64 %i) {
+	%1 = getelementptr i32, i32* %p, i64 %i
+	ret i32* %1
+}
+`,
+			want: []string{
+				`_1 = (*int32)(unsafe.Pointer(uintptr(unsafe.Pointer(p)) + uintptr(i)*unsafe.Sizeof(*p)))`,
+			},
+		},
+		// Pointer arithmetic followed by array element.
+		{
+			name: "pointer arithmetic array",
+			src: `
+define i32* @f([4 x i32]* %p) {
+	%1 = getelementptr [4 x i32], [4 x i32]* %p, i64 1, i64 2
+	ret i32* %1
+}
+`,
+			want: []string{
+				`_1 = &(*[4]int32)(unsafe.Pointer(uintptr(unsafe.Pointer(p)) + uintptr(1)*unsafe.Sizeof(*p)))[2]`,
+			},
+		},
+		// Constant expression on global variable.
+		{
+			name: "constant",
+			src: `
+@g = internal global [4 x i32] zeroinitializer
+
+define i32* @f() {
+	ret i32* getelementptr ([4 x i32], [4 x i32]* @g, i64 0, i64 2)
+}
+`,
+			want: []string{
+				`return &g[2]`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
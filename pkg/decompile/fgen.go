@@ -1,6 +1,11 @@
 package decompile
 
-import "go/ast"
+import (
+	"go/ast"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/value"
+)
 
 // funcGen is a Go code generator for a given function.
 type funcGen struct {
@@ -10,13 +15,50 @@ type funcGen struct {
 	f *ast.FuncDecl
 	// Current block statement being generated.
 	cur *ast.BlockStmt
+	// multiResult specifies whether the Go function has multiple results.
+	multiResult bool
+	// results maps from call instruction to the Go names of its results, for
+	// calls to functions lifted to Go functions with multiple results.
+	results map[*ir.InstCall][]string
+	// folded records extractvalue instructions folded into the assignment of
+	// call results.
+	folded map[*ir.InstExtractValue]bool
+	// vaTyped specifies whether the variadic parameter of the Go function has a
+	// typed element type.
+	vaTyped bool
+	// vaDerived records instructions deriving pointers to variable argument
+	// lists.
+	vaDerived map[ir.Instruction]bool
+	// vaLists records the root values (e.g. alloca instructions) of pointers to
+	// variable argument lists.
+	vaLists map[value.Value]bool
 }
 
 // newFuncGen returns a new Go function generator for the given Go source file
 // generator and Go function declaration.
 func (gen *Generator) newFuncGen(f *ast.FuncDecl) *funcGen {
 	return &funcGen{
-		gen: gen,
-		f:   f,
+		gen:       gen,
+		f:         f,
+		results:   make(map[*ir.InstCall][]string),
+		folded:    make(map[*ir.InstExtractValue]bool),
+		vaDerived: make(map[ir.Instruction]bool),
+		vaLists:   make(map[value.Value]bool),
+	}
+}
+
+// addDocComment adds a line comment with the given text to the doc comment of
+// f, unless already present. As the generated Go AST carries no position
+// information, comments are only printed when attached to declarations.
+func (fgen *funcGen) addDocComment(text string) {
+	if fgen.f.Doc == nil {
+		fgen.f.Doc = &ast.CommentGroup{}
+	}
+	c := &ast.Comment{Text: "// " + text}
+	for _, prev := range fgen.f.Doc.List {
+		if prev.Text == c.Text {
+			return
+		}
 	}
+	fgen.f.Doc.List = append(fgen.f.Doc.List, c)
 }
@@ -0,0 +1,388 @@
+package decompile
+
+import (
+	"math/big"
+
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+)
+
+// foldConst evaluates the given LLVM IR constant at decompile time. The result
+// of the evaluation is returned if the value of the constant is fully known,
+// and the original constant is returned otherwise.
+func foldConst(c constant.Constant) constant.Constant {
+	switch c := c.(type) {
+	// Binary expressions
+	case *constant.ExprAdd:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			z.Add(x, y)
+			return true
+		})
+	case *constant.ExprSub:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			z.Sub(x, y)
+			return true
+		})
+	case *constant.ExprMul:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			z.Mul(x, y)
+			return true
+		})
+	case *constant.ExprUDiv:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			if y.Sign() == 0 {
+				// Division by zero is undefined behaviour.
+				return false
+			}
+			z.Quo(unsignedInt(x, bits), unsignedInt(y, bits))
+			return true
+		})
+	case *constant.ExprSDiv:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			if y.Sign() == 0 {
+				// Division by zero is undefined behaviour.
+				return false
+			}
+			z.Quo(signedInt(x, bits), signedInt(y, bits))
+			return true
+		})
+	case *constant.ExprURem:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			if y.Sign() == 0 {
+				// Division by zero is undefined behaviour.
+				return false
+			}
+			z.Rem(unsignedInt(x, bits), unsignedInt(y, bits))
+			return true
+		})
+	case *constant.ExprSRem:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			if y.Sign() == 0 {
+				// Division by zero is undefined behaviour.
+				return false
+			}
+			z.Rem(signedInt(x, bits), signedInt(y, bits))
+			return true
+		})
+	// Bitwise expressions
+	case *constant.ExprShl:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			n, ok := shiftCount(y, bits)
+			if !ok {
+				return false
+			}
+			z.Lsh(x, n)
+			return true
+		})
+	case *constant.ExprLShr:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			n, ok := shiftCount(y, bits)
+			if !ok {
+				return false
+			}
+			z.Rsh(unsignedInt(x, bits), n)
+			return true
+		})
+	case *constant.ExprAShr:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			n, ok := shiftCount(y, bits)
+			if !ok {
+				return false
+			}
+			// Note, Rsh implements arithmetic shift right.
+			z.Rsh(signedInt(x, bits), n)
+			return true
+		})
+	case *constant.ExprAnd:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			z.And(x, y)
+			return true
+		})
+	case *constant.ExprOr:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			z.Or(x, y)
+			return true
+		})
+	case *constant.ExprXor:
+		return foldIntBinOp(c, c.X, c.Y, func(z, x, y *big.Int, bits uint64) bool {
+			z.Xor(x, y)
+			return true
+		})
+	// Vector expressions
+	case *constant.ExprExtractElement:
+		x, ok := foldConst(c.X).(*constant.Vector)
+		if !ok {
+			return c
+		}
+		index, ok := foldConst(c.Index).(*constant.Int)
+		if !ok || !index.X.IsUint64() || index.X.Uint64() >= uint64(len(x.Elems)) {
+			return c
+		}
+		return foldConst(x.Elems[index.X.Uint64()])
+	case *constant.ExprInsertElement:
+		x, ok := foldConst(c.X).(*constant.Vector)
+		if !ok {
+			return c
+		}
+		index, ok := foldConst(c.Index).(*constant.Int)
+		if !ok || !index.X.IsUint64() || index.X.Uint64() >= uint64(len(x.Elems)) {
+			return c
+		}
+		elems := make([]constant.Constant, len(x.Elems))
+		copy(elems, x.Elems)
+		elems[index.X.Uint64()] = foldConst(c.Elem)
+		return &constant.Vector{Typ: x.Typ, Elems: elems}
+	case *constant.ExprShuffleVector:
+		x, ok := foldConst(c.X).(*constant.Vector)
+		if !ok {
+			return c
+		}
+		y, ok := foldConst(c.Y).(*constant.Vector)
+		if !ok {
+			return c
+		}
+		mask, ok := foldConst(c.Mask).(*constant.Vector)
+		if !ok {
+			return c
+		}
+		var elems []constant.Constant
+		n := uint64(len(x.Elems))
+		for _, m := range mask.Elems {
+			index, ok := m.(*constant.Int)
+			if !ok || !index.X.IsUint64() {
+				// Undefined mask element.
+				return c
+			}
+			i := index.X.Uint64()
+			switch {
+			case i < n:
+				elems = append(elems, x.Elems[i])
+			case i < 2*n:
+				elems = append(elems, y.Elems[i-n])
+			default:
+				return c
+			}
+		}
+		typ := types.NewVector(uint64(len(elems)), x.Typ.ElemType)
+		return &constant.Vector{Typ: typ, Elems: elems}
+	// Aggregate expressions
+	case *constant.ExprExtractValue:
+		elem, ok := extractValue(foldConst(c.X), c.Indices)
+		if !ok {
+			return c
+		}
+		return elem
+	case *constant.ExprInsertValue:
+		agg, ok := insertValue(foldConst(c.X), foldConst(c.Elem), c.Indices)
+		if !ok {
+			return c
+		}
+		return agg
+	// Conversion expressions
+	case *constant.ExprTrunc:
+		return foldIntConv(c, c.From, c.To, signedInt)
+	case *constant.ExprZExt:
+		return foldIntConv(c, c.From, c.To, unsignedInt)
+	case *constant.ExprSExt:
+		return foldIntConv(c, c.From, c.To, signedInt)
+	// Other expressions
+	case *constant.ExprICmp:
+		x, ok := foldConst(c.X).(*constant.Int)
+		if !ok {
+			return c
+		}
+		y, ok := foldConst(c.Y).(*constant.Int)
+		if !ok {
+			return c
+		}
+		bits := x.Typ.BitSize
+		var cmp int
+		switch c.Pred {
+		case enum.IPredUGE, enum.IPredUGT, enum.IPredULE, enum.IPredULT:
+			cmp = unsignedInt(x.X, bits).Cmp(unsignedInt(y.X, bits))
+		default:
+			cmp = signedInt(x.X, bits).Cmp(signedInt(y.X, bits))
+		}
+		var result bool
+		switch c.Pred {
+		case enum.IPredEQ:
+			result = cmp == 0
+		case enum.IPredNE:
+			result = cmp != 0
+		case enum.IPredSGE, enum.IPredUGE:
+			result = cmp >= 0
+		case enum.IPredSGT, enum.IPredUGT:
+			result = cmp > 0
+		case enum.IPredSLE, enum.IPredULE:
+			result = cmp <= 0
+		case enum.IPredSLT, enum.IPredULT:
+			result = cmp < 0
+		default:
+			return c
+		}
+		return newBoolConst(result)
+	case *constant.ExprSelect:
+		cond, ok := foldConst(c.Cond).(*constant.Int)
+		if !ok {
+			return c
+		}
+		if cond.X.Sign() != 0 {
+			return foldConst(c.X)
+		}
+		return foldConst(c.Y)
+	default:
+		return c
+	}
+}
+
+// foldIntBinOp evaluates the integer binary operation op on the operands x and
+// y. The result of the evaluation is returned if both operands are fully known
+// integer constants, and the original constant orig is returned otherwise.
+//
+// The result is stored in z by op, which reports whether the operation is
+// defined for the given operands.
+func foldIntBinOp(orig, x, y constant.Constant, op func(z, x, y *big.Int, bits uint64) bool) constant.Constant {
+	xi, ok := foldConst(x).(*constant.Int)
+	if !ok {
+		return orig
+	}
+	yi, ok := foldConst(y).(*constant.Int)
+	if !ok {
+		return orig
+	}
+	z := new(big.Int)
+	if !op(z, xi.X, yi.X, xi.Typ.BitSize) {
+		return orig
+	}
+	return newIntConst(xi.Typ, z)
+}
+
+// foldIntConv evaluates the integer conversion of from to the integer type to,
+// where extend is used to interpret the bits of the source operand (i.e.
+// signedInt or unsignedInt). The result of the evaluation is returned if the
+// source operand is a fully known integer constant, and the original constant
+// orig is returned otherwise.
+func foldIntConv(orig, from constant.Constant, to types.Type, extend func(x *big.Int, bits uint64) *big.Int) constant.Constant {
+	x, ok := foldConst(from).(*constant.Int)
+	if !ok {
+		return orig
+	}
+	toType, ok := to.(*types.IntType)
+	if !ok {
+		return orig
+	}
+	return newIntConst(toType, extend(x.X, x.Typ.BitSize))
+}
+
+// extractValue returns the element at the given indices of the aggregate
+// constant. The boolean return value indicates success.
+func extractValue(agg constant.Constant, indices []uint64) (constant.Constant, bool) {
+	if len(indices) == 0 {
+		return agg, true
+	}
+	index := indices[0]
+	switch agg := agg.(type) {
+	case *constant.Struct:
+		if index >= uint64(len(agg.Fields)) {
+			return nil, false
+		}
+		return extractValue(foldConst(agg.Fields[index]), indices[1:])
+	case *constant.Array:
+		if index >= uint64(len(agg.Elems)) {
+			return nil, false
+		}
+		return extractValue(foldConst(agg.Elems[index]), indices[1:])
+	default:
+		return nil, false
+	}
+}
+
+// insertValue returns a copy of the aggregate constant with the element at the
+// given indices replaced by elem. The boolean return value indicates success.
+func insertValue(agg, elem constant.Constant, indices []uint64) (constant.Constant, bool) {
+	if len(indices) == 0 {
+		return elem, true
+	}
+	index := indices[0]
+	switch agg := agg.(type) {
+	case *constant.Struct:
+		if index >= uint64(len(agg.Fields)) {
+			return nil, false
+		}
+		field, ok := insertValue(foldConst(agg.Fields[index]), elem, indices[1:])
+		if !ok {
+			return nil, false
+		}
+		fields := make([]constant.Constant, len(agg.Fields))
+		copy(fields, agg.Fields)
+		fields[index] = field
+		return &constant.Struct{Typ: agg.Typ, Fields: fields}, true
+	case *constant.Array:
+		if index >= uint64(len(agg.Elems)) {
+			return nil, false
+		}
+		e, ok := insertValue(foldConst(agg.Elems[index]), elem, indices[1:])
+		if !ok {
+			return nil, false
+		}
+		elems := make([]constant.Constant, len(agg.Elems))
+		copy(elems, agg.Elems)
+		elems[index] = e
+		return &constant.Array{Typ: agg.Typ, Elems: elems}, true
+	default:
+		return nil, false
+	}
+}
+
+// ### [ Helper functions ] ####################################################
+
+// newIntConst returns a new integer constant of the given type, truncating x to
+// the bit size of the type. Integers of bit size 1 (i.e. booleans) are
+// represented as unsigned values and all other integers as signed values, so
+// that they map directly to the corresponding Go type.
+func newIntConst(typ *types.IntType, x *big.Int) *constant.Int {
+	if typ.BitSize == 1 {
+		return &constant.Int{Typ: typ, X: unsignedInt(x, typ.BitSize)}
+	}
+	return &constant.Int{Typ: typ, X: signedInt(x, typ.BitSize)}
+}
+
+// newBoolConst returns a new boolean (i1) integer constant.
+func newBoolConst(x bool) *constant.Int {
+	if x {
+		return &constant.Int{Typ: types.I1, X: big.NewInt(1)}
+	}
+	return &constant.Int{Typ: types.I1, X: big.NewInt(0)}
+}
+
+// unsignedInt returns the unsigned interpretation of the two's complement
+// integer x of the given bit size.
+func unsignedInt(x *big.Int, bits uint64) *big.Int {
+	mask := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	mask.Sub(mask, big.NewInt(1))
+	// Note, And uses two's complement semantics for negative integers.
+	return new(big.Int).And(x, mask)
+}
+
+// signedInt returns the signed interpretation of the two's complement integer x
+// of the given bit size.
+func signedInt(x *big.Int, bits uint64) *big.Int {
+	z := unsignedInt(x, bits)
+	if bits > 0 && z.Bit(int(bits-1)) == 1 {
+		// Sign bit set; subtract 2^bits.
+		z.Sub(z, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+	}
+	return z
+}
+
+// shiftCount returns the shift count y of a shift operation on integers of the
+// given bit size. The boolean return value indicates whether the shift count
+// is valid (i.e. the result of the shift is not a poison value).
+func shiftCount(y *big.Int, bits uint64) (uint, bool) {
+	n := unsignedInt(y, bits)
+	if !n.IsUint64() || n.Uint64() >= bits {
+		return 0, false
+	}
+	return uint(n.Uint64()), true
+}
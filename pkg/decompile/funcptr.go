@@ -0,0 +1,81 @@
+package decompile
+
+import (
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// Pointers to functions are lifted to Go function values, as Go function values
+// already refer to the function. The LLVM IR function @f used as a value is
+// thus lifted to the Go identifier f, and calls through function pointers are
+// lifted to calls of function-typed values.
+//
+//    void (i32)*   ->   func(param0 int32)
+
+// isFuncPtr reports whether the given LLVM IR type is a pointer to a function.
+func isFuncPtr(t types.Type) bool {
+	if t, ok := t.(*types.PointerType); ok {
+		_, ok := t.ElemType.(*types.FuncType)
+		return ok
+	}
+	return false
+}
+
+// liftCallee lifts the LLVM IR callee of a call instruction or invoke
+// terminator to a Go expression of function type, emitting to f.
+//
+//    f(args)
+//    (*p)(args)
+func (fgen *funcGen) liftCallee(v value.Value) ast.Expr {
+	callee := fgen.liftValue(v)
+	switch callee.(type) {
+	case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr, *ast.CallExpr, *ast.ParenExpr:
+		return callee
+	default:
+		return &ast.ParenExpr{X: callee}
+	}
+}
+
+// funcPtrCastExpr returns the Go expression reinterpreting the bits of the
+// value x of the given Go type as a value of the given Go type, where either
+// type is a function type. Go function values may not be converted to
+// unsafe.Pointer, and the conversion is therefore made through the address of
+// a temporary copy of x.
+//
+//    func() T {
+//       x := x
+//       return *(*T)(unsafe.Pointer(&x))
+//    }()
+func (gen *Generator) funcPtrCastExpr(x ast.Expr, from, to gotypes.Type) ast.Expr {
+	tmp := ast.NewIdent("x")
+	defStmt := &ast.AssignStmt{
+		Lhs: []ast.Expr{tmp},
+		Tok: token.DEFINE,
+		Rhs: []ast.Expr{goConvExpr(from, x)},
+	}
+	ptr := goConvExpr(gotypes.NewPointer(to), gen.unsafePointerExpr(addrExpr(tmp)))
+	returnStmt := &ast.ReturnStmt{
+		Results: []ast.Expr{derefExpr(ptr)},
+	}
+	return funcLitCall(to, defStmt, returnStmt)
+}
+
+// funcPtrBitcastExpr returns the Go expression of the reinterpretation of the
+// pointer x from the given LLVM IR type as the given LLVM IR type, where either
+// type is a pointer to a function.
+func (gen *Generator) funcPtrBitcastExpr(x ast.Expr, from, to types.Type) (ast.Expr, error) {
+	goFrom, err := gen.goType(from)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	goTo, err := gen.goType(to)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return gen.funcPtrCastExpr(x, goFrom, goTo), nil
+}
@@ -0,0 +1,88 @@
+package decompile
+
+import "testing"
+
+func TestFuncPtr(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Indirect call through function pointer parameter.
+		{
+			name: "param",
+			src: `
+define i32 @f(i32 (i32)* %g, i32 %x) {
+	%1 = call i32 %g(i32 %x)
+	ret i32 %1
+}
+`,
+			want: []string{
+				`g func(param0 int32) int32`,
+				`_1 = g(x)`,
+			},
+		},
+		// Indirect call through function pointer stored in local variable.
+		{
+			name: "load",
+			src: `
+define internal i32 @inc(i32 %x) {
+	%1 = add i32 %x, 1
+	ret i32 %1
+}
+
+define i32 @f(i32 %x) {
+	%p = alloca i32 (i32)*
+	store i32 (i32)* @inc, i32 (i32)** %p
+	%g = load i32 (i32)*, i32 (i32)** %p
+	%1 = call i32 %g(i32 %x)
+	ret i32 %1
+}
+`,
+			want: []string{
+				`p = new(func(param0 int32) int32)`,
+				`*p = inc`,
+				`g = *p`,
+				`_1 = g(x)`,
+			},
+		},
+		// Indirect call through function pointer field of struct.
+		{
+			name: "field",
+			src: `
+define i32 @f({ i32, i32 (i32)* }* %o) {
+	%1 = getelementptr { i32, i32 (i32)* }, { i32, i32 (i32)* }* %o, i64 0, i32 1
+	%2 = load i32 (i32)*, i32 (i32)** %1
+	%3 = getelementptr { i32, i32 (i32)* }, { i32, i32 (i32)* }* %o, i64 0, i32 0
+	%4 = load i32, i32* %3
+	%5 = call i32 %2(i32 %4)
+	ret i32 %5
+}
+`,
+			want: []string{
+				`_1 = &o.field1`,
+				`_2 = *_1`,
+				`_5 = _2(_4)`,
+			},
+		},
+		// Indirect call through function pointer of different type.
+		{
+			name: "bitcast",
+			src: `
+define void @f(i32 (i32)* %g) {
+	%h = bitcast i32 (i32)* %g to void ()*
+	call void %h()
+	ret void
+}
+`,
+			want: []string{
+				`x := (func(param0 int32) int32)(g)`,
+				`return *(*func())(unsafe.Pointer(&x))`,
+				`h()`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
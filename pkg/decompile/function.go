@@ -5,12 +5,13 @@ import (
 	"go/ast"
 	"go/token"
 	gotypes "go/types"
-	"strings"
 
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
 )
 
 // decompileFuncDef decompiles the LLVM IR function definition to Go source
@@ -19,6 +20,9 @@ func (fgen *funcGen) decompileFuncDef(irFunc *ir.Func) {
 	blockStmt := &ast.BlockStmt{}
 	fgen.f.Body = blockStmt
 	fgen.cur = blockStmt
+	fgen.multiResult = fgen.gen.multiResults[irFunc.Name()]
+	fgen.indexResults(irFunc)
+	fgen.indexVALists(irFunc)
 	blocks := fgen.primBlocks(irFunc)
 	for _, block := range blocks {
 		fgen.liftBlock(block)
@@ -26,7 +30,7 @@ func (fgen *funcGen) decompileFuncDef(irFunc *ir.Func) {
 	// Lift last terminator if not already lifted.
 	if len(blocks) > 0 {
 		if term, ok := blocks[len(blocks)-1].GetTerm(); ok {
-			fgen.liftTerm(term)
+			fgen.liftStmt(term, func() { fgen.liftTerm(term) })
 		}
 	}
 }
@@ -46,6 +50,10 @@ func (fgen *funcGen) liftBlock(block Block) {
 		fgen.liftPreLoop(block)
 	case *PostLoop:
 		fgen.liftPostLoop(block)
+	case *Loop:
+		fgen.liftLoop(block)
+	case *CompCond:
+		fgen.liftCompCond(block)
 	default:
 		panic(fmt.Errorf("support for pseudo basic block type %T not yet implemented", block))
 	}
@@ -55,11 +63,16 @@ func (fgen *funcGen) liftBlock(block Block) {
 // f.
 func (fgen *funcGen) liftBasicBlock(block *IRBlock) {
 	for _, inst := range block.Insts {
-		fgen.liftInst(inst)
+		fgen.liftStmt(inst, func() { fgen.liftInst(inst) })
 	}
 	if block.HasTerm {
-		fgen.liftTerm(block.Term)
+		fgen.liftStmt(block.Term, func() { fgen.liftTerm(block.Term) })
 		block.SetHasTerm(false)
+	} else if term, ok := block.Term.(*ir.TermInvoke); ok {
+		// The call of an invoke terminator is lifted even if its normal
+		// successor is structured, as unwind edges are excluded from control
+		// flow recovery.
+		fgen.liftStmt(term, func() { fgen.liftInvokeUnwind(term) })
 	}
 }
 
@@ -80,9 +93,8 @@ func (fgen *funcGen) liftIf(block *If) {
 	fgen.liftBlock(block.Cond)
 	// Get if-else statement.
 	body := &ast.BlockStmt{}
-	condTerm, _ := block.Cond.GetTerm()
 	ifStmt := &ast.IfStmt{
-		Cond: fgen.getCond(condTerm),
+		Cond: fgen.blockCond(block.Cond),
 		Body: body,
 	}
 	fgen.cur.List = append(fgen.cur.List, ifStmt)
@@ -105,9 +117,8 @@ func (fgen *funcGen) liftIfElse(block *IfElse) {
 	// Generate if-else statement.
 	bodyTrue := &ast.BlockStmt{}
 	bodyFalse := &ast.BlockStmt{}
-	condTerm, _ := block.Cond.GetTerm()
 	ifStmt := &ast.IfStmt{
-		Cond: fgen.getCond(condTerm),
+		Cond: fgen.blockCond(block.Cond),
 		Body: bodyTrue,
 		Else: bodyFalse,
 	}
@@ -134,9 +145,8 @@ func (fgen *funcGen) liftPreLoop(block *PreLoop) {
 	fgen.liftBlock(block.Cond)
 	// Generate for-loop statement.
 	body := &ast.BlockStmt{}
-	condTerm, _ := block.Cond.GetTerm()
 	forStmt := &ast.ForStmt{
-		Cond: fgen.getCond(condTerm),
+		Cond: fgen.blockCond(block.Cond),
 		Body: body,
 	}
 	fgen.cur.List = append(fgen.cur.List, forStmt)
@@ -160,9 +170,8 @@ func (fgen *funcGen) liftPostLoop(block *PostLoop) {
 	fgen.cur = body
 	block.Cond.SetHasTerm(false)
 	fgen.liftBlock(block.Cond)
-	condTerm, _ := block.Cond.GetTerm()
 	ifStmt := &ast.IfStmt{
-		Cond: fgen.getCond(condTerm),
+		Cond: fgen.blockCond(block.Cond),
 		Body: &ast.BlockStmt{
 			List: []ast.Stmt{&ast.BranchStmt{Tok: token.BREAK}},
 		},
@@ -178,13 +187,263 @@ func (fgen *funcGen) liftPostLoop(block *PostLoop) {
 	fgen.liftBlock(block.Exit)
 }
 
+// liftLoop lifts the pseudo endless loop or loop with several exits to Go
+// source code, emitting to f.
+func (fgen *funcGen) liftLoop(block *Loop) {
+	cur := fgen.cur
+	body := &ast.BlockStmt{}
+	fgen.cur = body
+	nodes := append([]Block{block.Head}, block.Body...)
+	for i, node := range nodes {
+		node.SetHasTerm(false)
+		fgen.liftBlock(node)
+		// The successor of the last loop node is the loop header.
+		next := block.Head.Name()
+		if i+1 < len(nodes) {
+			next = nodes[i+1].Name()
+		}
+		term, ok := node.GetTerm()
+		if !ok {
+			fgen.gen.Errorf("unable to locate terminator of loop node %q in loop %q", node.Name(), block.Name())
+			continue
+		}
+		fgen.liftLoopBranch(node, term, block, next)
+	}
+	// Generate for-loop statement.
+	forStmt := &ast.ForStmt{
+		Body: body,
+	}
+	fgen.cur = cur
+	fgen.cur.List = append(fgen.cur.List, forStmt)
+	// Lift exit block.
+	if block.Exit != nil {
+		fgen.liftBlock(block.Exit)
+	}
+}
+
+// liftLoopBranch lifts the terminator of a loop node to a conditional break or
+// continue statement, emitting to f. The branch to next (i.e. the next loop
+// node in the chain) requires no statement.
+func (fgen *funcGen) liftLoopBranch(node Block, term ir.Terminator, block *Loop, next string) {
+	if term, ok := term.(*ir.TermSwitch); ok {
+		fgen.liftLoopSwitch(term, block, next)
+		return
+	}
+	succs := term.Succs()
+	switch len(succs) {
+	case 0, 1:
+		// Unconditional branch to next.
+		return
+	case 2:
+		// Conditional branch.
+	default:
+		fgen.gen.Errorf("support for %d-way terminator %T of loop node %q in loop %q not yet implemented", len(succs), term, node.Name(), block.Name())
+		return
+	}
+	// Locate the target of the conditional branch (i.e. not next); the first
+	// successor is taken if the condition holds.
+	target, cond := succs[0], fgen.blockCond(node)
+	if target.Name() == next {
+		target, cond = succs[1], notExpr(cond)
+	}
+	fgen.liftLoopBranchTo(target.Name(), cond, block)
+}
+
+// liftLoopSwitch lifts the switch terminator of a loop node to conditional
+// break or continue statements, emitting to f. The cases branching to next
+// (i.e. the next loop node in the chain) require no statement.
+func (fgen *funcGen) liftLoopSwitch(term *ir.TermSwitch, block *Loop, next string) {
+	x := fgen.liftValue(term.X)
+	// Conditions of the cases branching to each target, in order of
+	// occurrence; and the condition of any case.
+	var targets []string
+	conds := make(map[string]ast.Expr)
+	var anyCase ast.Expr
+	for _, c := range term.Cases {
+		cond := &ast.BinaryExpr{
+			X:  x,
+			Op: token.EQL,
+			Y:  fgen.liftValue(c.X),
+		}
+		anyCase = orExpr(anyCase, cond)
+		target := c.Target.Name()
+		if target == next {
+			continue
+		}
+		if _, ok := conds[target]; !ok {
+			targets = append(targets, target)
+		}
+		conds[target] = orExpr(conds[target], cond)
+	}
+	// The default target is taken if no case holds.
+	if target := term.TargetDefault.Name(); target != next {
+		if _, ok := conds[target]; !ok {
+			targets = append(targets, target)
+		}
+		var cond ast.Expr
+		if anyCase != nil {
+			cond = notExpr(anyCase)
+		}
+		conds[target] = orExpr(conds[target], cond)
+	}
+	// The conditions of the targets are mutually exclusive; thus each target is
+	// lifted to a separate if-statement.
+	for _, target := range targets {
+		fgen.liftLoopBranchTo(target, conds[target], block)
+	}
+}
+
+// liftLoopBranchTo lifts the branch to the given target of a loop node to a
+// break or continue statement, emitting to f. The branch statement is guarded
+// by an if-statement if cond is non-nil.
+func (fgen *funcGen) liftLoopBranchTo(target string, cond ast.Expr, block *Loop) {
+	var tok token.Token
+	switch {
+	case target == block.Head.Name():
+		tok = token.CONTINUE
+	case block.Exit != nil && target == block.Exit.Name():
+		tok = token.BREAK
+	default:
+		fgen.gen.Errorf("invalid branch target %q of loop %q; expected loop header or follow block", target, block.Name())
+		return
+	}
+	branchStmt := &ast.BranchStmt{Tok: tok}
+	if cond == nil {
+		fgen.cur.List = append(fgen.cur.List, branchStmt)
+		return
+	}
+	ifStmt := &ast.IfStmt{
+		Cond: cond,
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{branchStmt},
+		},
+	}
+	fgen.cur.List = append(fgen.cur.List, ifStmt)
+}
+
+// liftCompCond lifts the pseudo compound condition block to Go source code,
+// emitting to f. Only the instructions of the condition blocks are lifted, as
+// the compound condition is lifted as part of the enclosing primitive (see
+// blockCond).
+func (fgen *funcGen) liftCompCond(block *CompCond) {
+	block.A.SetHasTerm(false)
+	fgen.liftBlock(block.A)
+	fgen.liftBlock(block.B)
+}
+
+// blockCond returns the Go expression of the condition under which the first
+// successor of the terminator of the given block is taken.
+func (fgen *funcGen) blockCond(block Block) ast.Expr {
+	cond := fgen.leafCond(block)
+	if !firstSuccIsTrue(block) {
+		return notExpr(cond)
+	}
+	return cond
+}
+
+// leafCond returns the Go expression of the condition under which the true
+// branch of the given block is taken; i.e. the branch condition of a leaf of a
+// compound condition.
+func (fgen *funcGen) leafCond(block Block) ast.Expr {
+	if block, ok := block.(*CompCond); ok {
+		return fgen.compCondExpr(block, block.Cond)
+	}
+	term, _ := block.GetTerm()
+	return fgen.getCond(term)
+}
+
+// compCondExpr returns the Go expression of the given compound condition of the
+// pseudo compound condition block.
+func (fgen *funcGen) compCondExpr(block *CompCond, cond *primitive.Cond) ast.Expr {
+	switch cond.Op {
+	case primitive.CondAnd, primitive.CondOr:
+		op := token.LAND
+		if cond.Op == primitive.CondOr {
+			op = token.LOR
+		}
+		x := fgen.compCondExpr(block, cond.Args[0])
+		y := fgen.compCondExpr(block, cond.Args[1])
+		return &ast.BinaryExpr{
+			X:  parenOperand(x, op),
+			Op: op,
+			Y:  parenOperand(y, op),
+		}
+	case primitive.CondNot:
+		return notExpr(fgen.compCondExpr(block, cond.Args[0]))
+	case primitive.CondLeaf:
+		switch cond.Node {
+		case block.A.Name():
+			return fgen.leafCond(block.A)
+		case block.B.Name():
+			return fgen.leafCond(block.B)
+		default:
+			fgen.gen.Errorf("unable to locate leaf block %q of compound condition %q", cond.Node, block.Name())
+			return &ast.BadExpr{}
+		}
+	default:
+		panic(fmt.Errorf("support for compound condition operator %q not yet implemented", cond.Op))
+	}
+}
+
+// orExpr returns the logical OR of the Go expressions x and y; or y if x is nil.
+func orExpr(x, y ast.Expr) ast.Expr {
+	if x == nil {
+		return y
+	}
+	return &ast.BinaryExpr{
+		X:  parenOperand(x, token.LOR),
+		Op: token.LOR,
+		Y:  parenOperand(y, token.LOR),
+	}
+}
+
+// parenOperand returns the Go expression x as an operand of the binary operator
+// op, enclosed in parentheses if x has lower precedence than op.
+func parenOperand(x ast.Expr, op token.Token) ast.Expr {
+	if x, ok := x.(*ast.BinaryExpr); ok && x.Op.Precedence() < op.Precedence() {
+		return &ast.ParenExpr{X: x}
+	}
+	return x
+}
+
+// firstSuccIsTrue reports whether the first successor of the terminator of the
+// given block is the target of the true branch of the block.
+func firstSuccIsTrue(block Block) bool {
+	c, ok := block.(*CompCond)
+	if !ok {
+		return true
+	}
+	// B is evaluated last, thus the successors of B are the targets of the
+	// compound condition; with branches swapped if the condition of B is
+	// negated.
+	y := c.Cond.Args[len(c.Cond.Args)-1]
+	return firstSuccIsTrue(c.B) != (y.Op == primitive.CondNot)
+}
+
 // primBlocks returns the list of pseudo basic blocks corresponding to the
 // recovered high-level primitives of the given function.
 func (fgen *funcGen) primBlocks(irFunc *ir.Func) []Block {
 	prims, err := fgen.gen.Prims(irFunc)
 	if err != nil {
+		// Continue with recovery, even on error; the control flow of blocks not
+		// part of any primitive is lifted to goto statements, and the function
+		// is thus not replaced by a stub.
+		funcErr := fgen.gen.funcErr
 		fgen.gen.eh(err)
-		// Continue with recovery, even on error.
+		fgen.gen.funcErr = funcErr
+	}
+	// Reject primitives not supported by the decompiler (e.g. primitives located
+	// by user-defined templates) up front, as the primitives recovered after
+	// them may refer to their merged nodes. The control flow of the function is
+	// then lifted to goto statements.
+	for _, prim := range prims {
+		if !isSupportedPrim(prim.Prim) {
+			funcErr := fgen.gen.funcErr
+			fgen.gen.Errorf("support for primitive %q of function %q not yet implemented", prim.Prim, irFunc.Name())
+			fgen.gen.funcErr = funcErr
+			prims = nil
+			break
+		}
 	}
 	blocks := make(map[string]Block)
 	for _, block := range irFunc.Blocks {
@@ -330,6 +589,78 @@ func (fgen *funcGen) primBlocks(irFunc *ir.Func) []Block {
 			delete(blocks, condName)
 			delete(blocks, exitName)
 			blocks[block.Name()] = block
+		case "comp_cond_a_AND_b", "comp_cond_a_AND_NOT_b", "comp_cond_a_OR_b", "comp_cond_a_OR_NOT_b":
+			aName := prim.Nodes["a"]
+			a, ok := blocks[aName]
+			if !ok {
+				fgen.gen.Errorf("unable to locate a block %q of primitive %q in function %q", aName, prim.Prim, irFunc.Name())
+				continue
+			}
+			bName := prim.Nodes["b"]
+			b, ok := blocks[bName]
+			if !ok {
+				fgen.gen.Errorf("unable to locate b block %q of primitive %q in function %q", bName, prim.Prim, irFunc.Name())
+				continue
+			}
+			if prim.Cond == nil {
+				fgen.gen.Errorf("unable to locate compound condition of primitive %q in function %q", prim.Prim, irFunc.Name())
+				continue
+			}
+			if op := prim.Cond.Op; (op != primitive.CondAnd && op != primitive.CondOr) || len(prim.Cond.Args) != 2 {
+				fgen.gen.Errorf("invalid compound condition %v of primitive %q in function %q; expected AND or OR of two operands", prim.Cond, prim.Prim, irFunc.Name())
+				continue
+			}
+			block := &CompCond{
+				BlockName: prim.Entry,
+				A:         a,
+				B:         b,
+				Cond:      prim.Cond,
+			}
+			delete(blocks, aName)
+			delete(blocks, bName)
+			blocks[block.Name()] = block
+		case "inf_loop", "multi_exit_loop":
+			headName := prim.Nodes["head"]
+			head, ok := blocks[headName]
+			if !ok {
+				fgen.gen.Errorf("unable to locate head block %q of primitive %q in function %q", headName, prim.Prim, irFunc.Name())
+				continue
+			}
+			block := &Loop{
+				BlockName: prim.Entry,
+				Head:      head,
+			}
+			names := []string{headName}
+			for i := 1; ; i++ {
+				bodyName, ok := prim.Nodes[fmt.Sprintf("body_%d", i)]
+				if !ok {
+					break
+				}
+				body, ok := blocks[bodyName]
+				if !ok {
+					fgen.gen.Errorf("unable to locate body block %q of primitive %q in function %q", bodyName, prim.Prim, irFunc.Name())
+					block = nil
+					break
+				}
+				block.Body = append(block.Body, body)
+				names = append(names, bodyName)
+			}
+			if block == nil {
+				continue
+			}
+			if exitName, ok := prim.Nodes["exit"]; ok {
+				exit, ok := blocks[exitName]
+				if !ok {
+					fgen.gen.Errorf("unable to locate exit block %q of primitive %q in function %q", exitName, prim.Prim, irFunc.Name())
+					continue
+				}
+				block.Exit = exit
+				names = append(names, exitName)
+			}
+			for _, name := range names {
+				delete(blocks, name)
+			}
+			blocks[block.Name()] = block
 		default:
 			panic(fmt.Errorf("support for primitive %q not yet implemented", prim.Prim))
 		}
@@ -347,6 +678,20 @@ func (fgen *funcGen) primBlocks(irFunc *ir.Func) []Block {
 	return bbs
 }
 
+// isSupportedPrim reports whether the given high-level control flow primitive
+// is supported by primBlocks.
+func isSupportedPrim(name string) bool {
+	switch name {
+	case "seq", "if", "if_else", "pre_loop", "post_loop":
+		return true
+	case "comp_cond_a_AND_b", "comp_cond_a_AND_NOT_b", "comp_cond_a_OR_b", "comp_cond_a_OR_NOT_b":
+		return true
+	case "inf_loop", "multi_exit_loop":
+		return true
+	}
+	return false
+}
+
 type Block interface {
 	Name() string
 	GetTerm() (ir.Terminator, bool)
@@ -403,6 +748,31 @@ func (block *PostLoop) SetHasTerm(hasTerm bool) {
 	block.Exit.SetHasTerm(hasTerm)
 }
 
+type Loop struct {
+	BlockName string
+	Head      Block
+	Body      []Block
+	Exit      Block
+}
+
+func (block *Loop) Name() string {
+	return block.BlockName
+}
+
+func (block *Loop) GetTerm() (ir.Terminator, bool) {
+	if block.Exit == nil {
+		// Endless loops have no terminator.
+		return nil, false
+	}
+	return block.Exit.GetTerm()
+}
+
+func (block *Loop) SetHasTerm(hasTerm bool) {
+	if block.Exit != nil {
+		block.Exit.SetHasTerm(hasTerm)
+	}
+}
+
 type Seq struct {
 	BlockName string
 	Entry     Block
@@ -421,6 +791,27 @@ func (block *Seq) SetHasTerm(hasTerm bool) {
 	block.Exit.SetHasTerm(hasTerm)
 }
 
+type CompCond struct {
+	BlockName string
+	A         Block
+	B         Block
+	Cond      *primitive.Cond
+}
+
+func (block *CompCond) Name() string {
+	return block.BlockName
+}
+
+func (block *CompCond) GetTerm() (ir.Terminator, bool) {
+	// B is evaluated last, thus the terminator of B branches to the targets of
+	// the compound condition.
+	return block.B.GetTerm()
+}
+
+func (block *CompCond) SetHasTerm(hasTerm bool) {
+	block.B.SetHasTerm(hasTerm)
+}
+
 type If struct {
 	BlockName string
 	Cond      Block
@@ -462,46 +853,67 @@ func (block *IfElse) SetHasTerm(hasTerm bool) {
 
 // liftInst lifts the LLVM IR instruction to Go source code, emitting to f.
 func (fgen *funcGen) liftInst(inst ir.Instruction) {
+	if v, ok := inst.(value.Value); ok {
+		if _, ok := fgen.gen.strConst(v); ok {
+			// Pointers to C string global variables are lifted at each use.
+			return
+		}
+	}
+	if fgen.vaDerived[inst] {
+		// Pointers to variable argument lists are lifted to nothing.
+		return
+	}
 	switch inst := inst.(type) {
 	// Binary instructions
 	case *ir.InstAdd:
-		// Variable name.
-		name := newIdent(inst)
-		// X and Y operands.
-		x := fgen.liftValue(inst.X)
-		y := fgen.liftValue(inst.Y)
-		fgen.emitAssignBinOp(name, x, y, token.ADD)
-	//case *ir.InstFAdd:
-	//case *ir.InstSub:
-	//case *ir.InstFSub:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.ADD, false))
+	case *ir.InstFAdd:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.ADD, false))
+	case *ir.InstSub:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.SUB, false))
+	case *ir.InstFSub:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.SUB, false))
 	case *ir.InstMul:
-		// Variable name.
-		name := newIdent(inst)
-		// X and Y operands.
-		x := fgen.liftValue(inst.X)
-		y := fgen.liftValue(inst.Y)
-		fgen.emitAssignBinOp(name, x, y, token.MUL)
-	//case *ir.InstFMul:
-	//case *ir.InstUDiv:
-	//case *ir.InstSDiv:
-	//case *ir.InstFDiv:
-	//case *ir.InstURem:
-	//case *ir.InstSRem:
-	//case *ir.InstFRem:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.MUL, false))
+	case *ir.InstFMul:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.MUL, false))
+	case *ir.InstUDiv:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.QUO, true))
+	case *ir.InstSDiv:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.QUO, false))
+	case *ir.InstFDiv:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.QUO, false))
+	case *ir.InstURem:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.REM, true))
+	case *ir.InstSRem:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.REM, false))
+	case *ir.InstFRem:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.fremExpr)
 	// Bitwise instructions
-	//case *ir.InstShl:
-	//case *ir.InstLShr:
-	//case *ir.InstAShr:
-	//case *ir.InstAnd:
-	//case *ir.InstOr:
-	//case *ir.InstXor:
+	case *ir.InstShl:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.SHL, false))
+	case *ir.InstLShr:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.SHR, true))
+	case *ir.InstAShr:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.SHR, false))
+	case *ir.InstAnd:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.AND, false))
+	case *ir.InstOr:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.OR, false))
+	case *ir.InstXor:
+		fgen.liftBinOp(inst, inst.X, inst.Y, fgen.gen.binOp(token.XOR, false))
 	// Vector instructions
-	//case *ir.InstExtractElement:
-	//case *ir.InstInsertElement:
-	//case *ir.InstShuffleVector:
+	case *ir.InstExtractElement:
+		fgen.liftInstExtractElement(inst)
+	case *ir.InstInsertElement:
+		fgen.liftInstInsertElement(inst)
+	case *ir.InstShuffleVector:
+		fgen.liftInstShuffleVector(inst)
 	// Aggregate instructions
-	//case *ir.InstExtractValue:
-	//case *ir.InstInsertValue:
+	case *ir.InstExtractValue:
+		fgen.liftInstExtractValue(inst)
+	case *ir.InstInsertValue:
+		fgen.liftInstInsertValue(inst)
 	// Memory instructions
 	case *ir.InstAlloca:
 		fgen.liftInstAlloca(inst)
@@ -509,55 +921,96 @@ func (fgen *funcGen) liftInst(inst ir.Instruction) {
 		fgen.liftInstLoad(inst)
 	case *ir.InstStore:
 		fgen.liftInstStore(inst)
-	//case *ir.InstFence:
-	//case *ir.InstCmpXchg:
-	//case *ir.InstAtomicRMW:
-	//case *ir.InstGetElementPtr:
+	case *ir.InstFence:
+		fgen.liftInstFence(inst)
+	case *ir.InstCmpXchg:
+		fgen.liftInstCmpXchg(inst)
+	case *ir.InstAtomicRMW:
+		fgen.liftInstAtomicRMW(inst)
+	case *ir.InstGetElementPtr:
+		fgen.liftInstGetElementPtr(inst)
 	// Conversion instructions
-	//case *ir.InstTrunc:
-	//case *ir.InstZExt:
-	//case *ir.InstSExt:
-	//case *ir.InstFPTrunc:
-	//case *ir.InstFPExt:
-	//case *ir.InstFPToUI:
-	//case *ir.InstFPToSI:
-	//case *ir.InstUIToFP:
-	//case *ir.InstSIToFP:
-	//case *ir.InstPtrToInt:
-	//case *ir.InstIntToPtr:
-	//case *ir.InstBitCast:
-	//case *ir.InstAddrSpaceCast:
+	case *ir.InstTrunc:
+		fgen.liftInstConv(inst, inst.From, inst.To, fgen.gen.intConvExpr)
+	case *ir.InstZExt:
+		fgen.liftInstConv(inst, inst.From, inst.To, fgen.gen.zextExpr)
+	case *ir.InstSExt:
+		fgen.liftInstConv(inst, inst.From, inst.To, fgen.gen.intConvExpr)
+	case *ir.InstFPTrunc:
+		fgen.liftInstConv(inst, inst.From, inst.To, fgen.gen.convExpr)
+	case *ir.InstFPExt:
+		fgen.liftInstConv(inst, inst.From, inst.To, fgen.gen.convExpr)
+	case *ir.InstFPToUI:
+		fgen.liftInstConv(inst, inst.From, inst.To, fgen.gen.fptouiExpr)
+	case *ir.InstFPToSI:
+		fgen.liftInstConv(inst, inst.From, inst.To, fgen.gen.convExpr)
+	case *ir.InstUIToFP:
+		fgen.liftInstConv(inst, inst.From, inst.To, fgen.gen.uitofpExpr)
+	case *ir.InstSIToFP:
+		fgen.liftInstConv(inst, inst.From, inst.To, fgen.gen.convExpr)
+	case *ir.InstPtrToInt:
+		fgen.liftInstConv(inst, inst.From, inst.To, fgen.gen.ptrtointExpr)
+	case *ir.InstIntToPtr:
+		fgen.liftInstIntToPtr(inst)
+	case *ir.InstBitCast:
+		fgen.liftInstBitCast(inst)
+	case *ir.InstAddrSpaceCast:
+		fgen.liftInstConv(inst, inst.From, inst.To, fgen.gen.bitcastExpr)
 	// Other instructions
 	case *ir.InstICmp:
 		fgen.liftInstICmp(inst)
-	//case *ir.InstFCmp:
+	case *ir.InstFCmp:
+		fgen.liftInstFCmp(inst)
 	//case *ir.InstPhi:
-	//case *ir.InstSelect:
+	case *ir.InstSelect:
+		fgen.liftInstSelect(inst)
 	case *ir.InstCall:
+		if callee, ok := inst.Callee.(*ir.Func); ok && isVAIntrinsic(callee.Name()) {
+			fgen.liftVAIntrinsic(inst, callee.Name())
+			break
+		}
 		// Variable name.
 		name := newIdent(inst)
 		// Callee.
-		callee := fgen.liftValue(inst.Callee)
+		callee := fgen.liftCallee(inst.Callee)
 		var args []ast.Expr
-		for _, irArg := range inst.Args {
-			arg := fgen.liftValue(irArg)
+		for i, irArg := range inst.Args {
+			arg := fgen.liftCallArg(inst.Callee, i, irArg)
 			args = append(args, arg)
 		}
 		callExpr := &ast.CallExpr{
 			Fun:  callee,
 			Args: args,
 		}
+		if _, ok := vaListParam(inst.Callee); ok {
+			// Spread variable argument list.
+			callExpr.Ellipsis = 1
+		}
+		if fgen.gen.isMultiResultCall(inst) {
+			fgen.liftMultiResultCall(inst, callExpr)
+			break
+		}
+		// Convert result to inferred type.
+		result, err := fgen.gen.bitcastExpr(callExpr, inst.Type(), fgen.gen.valueType(inst))
+		if err != nil {
+			fgen.gen.eh(err)
+			break
+		}
 		// Append assignment statement.
 		assignStmt := &ast.AssignStmt{
 			Lhs: []ast.Expr{name},
 			Tok: token.ASSIGN,
-			Rhs: []ast.Expr{callExpr},
+			Rhs: []ast.Expr{result},
 		}
 		fgen.cur.List = append(fgen.cur.List, assignStmt)
-	//case *ir.InstVAArg:
-	//case *ir.InstLandingPad:
-	//case *ir.InstCatchPad:
-	//case *ir.InstCleanupPad:
+	case *ir.InstVAArg:
+		fgen.liftInstVAArg(inst)
+	case *ir.InstLandingPad:
+		fgen.liftInstLandingPad(inst)
+	case *ir.InstCatchPad:
+		fgen.liftInstCatchPad(inst)
+	case *ir.InstCleanupPad:
+		fgen.liftInstCleanupPad(inst)
 	default:
 		panic(fmt.Errorf("support for instruction type %T not yet implemented", inst))
 	}
@@ -569,7 +1022,7 @@ func (fgen *funcGen) liftInstAlloca(inst *ir.InstAlloca) {
 	// Variable name.
 	name := newIdent(inst)
 	// Element type.
-	elemType, err := fgen.gen.goType(inst.ElemType)
+	elemType, err := fgen.gen.goType(fgen.gen.contentType(inst))
 	if err != nil {
 		fgen.gen.eh(err)
 		return
@@ -611,10 +1064,14 @@ func (fgen *funcGen) liftInstStore(inst *ir.InstStore) {
 	// Destination.
 	dst := fgen.liftValue(inst.Dst)
 	// Source.
-	src := fgen.liftValue(inst.Src)
+	src, err := fgen.gen.convValueExpr(fgen.liftValue(inst.Src), inst.Src, fgen.gen.contentType(inst.Dst))
+	if err != nil {
+		fgen.gen.eh(err)
+		return
+	}
 	// Append assignment statement.
 	assignStmt := &ast.AssignStmt{
-		Lhs: []ast.Expr{&ast.StarExpr{X: dst}},
+		Lhs: []ast.Expr{derefExpr(dst)},
 		Tok: token.ASSIGN,
 		Rhs: []ast.Expr{src},
 	}
@@ -628,55 +1085,143 @@ func (fgen *funcGen) liftInstLoad(inst *ir.InstLoad) {
 	name := newIdent(inst)
 	// Source.
 	src := fgen.liftValue(inst.Src)
+	// Convert loaded value to inferred type.
+	x, err := fgen.gen.bitcastExpr(derefExpr(src), fgen.gen.contentType(inst.Src), fgen.gen.valueType(inst))
+	if err != nil {
+		fgen.gen.eh(err)
+		return
+	}
 	// Append assignment statement.
 	assignStmt := &ast.AssignStmt{
 		Lhs: []ast.Expr{name},
 		Tok: token.ASSIGN,
-		Rhs: []ast.Expr{&ast.StarExpr{X: src}},
+		Rhs: []ast.Expr{x},
 	}
 	fgen.cur.List = append(fgen.cur.List, assignStmt)
 }
 
-// liftInstICmp lifts the LLVM IR icmp instruction to Go source code, emitting
-// to f.
-func (fgen *funcGen) liftInstICmp(inst *ir.InstICmp) {
+// liftInstGetElementPtr lifts the LLVM IR getelementptr instruction to Go
+// source code, emitting to f.
+//
+//    name = &src.field1[i]
+func (fgen *funcGen) liftInstGetElementPtr(inst *ir.InstGetElementPtr) {
 	// Variable name.
 	name := newIdent(inst)
-	// Predicate.
-	op := ipred(inst.Pred)
-	// X and Y operands.
-	x := fgen.liftValue(inst.X)
-	y := fgen.liftValue(inst.Y)
-	binExpr := &ast.BinaryExpr{
-		X:  x,
-		Op: op,
-		Y:  y,
+	if ref, ok := fgen.gen.fieldRefs[inst]; ok {
+		// Field of recovered struct type.
+		fgen.cur.List = append(fgen.cur.List, assignStmt(name, fgen.fieldRefExpr(ref)))
+		return
+	}
+	// Source address and indices.
+	src := fgen.liftValue(inst.Src)
+	liftIndex := func(v value.Value) (ast.Expr, error) {
+		return fgen.liftValue(v), nil
+	}
+	addr, err := fgen.gen.gepExpr(inst.ElemType, src, inst.Indices, liftIndex)
+	if err != nil {
+		fgen.gen.eh(err)
+		return
 	}
 	// Append assignment statement.
-	assignStmt := &ast.AssignStmt{
-		Lhs: []ast.Expr{name},
-		Tok: token.ASSIGN,
-		Rhs: []ast.Expr{binExpr},
+	fgen.cur.List = append(fgen.cur.List, assignStmt(name, addr))
+}
+
+// liftInstBitCast lifts the LLVM IR bitcast instruction to Go source code,
+// emitting to f.
+//
+//    name = (*T)(unsafe.Pointer(from))
+func (fgen *funcGen) liftInstBitCast(inst *ir.InstBitCast) {
+	// Variable name.
+	name := newIdent(inst)
+	if ref, ok := fgen.gen.fieldRefs[inst]; ok {
+		// Field of recovered struct type.
+		fgen.cur.List = append(fgen.cur.List, assignStmt(name, fgen.fieldRefExpr(ref)))
+		return
 	}
-	fgen.cur.List = append(fgen.cur.List, assignStmt)
+	// Source value.
+	from := fgen.liftValue(inst.From)
+	to, err := fgen.gen.convValueExpr(from, inst.From, fgen.gen.valueType(inst))
+	if err != nil {
+		fgen.gen.eh(err)
+		return
+	}
+	// Append assignment statement.
+	fgen.cur.List = append(fgen.cur.List, assignStmt(name, to))
 }
 
-// emitAssignBinOp emits an assignment statement based on the given name,
-// operands and binary operation, emitting to f.
-func (fgen *funcGen) emitAssignBinOp(name *ast.Ident, x, y ast.Expr, op token.Token) {
-	// Binary expression.
-	binExpr := &ast.BinaryExpr{
-		X:  x,
-		Op: op,
-		Y:  y,
+// liftInstConv lifts the LLVM IR conversion instruction to Go source code,
+// emitting to f. Conversions of vectors are lifted element-wise.
+//
+//    name = T(from)
+func (fgen *funcGen) liftInstConv(inst namedValue, from value.Value, to types.Type, conv func(x ast.Expr, from, to types.Type) (ast.Expr, error)) {
+	if _, ok := to.(*types.VectorType); ok {
+		fgen.liftVectorConv(inst, from, to, conv)
+		return
+	}
+	// Variable name.
+	name := newIdent(inst)
+	// Source value.
+	x, err := conv(fgen.liftValue(from), fgen.gen.valueType(from), to)
+	if err != nil {
+		fgen.gen.eh(err)
+		return
 	}
 	// Append assignment statement.
-	assignStmt := &ast.AssignStmt{
-		Lhs: []ast.Expr{name},
-		Tok: token.ASSIGN,
-		Rhs: []ast.Expr{binExpr},
+	fgen.cur.List = append(fgen.cur.List, assignStmt(name, x))
+}
+
+// liftInstICmp lifts the LLVM IR icmp instruction to Go source code, emitting
+// to f.
+//
+//    name = uint32(x) < uint32(y) // ult
+func (fgen *funcGen) liftInstICmp(inst *ir.InstICmp) {
+	icmp := func(x, y ast.Expr, t types.Type) (ast.Expr, error) {
+		return fgen.gen.icmpExpr(inst.Pred, x, y, t), nil
 	}
-	fgen.cur.List = append(fgen.cur.List, assignStmt)
+	fgen.liftBinOp(inst, inst.X, inst.Y, icmp)
+}
+
+// liftInstFCmp lifts the LLVM IR fcmp instruction to Go source code, emitting
+// to f.
+//
+//    name = !(x >= y) // ult
+func (fgen *funcGen) liftInstFCmp(inst *ir.InstFCmp) {
+	fcmp := func(x, y ast.Expr, t types.Type) (ast.Expr, error) {
+		return fgen.gen.fcmpExpr(inst.Pred, x, y, t)
+	}
+	fgen.liftBinOp(inst, inst.X, inst.Y, fcmp)
+}
+
+// liftInstSelect lifts the LLVM IR select instruction to Go source code,
+// emitting to f.
+//
+//    if cond {
+//       name = x
+//    } else {
+//       name = y
+//    }
+func (fgen *funcGen) liftInstSelect(inst *ir.InstSelect) {
+	if _, ok := inst.Cond.Type().(*types.VectorType); ok {
+		fgen.liftVectorSelect(inst)
+		return
+	}
+	// Variable name.
+	name := newIdent(inst)
+	// Condition and operands.
+	cond := fgen.liftValue(inst.Cond)
+	x := fgen.liftValue(inst.X)
+	y := fgen.liftValue(inst.Y)
+	// Append if-else statement.
+	ifStmt := &ast.IfStmt{
+		Cond: cond,
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{assignStmt(name, x)},
+		},
+		Else: &ast.BlockStmt{
+			List: []ast.Stmt{assignStmt(name, y)},
+		},
+	}
+	fgen.cur.List = append(fgen.cur.List, ifStmt)
 }
 
 // ipred returns the Go token corresponding to the given LLVM IR integer
@@ -718,6 +1263,16 @@ func (fgen *funcGen) liftTerm(term ir.Terminator) {
 		fgen.liftTermBr(term)
 	case *ir.TermCondBr:
 		fgen.liftTermCondBr(term)
+	case *ir.TermInvoke:
+		fgen.liftTermInvoke(term)
+	case *ir.TermResume:
+		fgen.liftTermResume(term)
+	case *ir.TermCatchSwitch:
+		fgen.liftTermCatchSwitch(term)
+	case *ir.TermCatchRet:
+		fgen.liftTermCatchRet(term)
+	case *ir.TermCleanupRet:
+		fgen.liftTermCleanupRet(term)
 	default:
 		panic(fmt.Errorf("support for terminator %T not yet implemented", term))
 	}
@@ -727,7 +1282,9 @@ func (fgen *funcGen) liftTerm(term ir.Terminator) {
 // f.
 func (fgen *funcGen) liftTermRet(term *ir.TermRet) {
 	var results []ast.Expr
-	if term.X != nil {
+	if term.X != nil && fgen.multiResult {
+		results = fgen.liftMultiResultRet(term.X)
+	} else if term.X != nil {
 		result := fgen.liftValue(term.X)
 		results = append(results, result)
 	}
@@ -778,6 +1335,9 @@ func (fgen *funcGen) getCond(term ir.Terminator) ast.Expr {
 	switch term := term.(type) {
 	case *ir.TermCondBr:
 		return fgen.liftValue(term.Cond)
+	case *ir.TermInvoke:
+		// True if the call returned normally.
+		return fgen.liftInvoke(term)
 	default:
 		panic(fmt.Errorf("support for terminator %T not yet implemented", term))
 	}
@@ -787,15 +1347,68 @@ func (fgen *funcGen) getCond(term ir.Terminator) ast.Expr {
 // to f.
 func (fgen *funcGen) liftValue(v value.Value) ast.Expr {
 	switch v := v.(type) {
+	case constant.Constant:
+		// Note, constants (including global variables and functions) are handled
+		// before named values, as the address of a global variable is lifted to
+		// &g.
+		expr, err := fgen.gen.liftConst(v)
+		if err != nil {
+			fgen.gen.eh(err)
+			return &ast.BadExpr{}
+		}
+		return expr
 	case namedValue:
+		if name, ok := fgen.gen.strConst(v); ok {
+			return fgen.gen.strPtrExpr(name)
+		}
 		return newIdent(v)
-	case *constant.Int:
-		return &ast.BasicLit{Kind: token.INT, Value: v.X.String()}
 	default:
 		panic(fmt.Errorf("support for value %T not yet implemented", v))
 	}
 }
 
+// liftCallArg lifts the LLVM IR argument of the given parameter index of a call
+// to callee, converting it to the inferred type of the parameter. C strings
+// passed to known C library functions are lifted to Go strings.
+func (fgen *funcGen) liftCallArg(callee value.Value, i int, v value.Value) ast.Expr {
+	if j, ok := vaListParam(callee); ok && i == j {
+		// Variable argument list passed to C library function; spread as
+		// variadic arguments.
+		return fgen.vaListIdent(v)
+	}
+	if fgen.isVAList(v) {
+		fgen.gen.Errorf("support for passing variable argument list to callee %q not yet implemented", callee.Ident())
+		return &ast.BadExpr{}
+	}
+	f, ok := callee.(*ir.Func)
+	if !ok {
+		return fgen.liftValue(v)
+	}
+	if i >= len(f.Params) {
+		// Variadic argument.
+		if _, ok := strParams[f.Name()]; ok {
+			if name, ok := fgen.gen.strConst(v); ok {
+				return fgen.gen.globalIdent(name)
+			}
+		}
+		return fgen.liftValue(v)
+	}
+	if isStrParam(f, i) {
+		if name, ok := fgen.gen.strConst(v); ok {
+			return fgen.gen.globalIdent(name)
+		}
+		fgen.gen.addGoStringHelper()
+		return callExpr(ast.NewIdent(goStringName), fgen.liftValue(v))
+	}
+	arg := fgen.liftValue(v)
+	x, err := fgen.gen.convValueExpr(arg, v, fgen.gen.valueType(f.Params[i]))
+	if err != nil {
+		fgen.gen.eh(err)
+		return &ast.BadExpr{}
+	}
+	return x
+}
+
 // namedValue is a global or local variable.
 type namedValue interface {
 	value.Named
@@ -814,18 +1427,10 @@ func newName(v namedValue) string {
 		name := fmt.Sprintf("_%d", v.ID())
 		return name
 	}
-	f := func(r rune) rune {
-		const (
-			lower = "abcdefghijklmnopqrstuvwxyz"
-			upper = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-			digit = "0123456789"
-			valid = lower + upper + digit + "_"
-		)
-		if !strings.ContainsRune(valid, r) {
-			return '_'
-		}
-		return r
+	name := sanitizeName(v.Name())
+	if reservedNames[name] {
+		// Avoid shadowing predeclared identifiers and imported packages.
+		name += "_"
 	}
-	name := strings.Map(f, v.Name())
 	return name
 }
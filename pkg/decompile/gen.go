@@ -5,6 +5,8 @@ import (
 	gotypes "go/types"
 
 	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
 	"github.com/mewmew/lnp/pkg/cfa/primitive"
 )
 
@@ -14,47 +16,160 @@ type Generator struct {
 	// Prims returns the control flow primitives of the given function.
 	Prims func(f *ir.Func) ([]*primitive.Primitive, error)
 
+	// BigFloat specifies whether to represent extended precision floating-point
+	// types as arbitrary precision floating-point numbers (*big.Float), rather
+	// than float64.
+	BigFloat bool
+
+	// PkgName specifies the package name of the generated Go source files. By
+	// default, the package name is derived from the source files and target
+	// triples of the LLVM IR modules.
+	PkgName string
+
 	// Error handler used to report errors encountered during decompilation.
 	eh func(error)
+	// LLVM IR modules being decompiled.
+	ms []*ir.Module
 	// LLVM IR module being decompiled.
 	m *ir.Module
 	// Go source file being generated.
 	file *ast.File
 
-	// Index of Go top-level declarations.
+	// Index of Go top-level declarations of the current module.
 
 	// typeDefs maps from type name to type definition.
 	typeDefs map[string]*gotypes.Named
+	// sharedTypes records the type names of type definitions already emitted to
+	// the Go source file of a previous module.
+	sharedTypes map[string]bool
 	// globals maps from global identifier to global declarations and defintions.
 	globals map[string]*ast.GenDecl
 	// funcs maps from global identifier to function declarations and defintions.
 	funcs map[string]*ast.FuncDecl
+	// external records the global identifiers of global variables and
+	// functions declared or defined in the Go source file of another module.
+	external map[string]bool
+	// strGlobals maps from global identifier to the contents of C string
+	// global variables, which are lifted to Go string constants.
+	strGlobals map[string]string
+	// multiResults records the global identifiers of functions lifted to Go
+	// functions with multiple results.
+	multiResults map[string]bool
+	// goNames maps from global identifier to Go identifier.
+	goNames map[string]string
+	// users maps from LLVM IR values to their users.
+	users map[value.Value][]interface{}
+	// valueTypes maps from LLVM IR values to their inferred LLVM IR types.
+	valueTypes map[value.Value]types.Type
+	// fieldRefs maps from LLVM IR getelementptr and bitcast instructions to the
+	// fields of recovered struct types they address.
+	fieldRefs map[value.Value]fieldRef
+	// imports records the import paths of packages used by the generated Go
+	// source code.
+	imports map[string]bool
+
+	// Index of Go top-level declarations of the package.
+
+	// pkgNames maps from global identifier to Go identifier of global variables
+	// and functions shared between modules (i.e. not of internal or private
+	// linkage).
+	pkgNames map[string]string
+	// pkgTypes maps from type name to type definitions shared between modules.
+	pkgTypes map[string]*pkgType
+	// typeBodies maps from type name to the definition of the first non-opaque
+	// type definition of the type name in any module.
+	typeBodies map[string]string
+	// defined records the global identifiers of shared global variables and
+	// functions defined by any module.
+	defined map[string]bool
+	// exported records the global identifiers of shared global variables and
+	// functions lifted to exported Go identifiers.
+	exported map[string]bool
+	// emitted records the global identifiers of shared global variables and
+	// functions emitted to the Go source file of any module.
+	emitted map[string]bool
+	// notStr records the global identifiers of shared global variables which
+	// are not lifted to Go string constants in every module.
+	notStr map[string]bool
+	// notMultiResult records the global identifiers of shared functions which
+	// are not lifted to Go functions with multiple results in every module.
+	notMultiResult map[string]bool
+	// usedNames records the Go identifiers of top-level declarations.
+	usedNames map[string]bool
+	// warned records the floating-point kinds for which a warning about lost
+	// precision has been reported.
+	warned map[types.FloatKind]bool
+	// nerrs is the number of errors encountered during decompilation.
+	nerrs int
+	// curFunc is the name of the LLVM IR function being decompiled.
+	curFunc string
+	// curInst is the LLVM IR assembly of the instruction or terminator being
+	// decompiled.
+	curInst string
+	// funcErr is the first error encountered outside of instructions and
+	// terminators (e.g. while lifting declarations, types or control flow
+	// primitives) of the LLVM IR function being decompiled.
+	funcErr error
+	// helpers records the names of the helper functions (e.g. the
+	// deferred-recover helper function used by lifted invoke terminators) added
+	// to the Go source file.
+	helpers map[string]bool
 }
 
-// NewGenerator returns a new generator for decompiling the LLVM IR module to Go
-// source code. The error handler eh is invoked when an error is encountered
-// during decompilation.
-func NewGenerator(eh func(error), m *ir.Module) *Generator {
-	// TODO: sanitize source file name to be valid Go identifier.
-	pkgName := m.SourceFilename
-	if len(pkgName) == 0 {
-		pkgName = "p"
-	}
-	for _, f := range m.Funcs {
-		if f.Name() == "main" {
-			pkgName = "main"
-			break
-		}
-	}
+// pkgType is a type definition shared between modules.
+type pkgType struct {
+	// Definition of the LLVM IR type definition.
+	def string
+	// Go type definition.
+	t *gotypes.Named
+	// Specifies whether the type definition is emitted to the Go source file
+	// of any module; opaque type definitions are emitted by the module which
+	// defines their body.
+	emitted bool
+}
+
+// NewGenerator returns a new generator for decompiling the LLVM IR modules to
+// Go source code of a single Go package. The error handler eh is invoked with an
+// *Error when an error is encountered during decompilation.
+func NewGenerator(eh func(error), ms ...*ir.Module) *Generator {
 	gen := &Generator{
-		eh: eh,
-		m:  m,
-		file: &ast.File{
-			Name: ast.NewIdent(pkgName),
-		},
-		typeDefs: make(map[string]*gotypes.Named),
-		globals:  make(map[string]*ast.GenDecl),
-		funcs:    make(map[string]*ast.FuncDecl),
+		PkgName:        PackageName(ms...),
+		ms:             ms,
+		pkgNames:       make(map[string]string),
+		pkgTypes:       make(map[string]*pkgType),
+		typeBodies:     make(map[string]string),
+		defined:        make(map[string]bool),
+		exported:       make(map[string]bool),
+		emitted:        make(map[string]bool),
+		notStr:         make(map[string]bool),
+		notMultiResult: make(map[string]bool),
+		usedNames:      make(map[string]bool),
+		warned:         make(map[types.FloatKind]bool),
+	}
+	gen.eh = func(err error) {
+		gen.handleError(eh, err)
 	}
 	return gen
 }
+
+// enterModule prepares the generator for decompiling the given LLVM IR module
+// to a new Go source file.
+func (gen *Generator) enterModule(m *ir.Module) {
+	gen.m = m
+	gen.file = &ast.File{
+		Name: ast.NewIdent(gen.PkgName),
+	}
+	gen.typeDefs = make(map[string]*gotypes.Named)
+	gen.sharedTypes = make(map[string]bool)
+	gen.globals = make(map[string]*ast.GenDecl)
+	gen.funcs = make(map[string]*ast.FuncDecl)
+	gen.external = make(map[string]bool)
+	gen.strGlobals = make(map[string]string)
+	gen.multiResults = make(map[string]bool)
+	gen.goNames = make(map[string]string)
+	gen.users = make(map[value.Value][]interface{})
+	gen.valueTypes = make(map[value.Value]types.Type)
+	gen.fieldRefs = make(map[value.Value]fieldRef)
+	gen.imports = make(map[string]bool)
+	gen.helpers = make(map[string]bool)
+}
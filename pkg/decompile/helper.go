@@ -3,6 +3,7 @@ package decompile
 import (
 	"go/ast"
 	"go/token"
+	gotypes "go/types"
 	"strconv"
 )
 
@@ -14,3 +15,142 @@ func goIntLit(n int64) *ast.BasicLit {
 		Value: strconv.FormatInt(n, 10),
 	}
 }
+
+// goUintLit returns the AST Go integer literal corresponding to the given
+// 64-bit unsigned integer.
+func goUintLit(n uint64) *ast.BasicLit {
+	return &ast.BasicLit{
+		Kind:  token.INT,
+		Value: strconv.FormatUint(n, 10),
+	}
+}
+
+// goConvExpr returns the AST Go conversion expression of x to the given Go
+// type.
+func goConvExpr(goType gotypes.Type, x ast.Expr) *ast.CallExpr {
+	typ := goTypeExpr(goType)
+	switch typ.(type) {
+	case *ast.StarExpr, *ast.FuncType:
+		// Parenthesize pointer and function types to disambiguate the
+		// conversion; e.g.
+		//
+		//    (*int8)(p)
+		typ = &ast.ParenExpr{X: typ}
+	}
+	return callExpr(typ, x)
+}
+
+// callExpr returns the AST Go call expression of fun with the given arguments.
+func callExpr(fun ast.Expr, args ...ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{
+		Fun:  fun,
+		Args: args,
+	}
+}
+
+// notExpr returns the AST Go expression of the logical negation of x.
+func notExpr(x ast.Expr) *ast.UnaryExpr {
+	return &ast.UnaryExpr{
+		Op: token.NOT,
+		X:  &ast.ParenExpr{X: x},
+	}
+}
+
+// derefExpr returns the AST Go expression of the value pointed to by the
+// pointer x.
+func derefExpr(x ast.Expr) ast.Expr {
+	// Simplify *&v to v.
+	if x, ok := x.(*ast.UnaryExpr); ok && x.Op == token.AND {
+		return x.X
+	}
+	return &ast.StarExpr{X: x}
+}
+
+// addrExpr returns the AST Go expression of the address of x.
+func addrExpr(x ast.Expr) ast.Expr {
+	// Simplify &*p to p.
+	if x, ok := x.(*ast.StarExpr); ok {
+		return x.X
+	}
+	return &ast.UnaryExpr{Op: token.AND, X: x}
+}
+
+// autoDerefExpr returns the AST Go expression of the operand x of a selector or
+// array index expression. Go automatically dereferences pointers to structs
+// and arrays in such expressions, thus (*p).f is simplified to p.f.
+func autoDerefExpr(x ast.Expr) ast.Expr {
+	if x, ok := x.(*ast.StarExpr); ok {
+		return x.X
+	}
+	return x
+}
+
+// defineStmt returns the AST Go short variable declaration of lhs initialized
+// to rhs.
+func defineStmt(lhs, rhs ast.Expr) *ast.AssignStmt {
+	return &ast.AssignStmt{
+		Lhs: []ast.Expr{lhs},
+		Tok: token.DEFINE,
+		Rhs: []ast.Expr{rhs},
+	}
+}
+
+// assignStmt returns the AST Go assignment statement of rhs to lhs.
+func assignStmt(lhs, rhs ast.Expr) *ast.AssignStmt {
+	return &ast.AssignStmt{
+		Lhs: []ast.Expr{lhs},
+		Tok: token.ASSIGN,
+		Rhs: []ast.Expr{rhs},
+	}
+}
+
+// funcLitCall returns the AST Go call expression of an immediately invoked
+// function literal with the given result type and body statements. It is used
+// to represent LLVM IR values which have no direct Go expression counterpart.
+//
+//    func() T {
+//       stmts
+//    }()
+func funcLitCall(result gotypes.Type, stmts ...ast.Stmt) *ast.CallExpr {
+	funcLit := &ast.FuncLit{
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{},
+			Results: &ast.FieldList{
+				List: []*ast.Field{{Type: goTypeExpr(result)}},
+			},
+		},
+		Body: &ast.BlockStmt{
+			List: stmts,
+		},
+	}
+	return callExpr(funcLit)
+}
+
+// condExpr returns the AST Go expression evaluating to x if cond is true and to
+// y otherwise.
+//
+//    func() T {
+//       if cond {
+//          return x
+//       }
+//       return y
+//    }()
+func condExpr(goType gotypes.Type, cond, x, y ast.Expr) *ast.CallExpr {
+	ifStmt := &ast.IfStmt{
+		Cond: cond,
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{x}}},
+		},
+	}
+	return funcLitCall(goType, ifStmt, &ast.ReturnStmt{Results: []ast.Expr{y}})
+}
+
+// zeroValueExpr returns the AST Go expression of the zero value of the given Go
+// type.
+//
+//    *new(T)
+func zeroValueExpr(goType gotypes.Type) ast.Expr {
+	return &ast.StarExpr{
+		X: callExpr(ast.NewIdent("new"), goTypeExpr(goType)),
+	}
+}
@@ -0,0 +1,314 @@
+package decompile
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/enum"
+)
+
+// Identifier policy.
+//
+// LLVM IR names are mapped to Go identifiers as follows.
+//
+//    1. Itanium (_Z) and legacy Rust (_ZN...17h<hash>E) mangled names are
+//       demangled, and their name components are joined by underscores.
+//
+//          _ZN3foo3barEv   ->   foo_bar
+//
+//    2. Characters not valid in Go identifiers are replaced by underscores, and
+//       leading dots are dropped.
+//
+//          .str.1   ->   str_1
+//
+//    3. Global identifiers defined with external linkage and non-hidden
+//       visibility by any module are exported, while those with internal or
+//       private linkage are kept unexported. Other global identifiers (e.g.
+//       declarations of C library functions) retain the case of their LLVM IR
+//       name.
+//
+//    4. Collisions with Go keywords, predeclared identifiers, names of imported
+//       packages, helper functions of the generated Go source code and other
+//       top-level identifiers of the Go package (e.g. static functions of the
+//       same name in different modules) are resolved by appending a numeric
+//       suffix.
+//
+//          @int, @int.1   ->   int_1, int_2
+
+// reservedNames specifies identifiers which may not be used for top-level
+// declarations of the generated Go source code.
+var reservedNames = map[string]bool{
+	// Predeclared identifiers.
+	"bool": true, "byte": true, "complex64": true, "complex128": true,
+	"error": true, "float32": true, "float64": true, "int": true, "int8": true,
+	"int16": true, "int32": true, "int64": true, "rune": true, "string": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"uintptr": true, "true": true, "false": true, "iota": true, "nil": true,
+	"append": true, "cap": true, "close": true, "complex": true, "copy": true,
+	"delete": true, "imag": true, "len": true, "make": true, "new": true,
+	"panic": true, "print": true, "println": true, "real": true,
+	"recover": true,
+	// Special function names.
+	"init": true, "main": true,
+	// Names of imported packages.
+	"atomic": true, "big": true, "math": true, "unsafe": true,
+	// Helper functions.
+	tryCallName:    true,
+	goStringName:   true,
+	wrapBigIntName: true,
+	// Local variables.
+	vaParamName: true,
+	excName:     true,
+	unwoundName: true,
+}
+
+// indexGlobalNames assigns unique Go identifiers to the global variables,
+// functions, aliases and IFuncs of the current LLVM IR module. Global
+// identifiers shared between modules are assigned Go identifiers by
+// indexPackage.
+//
+// post-condition: gen.goNames maps from global identifier (without '@' prefix)
+// to Go identifier.
+func (gen *Generator) indexGlobalNames() {
+	for _, sym := range moduleSymbols(gen.m) {
+		if !isLocal(sym.linkage) {
+			gen.goNames[sym.name] = gen.pkgNames[sym.name]
+			continue
+		}
+		goName := unexportName(sanitizeName(demangle(sym.name)))
+		gen.goNames[sym.name] = gen.uniqueName(goName)
+	}
+}
+
+// sharedName returns a unique Go identifier for the given global identifier
+// shared between modules. Shared global identifiers are exported if defined
+// with non-hidden visibility by any module, and retain the case of their LLVM
+// IR name otherwise.
+func (gen *Generator) sharedName(name string) string {
+	if name == "main" && gen.defined[name] {
+		// Keep entry point of Go main package.
+		gen.usedNames[name] = true
+		return name
+	}
+	goName := sanitizeName(demangle(name))
+	if gen.exported[name] {
+		goName = exportName(goName)
+	}
+	return gen.uniqueName(goName)
+}
+
+// globalIdent returns the Go identifier of the given global identifier
+// (without '@' prefix).
+func (gen *Generator) globalIdent(name string) *ast.Ident {
+	if goName, ok := gen.goNames[name]; ok {
+		return ast.NewIdent(goName)
+	}
+	return ast.NewIdent(sanitizeName(name))
+}
+
+// uniqueName returns a Go identifier based on the given name which does not
+// collide with reserved names or previously assigned identifiers.
+func (gen *Generator) uniqueName(name string) string {
+	goName := name
+	for i := 1; reservedNames[goName] || token.IsKeyword(goName) || gen.usedNames[goName]; i++ {
+		goName = fmt.Sprintf("%s_%d", name, i)
+	}
+	gen.usedNames[goName] = true
+	return goName
+}
+
+// symbol is a global variable, function, alias or IFunc of an LLVM IR module.
+type symbol struct {
+	// Global identifier (without '@' prefix).
+	name string
+	// Linkage type.
+	linkage enum.Linkage
+	// Visibility style.
+	visibility enum.Visibility
+	// Specifies whether the symbol is defined (rather than declared) by the
+	// module.
+	isDef bool
+}
+
+// moduleSymbols returns the global variables, functions, aliases and IFuncs of
+// the given LLVM IR module.
+func moduleSymbols(m *ir.Module) []symbol {
+	var syms []symbol
+	for _, irGlobal := range m.Globals {
+		syms = append(syms, symbol{name: irGlobal.Name(), linkage: irGlobal.Linkage, visibility: irGlobal.Visibility, isDef: irGlobal.Init != nil})
+	}
+	for _, irFunc := range m.Funcs {
+		syms = append(syms, symbol{name: irFunc.Name(), linkage: irFunc.Linkage, visibility: irFunc.Visibility, isDef: len(irFunc.Blocks) > 0})
+	}
+	for _, irAlias := range m.Aliases {
+		syms = append(syms, symbol{name: irAlias.Name(), linkage: irAlias.Linkage, visibility: irAlias.Visibility, isDef: true})
+	}
+	for _, irIFunc := range m.IFuncs {
+		syms = append(syms, symbol{name: irIFunc.Name(), linkage: irIFunc.Linkage, visibility: irIFunc.Visibility, isDef: true})
+	}
+	return syms
+}
+
+// isLocal reports whether global identifiers of the given linkage are local to
+// their LLVM IR module (i.e. internal or private linkage).
+func isLocal(linkage enum.Linkage) bool {
+	return linkage == enum.LinkageInternal || linkage == enum.LinkagePrivate
+}
+
+// sanitizeName returns a valid Go identifier based on the given LLVM IR name,
+// by replacing invalid characters with underscores. Go keywords are suffixed by
+// an underscore.
+func sanitizeName(name string) string {
+	f := func(r rune) rune {
+		const (
+			lower = "abcdefghijklmnopqrstuvwxyz"
+			upper = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+			digit = "0123456789"
+			valid = lower + upper + digit + "_"
+		)
+		if !strings.ContainsRune(valid, r) {
+			return '_'
+		}
+		return r
+	}
+	name = strings.Map(f, strings.TrimLeft(name, "."))
+	switch {
+	case len(name) == 0:
+		return "_"
+	case '0' <= name[0] && name[0] <= '9':
+		name = "_" + name
+	case token.IsKeyword(name):
+		name += "_"
+	}
+	return name
+}
+
+// exportName returns the exported Go identifier of the given valid Go
+// identifier, by upper-casing its first letter. Leading underscores are
+// dropped.
+func exportName(name string) string {
+	s := strings.TrimLeft(name, "_")
+	if len(s) == 0 || !unicode.IsLetter(rune(s[0])) {
+		return "X" + s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// unexportName returns the unexported Go identifier of the given valid Go
+// identifier, by lower-casing its leading upper-case letters (e.g. FOO_BAR ->
+// foo_BAR, URLParse -> urlParse).
+func unexportName(name string) string {
+	n := 0
+	for n < len(name) && unicode.IsUpper(rune(name[n])) {
+		n++
+	}
+	if n > 1 && n < len(name) && unicode.IsLower(rune(name[n])) {
+		// Keep the first letter of the next word upper-case.
+		n--
+	}
+	return strings.ToLower(name[:n]) + name[n:]
+}
+
+// --- [ Demangling ] ----------------------------------------------------------
+
+// demangle returns the name components of the given Itanium or legacy Rust
+// mangled name, joined by underscores. Names which are not mangled, or which
+// use unsupported manglings (e.g. templates and substitutions), are returned
+// unmodified.
+//
+//    _Z3foov          ->   foo
+//    _ZN3foo3barEv    ->   foo_bar
+//    _ZN3FooC2Ev      ->   Foo_ctor
+//    _ZN4core3fmt5write17h0123456789abcdefE   ->   core_fmt_write
+func demangle(name string) string {
+	// Strip leading underscore added by Mach-O.
+	s := strings.TrimPrefix(name, "__Z")
+	if len(s) == len(name) {
+		s = strings.TrimPrefix(name, "_Z")
+		if len(s) == len(name) {
+			return name
+		}
+	}
+	var parts []string
+	if strings.HasPrefix(s, "N") {
+		// Nested name.
+		//
+		//    N [<CV-qualifiers>] <prefix> <unqualified-name> E
+		s = strings.TrimLeft(s[1:], "rVK")
+		for !strings.HasPrefix(s, "E") {
+			part, rest, ok := demangleUnqualifiedName(s)
+			if !ok {
+				return name
+			}
+			parts = append(parts, part)
+			s = rest
+		}
+		// Drop hash of legacy Rust symbols.
+		if n := len(parts); n > 1 && isRustHash(parts[n-1]) {
+			parts = parts[:n-1]
+		}
+	} else {
+		if strings.HasPrefix(s, "St") {
+			// ::std::
+			parts = append(parts, "std")
+			s = s[len("St"):]
+		}
+		part, _, ok := demangleUnqualifiedName(s)
+		if !ok {
+			return name
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return name
+	}
+	return strings.Join(parts, "_")
+}
+
+// demangleUnqualifiedName returns the first unqualified name of the given
+// Itanium mangled name, and the remaining mangled name. The boolean return
+// value indicates success.
+//
+//    <source-name>   ::= <positive length number> <identifier>
+//    <ctor-dtor-name> ::= C1 | C2 | C3 | D0 | D1 | D2
+func demangleUnqualifiedName(s string) (string, string, bool) {
+	switch {
+	case strings.HasPrefix(s, "St"):
+		return "std", s[len("St"):], true
+	case len(s) >= 2 && s[0] == 'C' && '1' <= s[1] && s[1] <= '3':
+		return "ctor", s[2:], true
+	case len(s) >= 2 && s[0] == 'D' && '0' <= s[1] && s[1] <= '2':
+		return "dtor", s[2:], true
+	}
+	n := 0
+	for n < len(s) && '0' <= s[n] && s[n] <= '9' {
+		n++
+	}
+	if n == 0 {
+		return "", "", false
+	}
+	length, err := strconv.Atoi(s[:n])
+	if err != nil || n+length > len(s) {
+		return "", "", false
+	}
+	return s[n : n+length], s[n+length:], true
+}
+
+// isRustHash reports whether the given name component is the hash suffix of a
+// legacy Rust symbol (e.g. h0123456789abcdef).
+func isRustHash(part string) bool {
+	if len(part) != 17 || part[0] != 'h' {
+		return false
+	}
+	for _, r := range part[1:] {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
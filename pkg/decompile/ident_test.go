@@ -0,0 +1,113 @@
+package decompile
+
+import "testing"
+
+func TestDemangle(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		// Not mangled.
+		{in: "main", want: "main"},
+		// Unqualified name.
+		{in: "_Z3foov", want: "foo"},
+		// Mach-O leading underscore.
+		{in: "__Z3foov", want: "foo"},
+		// Nested name.
+		{in: "_ZN3foo3barEv", want: "foo_bar"},
+		// Nested name with CV-qualifiers.
+		{in: "_ZNK3foo3barEv", want: "foo_bar"},
+		// Constructor and destructor.
+		{in: "_ZN3FooC2Ev", want: "Foo_ctor"},
+		{in: "_ZN3FooD1Ev", want: "Foo_dtor"},
+		// ::std::
+		{in: "_ZNSt6vectorE", want: "std_vector"},
+		{in: "_ZSt9terminatev", want: "std_terminate"},
+		// Legacy Rust symbol with hash.
+		{in: "_ZN4core3fmt5write17h0123456789abcdefE", want: "core_fmt_write"},
+		// Unsupported manglings are returned unmodified.
+		{in: "_ZN3fooIiEEv", want: "_ZN3fooIiEEv"},
+		{in: "_Zfoo", want: "_Zfoo"},
+		{in: "_Z99foo", want: "_Z99foo"},
+	}
+	for _, g := range golden {
+		got := demangle(g.in)
+		if got != g.want {
+			t.Errorf("%q: demangled name mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{in: "foo", want: "foo"},
+		// Leading dots are dropped.
+		{in: ".str", want: "str"},
+		{in: ".str.1", want: "str_1"},
+		// Invalid characters.
+		{in: "foo-bar$baz", want: "foo_bar_baz"},
+		{in: "café", want: "caf_"},
+		// Leading digit.
+		{in: "1abc", want: "_1abc"},
+		// Go keyword.
+		{in: "type", want: "type_"},
+		// Empty name.
+		{in: "", want: "_"},
+		{in: "..", want: "_"},
+	}
+	for _, g := range golden {
+		got := sanitizeName(g.in)
+		if got != g.want {
+			t.Errorf("%q: sanitized name mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
+
+func TestExportName(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{in: "foo", want: "Foo"},
+		{in: "Foo", want: "Foo"},
+		{in: "foo_bar", want: "Foo_bar"},
+		// Leading underscores are dropped.
+		{in: "_foo", want: "Foo"},
+		{in: "__foo", want: "Foo"},
+		// No leading letter.
+		{in: "_1abc", want: "X1abc"},
+		{in: "_", want: "X"},
+	}
+	for _, g := range golden {
+		got := exportName(g.in)
+		if got != g.want {
+			t.Errorf("%q: exported name mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
+
+func TestUnexportName(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+	}{
+		{in: "foo", want: "foo"},
+		{in: "Foo", want: "foo"},
+		{in: "FOO", want: "foo"},
+		{in: "ID", want: "id"},
+		{in: "FOO_BAR", want: "foo_BAR"},
+		// Keep the first letter of the next word upper-case.
+		{in: "URLParse", want: "urlParse"},
+		{in: "_Foo", want: "_Foo"},
+		{in: "_", want: "_"},
+	}
+	for _, g := range golden {
+		got := unexportName(g.in)
+		if got != g.want {
+			t.Errorf("%q: unexported name mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package decompile
+
+import (
+	"go/ast"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// qualIdent returns a qualified Go identifier referring to the exported name of
+// the given imported package (e.g. unsafe.Pointer). The package is recorded as
+// an import of the Go source file being generated.
+func (gen *Generator) qualIdent(pkgPath, name string) *ast.SelectorExpr {
+	gen.imports[pkgPath] = true
+	return &ast.SelectorExpr{
+		X:   ast.NewIdent(path.Base(pkgPath)),
+		Sel: ast.NewIdent(name),
+	}
+}
+
+// addImportDecl prepends an import declaration of the packages used by the
+// generated Go source code to the Go source file.
+func (gen *Generator) addImportDecl() {
+	if len(gen.imports) == 0 {
+		return
+	}
+	var pkgPaths []string
+	for pkgPath := range gen.imports {
+		pkgPaths = append(pkgPaths, pkgPath)
+	}
+	sort.Strings(pkgPaths)
+	importDecl := &ast.GenDecl{
+		Tok: token.IMPORT,
+	}
+	if len(pkgPaths) > 1 {
+		// Use parenthesized import declaration when importing more than one
+		// package.
+		importDecl.Lparen = 1
+	}
+	for _, pkgPath := range pkgPaths {
+		spec := &ast.ImportSpec{
+			Path: &ast.BasicLit{
+				Kind:  token.STRING,
+				Value: strconv.Quote(pkgPath),
+			},
+		}
+		importDecl.Specs = append(importDecl.Specs, spec)
+	}
+	gen.file.Decls = append([]ast.Decl{importDecl}, gen.file.Decls...)
+}
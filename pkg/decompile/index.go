@@ -22,6 +22,9 @@ func (gen *Generator) createGlobalDecls() {
 	// Index global identifiers and create scaffolding global variable
 	// declarations.
 	for _, irGlobal := range gen.m.Globals {
+		if gen.isEmittedElsewhere(irGlobal.Name(), irGlobal.Linkage, irGlobal.Init != nil) {
+			continue
+		}
 		global, err := gen.newGlobal(irGlobal)
 		if err != nil {
 			gen.eh(err)
@@ -38,6 +41,13 @@ func (gen *Generator) createGlobalDecls() {
 	}
 	// Index global identifiers and create scaffolding function declarations.
 	for _, irFunc := range gen.m.Funcs {
+		if isVAIntrinsic(irFunc.Name()) {
+			// Skip intrinsics lifted to operations on the variadic parameter.
+			continue
+		}
+		if gen.isEmittedElsewhere(irFunc.Name(), irFunc.Linkage, len(irFunc.Blocks) > 0) {
+			continue
+		}
 		f, err := gen.newFunc(irFunc)
 		if err != nil {
 			gen.eh(err)
@@ -58,12 +68,23 @@ func (gen *Generator) createGlobalDecls() {
 // type) based on the given LLVM IR global declaration or definition.
 func (gen *Generator) newGlobal(irGlobal *ir.Global) (*ast.GenDecl, error) {
 	name := irGlobal.Name()
-	contentType, err := gen.goType(irGlobal.ContentType)
+	if _, ok := gen.strGlobals[name]; ok {
+		// C string global variable lifted to Go string constant.
+		spec := &ast.ValueSpec{
+			Names: []*ast.Ident{gen.globalIdent(name)},
+		}
+		goGlobal := &ast.GenDecl{
+			Tok:   token.CONST,
+			Specs: []ast.Spec{spec},
+		}
+		return goGlobal, nil
+	}
+	contentType, err := gen.goType(gen.contentType(irGlobal))
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 	spec := &ast.ValueSpec{
-		Names: []*ast.Ident{ast.NewIdent(name)},
+		Names: []*ast.Ident{gen.globalIdent(name)},
 		Type:  goTypeExpr(contentType),
 	}
 	goGlobal := &ast.GenDecl{
@@ -84,17 +105,47 @@ func (gen *Generator) newFunc(irFunc *ir.Func) (*ast.FuncDecl, error) {
 	tsig := t.(*gotypes.Signature)
 	var ps []*gotypes.Var
 	tps := tsig.Params()
-	// TODO: handle variadic functions.
-	for i := 0; i < tps.Len(); i++ {
-		tp := tps.At(i)
-		name := newName(irFunc.Params[i])
-		p := gotypes.NewVar(0, nil, name, tp.Type())
+	variadic := tsig.Variadic()
+	for i, param := range irFunc.Params {
+		if j, ok := vaListParam(irFunc); ok && i == j {
+			// Variable argument list parameter of C library function.
+			elem := gotypes.NewInterface(nil, nil)
+			p := gotypes.NewVar(0, nil, vaParamName, gotypes.NewSlice(elem))
+			ps = append(ps, p)
+			variadic = true
+			continue
+		}
+		tp := tps.At(i).Type()
+		if isStrParam(irFunc, i) {
+			// C string parameter of C library function.
+			tp = gotypes.Typ[gotypes.String]
+		} else if t, ok := gen.valueTypes[param]; ok {
+			// Parameter of inferred type.
+			if tp, err = gen.goType(t); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		name := newName(param)
+		p := gotypes.NewVar(0, nil, name, tp)
+		ps = append(ps, p)
+	}
+	if tsig.Variadic() {
+		p, err := gen.goVariadicParam(irFunc)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
 		ps = append(ps, p)
 	}
 	params := gotypes.NewTuple(ps...)
-	sig := gotypes.NewSignature(tsig.Recv(), params, tsig.Results(), tsig.Variadic())
+	results := tsig.Results()
+	if gen.multiResults[name] {
+		// Return struct fields as multiple results.
+		t := results.At(0).Type().Underlying().(*gotypes.Struct)
+		results = goMultiResults(t)
+	}
+	sig := gotypes.NewSignature(tsig.Recv(), params, results, variadic)
 	goFunc := &ast.FuncDecl{
-		Name: ast.NewIdent(name),
+		Name: gen.globalIdent(name),
 		Type: goTypeExpr(sig).(*ast.FuncType),
 	}
 	return goFunc, nil
@@ -0,0 +1,554 @@
+package decompile
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"sort"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// Type recovery.
+//
+// C code compiled to LLVM IR passes untyped pointers as i8* (i.e. void*), which
+// would otherwise be lifted to *int8 and converted through unsafe.Pointer at
+// each use. The types of such values are instead inferred from their uses, and
+// propagated through loads, stores, getelementptr instructions, bitcasts and
+// the parameters of called functions.
+//
+//    1. An i8* value only ever bitcast to T* is lifted to a value of type *T.
+//
+//          %1 = bitcast i8* %p to %struct.foo*   ->   p *foo
+//
+//    2. An i8* value only ever accessed at constant offsets (i.e. through
+//       getelementptr instructions on i8 with a constant index, each bitcast
+//       to a single pointer type) is lifted to a pointer to a recovered struct
+//       type, and its accesses are lifted to field selectors.
+//
+//          %1 = getelementptr i8, i8* %p, i64 8
+//          %2 = bitcast i8* %1 to i32*            ->   _2 = &p.field1
+//
+//    3. An alloca or global variable of type i8* only ever loaded as values
+//       inferred to be of type T* and stored values bitcast from T* holds a
+//       value of type *T, and an alloca or global variable of byte array type
+//       only ever bitcast to T* holds a value of type T.
+//
+// Parameters are only recovered for functions of internal or private linkage
+// which are only ever called directly, as the signatures of other functions
+// must match their callers in other modules. Values of inferred type are
+// converted back to their LLVM IR type at uses which expect the LLVM IR type.
+
+// fieldRef is a field of a recovered struct type.
+type fieldRef struct {
+	// Pointer to struct.
+	base value.Value
+	// Field index.
+	index int
+}
+
+// inferTypes infers the types of the untyped pointers of the current LLVM IR
+// module, and recovers struct types for pointers only ever accessed at
+// constant offsets.
+//
+// post-condition: gen.valueTypes maps from LLVM IR values to their inferred
+// LLVM IR types, and gen.fieldRefs maps from getelementptr and bitcast
+// instructions to the fields of recovered struct types they address.
+func (gen *Generator) inferTypes() {
+	gen.indexUses()
+	// Values of type i8*.
+	for _, irFunc := range gen.m.Funcs {
+		if gen.isRecoverableFunc(irFunc) {
+			for _, param := range irFunc.Params {
+				gen.inferValueType(irFunc, param)
+			}
+		}
+		for _, block := range irFunc.Blocks {
+			for _, inst := range block.Insts {
+				switch inst := inst.(type) {
+				case *ir.InstCall:
+					if gen.isMultiResultCall(inst) {
+						continue
+					}
+					gen.inferValueType(irFunc, inst)
+				case *ir.InstLoad:
+					gen.inferValueType(irFunc, inst)
+				}
+			}
+		}
+	}
+	// Allocas and global variables.
+	for _, irFunc := range gen.m.Funcs {
+		for _, block := range irFunc.Blocks {
+			for _, inst := range block.Insts {
+				if inst, ok := inst.(*ir.InstAlloca); ok && inst.NElems == nil {
+					gen.inferSlotType(inst, inst.ElemType)
+				}
+			}
+		}
+	}
+	for _, irGlobal := range gen.m.Globals {
+		if irGlobal.Init == nil || !isLocal(irGlobal.Linkage) || !isZeroConst(irGlobal.Init) {
+			continue
+		}
+		if _, ok := gen.strGlobals[irGlobal.Name()]; ok {
+			continue
+		}
+		gen.inferSlotType(irGlobal, irGlobal.ContentType)
+	}
+}
+
+// inferValueType infers the type of the given i8* value of irFunc from its
+// uses.
+func (gen *Generator) inferValueType(irFunc *ir.Func, v value.Value) {
+	users := gen.users[v]
+	if !isBytePtr(v.Type()) || len(users) == 0 {
+		return
+	}
+	// Pointer types accessed at each offset.
+	offsets := make(map[uint64]*types.PointerType)
+	// Bitcast instructions and getelementptr instructions of each offset.
+	accesses := make(map[uint64][]value.Value)
+	hasGEP := false
+	for _, user := range users {
+		switch user := user.(type) {
+		case *ir.InstBitCast:
+			t, ok := bitcastPtrType(user)
+			if !ok || !addOffset(offsets, 0, t) {
+				return
+			}
+			accesses[0] = append(accesses[0], user)
+		case *ir.InstGetElementPtr:
+			if user.Src != v || !isByte(user.ElemType) || len(user.Indices) != 1 {
+				return
+			}
+			offset, ok := constIndex(user.Indices[0])
+			if !ok || int64(offset) < 0 {
+				return
+			}
+			t, ok := gen.gepPtrType(user)
+			if !ok || !addOffset(offsets, offset, t) {
+				return
+			}
+			accesses[offset] = append(accesses[offset], user)
+			hasGEP = true
+		default:
+			return
+		}
+	}
+	if !hasGEP {
+		// Value only ever bitcast to a single pointer type.
+		gen.valueTypes[v] = offsets[0]
+		return
+	}
+	// Recover struct type of value only ever accessed at constant offsets.
+	t, indices, ok := recoverStruct(offsets)
+	if !ok {
+		return
+	}
+	if err := gen.addRecoveredType(irFunc, t); err != nil {
+		gen.eh(err)
+		return
+	}
+	gen.valueTypes[v] = types.NewPointer(t)
+	for offset, vs := range accesses {
+		for _, access := range vs {
+			gen.fieldRefs[access] = fieldRef{base: v, index: indices[offset]}
+			if gep, ok := access.(*ir.InstGetElementPtr); ok {
+				gen.valueTypes[gep] = offsets[offset]
+			}
+		}
+	}
+}
+
+// gepPtrType returns the pointer type of the bitcast instructions using the
+// given getelementptr instruction. The boolean return value indicates whether
+// the getelementptr instruction is only ever bitcast to a single pointer type.
+func (gen *Generator) gepPtrType(gep *ir.InstGetElementPtr) (*types.PointerType, bool) {
+	var t *types.PointerType
+	for _, user := range gen.users[gep] {
+		bitcast, ok := user.(*ir.InstBitCast)
+		if !ok {
+			return nil, false
+		}
+		to, ok := bitcastPtrType(bitcast)
+		if !ok || (t != nil && !types.Equal(t, to)) {
+			return nil, false
+		}
+		t = to
+	}
+	return t, t != nil
+}
+
+// bitcastPtrType returns the pointer type of the given bitcast instruction. The
+// boolean return value indicates whether the bitcast is to a pointer type other
+// than i8* and function pointers.
+func bitcastPtrType(bitcast *ir.InstBitCast) (*types.PointerType, bool) {
+	t, ok := bitcast.To.(*types.PointerType)
+	if !ok || isBytePtr(t) || isFuncPtr(t) {
+		return nil, false
+	}
+	return t, true
+}
+
+// addOffset records the pointer type accessed at the given offset. The boolean
+// return value indicates whether the offset is only ever accessed as a single
+// pointer type.
+func addOffset(offsets map[uint64]*types.PointerType, offset uint64, t *types.PointerType) bool {
+	if prev, ok := offsets[offset]; ok {
+		return types.Equal(prev, t)
+	}
+	offsets[offset] = t
+	return true
+}
+
+// recoverStruct returns a struct type with fields of the given pointer element
+// types at the given offsets, padded by byte arrays. The field index of each
+// offset is returned in indices. The boolean return value indicates success.
+//
+//    {0: i32*, 8: i64*}   ->   { i32, [4 x i8], i64 }
+func recoverStruct(offsets map[uint64]*types.PointerType) (t *types.StructType, indices map[uint64]int, ok bool) {
+	var keys []uint64
+	for offset := range offsets {
+		keys = append(keys, offset)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	var fields []types.Type
+	indices = make(map[uint64]int)
+	cur := uint64(0)
+	for _, offset := range keys {
+		elem := offsets[offset].ElemType
+		size, ok := sizeOf(elem)
+		if !ok || offset < cur || offset%alignOf(elem) != 0 {
+			// Overlapping or misaligned field.
+			return nil, nil, false
+		}
+		if offset > cur {
+			pad := types.NewArray(offset-cur, types.I8)
+			fields = append(fields, pad)
+		}
+		indices[offset] = len(fields)
+		fields = append(fields, elem)
+		cur = offset + size
+	}
+	return types.NewStruct(fields...), indices, true
+}
+
+// ptrSize is the size in bytes of pointers, assuming a 64-bit target.
+const ptrSize = 8
+
+// sizeOf returns the size in bytes of the given LLVM IR type. The boolean
+// return value indicates whether the size is known.
+func sizeOf(t types.Type) (uint64, bool) {
+	switch t := t.(type) {
+	case *types.IntType:
+		switch t.BitSize {
+		case 8, 16, 32, 64:
+			return t.BitSize / 8, true
+		}
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindFloat:
+			return 4, true
+		case types.FloatKindDouble:
+			return 8, true
+		}
+	case *types.PointerType:
+		return ptrSize, true
+	case *types.ArrayType:
+		size, ok := sizeOf(t.ElemType)
+		return t.Len * size, ok
+	}
+	return 0, false
+}
+
+// alignOf returns the alignment in bytes of the given sized LLVM IR type.
+func alignOf(t types.Type) uint64 {
+	if t, ok := t.(*types.ArrayType); ok {
+		return alignOf(t.ElemType)
+	}
+	size, _ := sizeOf(t)
+	return size
+}
+
+// addRecoveredType adds a type definition of the given struct type recovered
+// from the uses of an untyped pointer of irFunc.
+//
+//    type f_recovered struct { field0 int32; field1 [4]uint8; field2 int64 }
+func (gen *Generator) addRecoveredType(irFunc *ir.Func, t *types.StructType) error {
+	goName := gen.uniqueName(fmt.Sprintf("%s_recovered", gen.globalIdent(irFunc.Name()).Name))
+	name := "recovered." + goName
+	t.SetName(name)
+	underlying, err := gen.goUnderlyingType(t)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	typeName := gotypes.NewTypeName(0, nil, goName, nil)
+	gen.typeDefs[name] = gotypes.NewNamed(typeName, underlying, nil)
+	gen.file.Decls = append(gen.file.Decls, newTypeDef(goName, underlying))
+	return nil
+}
+
+// inferSlotType infers the content type of the given alloca or global variable
+// from its uses.
+func (gen *Generator) inferSlotType(slot value.Value, contentType types.Type) {
+	users := gen.users[slot]
+	switch {
+	case isBytePtr(contentType):
+		// Pointer variable only ever holding values of a single inferred type.
+		var t types.Type
+		for _, user := range users {
+			switch user := user.(type) {
+			case *ir.InstLoad:
+				vt, ok := gen.valueTypes[user]
+				if !ok || (t != nil && !types.Equal(t, vt)) {
+					return
+				}
+				t = vt
+			case *ir.InstStore:
+				if user.Dst != slot || user.Src == slot {
+					return
+				}
+			default:
+				return
+			}
+		}
+		if t == nil {
+			return
+		}
+		// Check stored values.
+		var casts []*ir.InstBitCast
+		for _, user := range users {
+			store, ok := user.(*ir.InstStore)
+			if !ok {
+				continue
+			}
+			switch src := store.Src.(type) {
+			case *ir.InstBitCast:
+				if !types.Equal(gen.valueType(src.From), t) || len(gen.users[src]) != 1 {
+					return
+				}
+				casts = append(casts, src)
+			case *constant.ExprBitCast:
+				if !types.Equal(gen.valueType(src.From), t) {
+					return
+				}
+			case *constant.Null:
+			default:
+				return
+			}
+		}
+		for _, cast := range casts {
+			gen.valueTypes[cast] = t
+		}
+		gen.valueTypes[slot] = types.NewPointer(t)
+	case isByteArray(contentType):
+		// Byte buffer only ever accessed as a single type of the same size.
+		var t *types.PointerType
+		for _, user := range users {
+			var to types.Type
+			switch user := user.(type) {
+			case *ir.InstBitCast:
+				to = user.To
+			case *constant.ExprBitCast:
+				to = user.To
+			default:
+				return
+			}
+			pt, ok := to.(*types.PointerType)
+			if !ok || isFuncPtr(pt) || isBytePtr(pt) || (t != nil && !types.Equal(t, pt)) {
+				return
+			}
+			t = pt
+		}
+		if t == nil {
+			return
+		}
+		// Only retype the byte buffer if its contents are fully covered by the
+		// accessed type.
+		if size, ok := sizeOf(t.ElemType); !ok || size != contentType.(*types.ArrayType).Len {
+			return
+		}
+		gen.valueTypes[slot] = t
+	}
+}
+
+// isRecoverableFunc reports whether the parameter types of the given function
+// may be recovered; i.e. the function is defined with internal or private
+// linkage and only ever called directly by call instructions.
+func (gen *Generator) isRecoverableFunc(irFunc *ir.Func) bool {
+	if len(irFunc.Blocks) == 0 || !isLocal(irFunc.Linkage) {
+		return false
+	}
+	for _, user := range gen.users[irFunc] {
+		call, ok := user.(*ir.InstCall)
+		if !ok || call.Callee != irFunc {
+			return false
+		}
+		for _, arg := range call.Args {
+			if arg == irFunc {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// indexUses indexes the users of the values of the current LLVM IR module.
+//
+// post-condition: gen.users maps from LLVM IR values to their users
+// (instructions, terminators, constant expressions, global variables and
+// aliases).
+func (gen *Generator) indexUses() {
+	var add func(user interface{}, v value.Value)
+	add = func(user interface{}, v value.Value) {
+		gen.users[v] = append(gen.users[v], user)
+		if c, ok := v.(constant.Constant); ok {
+			for _, op := range constOperands(c) {
+				add(c, op)
+			}
+		}
+	}
+	for _, irGlobal := range gen.m.Globals {
+		if irGlobal.Init != nil {
+			add(irGlobal, irGlobal.Init)
+		}
+	}
+	for _, irAlias := range gen.m.Aliases {
+		add(irAlias, irAlias.Aliasee)
+	}
+	for _, irFunc := range gen.m.Funcs {
+		for _, block := range irFunc.Blocks {
+			for _, inst := range block.Insts {
+				for _, op := range instOperands(inst) {
+					add(inst, op)
+				}
+			}
+			for _, op := range termOperands(block.Term) {
+				add(block.Term, op)
+			}
+		}
+	}
+}
+
+// valueType returns the inferred LLVM IR type of the given value.
+func (gen *Generator) valueType(v value.Value) types.Type {
+	if t, ok := gen.valueTypes[v]; ok {
+		return t
+	}
+	return v.Type()
+}
+
+// contentType returns the inferred LLVM IR type of the value pointed to by the
+// given pointer.
+func (gen *Generator) contentType(ptr value.Value) types.Type {
+	return gen.valueType(ptr).(*types.PointerType).ElemType
+}
+
+// convValueExpr returns the Go expression of the conversion of x, holding the
+// given LLVM IR value, from its inferred type to the given LLVM IR type.
+func (gen *Generator) convValueExpr(x ast.Expr, v value.Value, to types.Type) (ast.Expr, error) {
+	return gen.bitcastExpr(x, gen.valueType(v), to)
+}
+
+// fieldRefExpr returns the Go expression of the address of the given field of a
+// recovered struct type, emitting to f.
+//
+//    &p.field1
+func (fgen *funcGen) fieldRefExpr(ref fieldRef) ast.Expr {
+	base := fgen.liftValue(ref.base)
+	field := &ast.SelectorExpr{X: autoDerefExpr(base), Sel: ast.NewIdent(fieldName(ref.index))}
+	return addrExpr(field)
+}
+
+// isByteArray reports whether the given LLVM IR type is an array of bytes (i.e.
+// [N x i8]).
+func isByteArray(t types.Type) bool {
+	if t, ok := t.(*types.ArrayType); ok {
+		return isByte(t.ElemType)
+	}
+	return false
+}
+
+// isZeroConst reports whether the given LLVM IR constant is a zero value (i.e.
+// zeroinitializer or null).
+func isZeroConst(c constant.Constant) bool {
+	switch c.(type) {
+	case *constant.ZeroInitializer, *constant.Null:
+		return true
+	}
+	return false
+}
+
+// --- [ Integer-typed pointers ] ----------------------------------------------
+
+// ptrIntSource returns the pointer and optional offset of the given integer
+// value if computed from a ptrtoint instruction; e.g.
+//
+//    %1 = ptrtoint %struct.foo* %p to i64
+//    %2 = add i64 %1, 8
+//
+// The token returned indicates whether the offset is added or subtracted.
+func ptrIntSource(v value.Value) (ptr, offset value.Value, op token.Token, ok bool) {
+	switch v := v.(type) {
+	case *ir.InstPtrToInt:
+		return v.From, nil, token.ADD, !isFuncPtr(v.From.Type())
+	case *ir.InstAdd:
+		if x, ok := v.X.(*ir.InstPtrToInt); ok && !isFuncPtr(x.From.Type()) {
+			return x.From, v.Y, token.ADD, true
+		}
+		if y, ok := v.Y.(*ir.InstPtrToInt); ok && !isFuncPtr(y.From.Type()) {
+			return y.From, v.X, token.ADD, true
+		}
+	case *ir.InstSub:
+		if x, ok := v.X.(*ir.InstPtrToInt); ok && !isFuncPtr(x.From.Type()) {
+			return x.From, v.Y, token.SUB, true
+		}
+	}
+	return nil, nil, token.ILLEGAL, false
+}
+
+// liftInstIntToPtr lifts the LLVM IR inttoptr instruction to Go source code,
+// emitting to f. Integers computed from pointers are converted back to
+// pointers within a single Go expression, as required for uintptr arithmetic
+// by the unsafe package.
+//
+//    name = (*T)(unsafe.Pointer(uintptr(unsafe.Pointer(p)) + uintptr(offset)))
+func (fgen *funcGen) liftInstIntToPtr(inst *ir.InstIntToPtr) {
+	ptr, offset, op, ok := ptrIntSource(inst.From)
+	if !ok || isFuncPtr(inst.To) {
+		fgen.liftInstConv(inst, inst.From, inst.To, fgen.gen.inttoptrExpr)
+		return
+	}
+	// Variable name.
+	name := newIdent(inst)
+	p := fgen.liftValue(ptr)
+	if offset == nil {
+		to, err := fgen.gen.convValueExpr(p, ptr, inst.To)
+		if err != nil {
+			fgen.gen.eh(err)
+			return
+		}
+		fgen.cur.List = append(fgen.cur.List, assignStmt(name, to))
+		return
+	}
+	goType, err := fgen.gen.goType(inst.To)
+	if err != nil {
+		fgen.gen.eh(err)
+		return
+	}
+	uintptrType := gotypes.Typ[gotypes.Uintptr]
+	addr := &ast.BinaryExpr{
+		X:  goConvExpr(uintptrType, fgen.gen.unsafePointerExpr(p)),
+		Op: op,
+		Y:  goConvExpr(uintptrType, fgen.liftValue(offset)),
+	}
+	to := goConvExpr(goType, fgen.gen.unsafePointerExpr(addr))
+	// Append assignment statement.
+	fgen.cur.List = append(fgen.cur.List, assignStmt(name, to))
+}
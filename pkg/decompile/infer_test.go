@@ -0,0 +1,341 @@
+package decompile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/ir/types"
+)
+
+func TestInferByteArraySlot(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		// Wanted and unwanted output.
+		want, notWant string
+	}{
+		// Byte buffer accessed as a type of the same size.
+		{
+			name: "same_size",
+			src: `
+define i32 @f() {
+	%1 = alloca [4 x i8]
+	%2 = bitcast [4 x i8]* %1 to i32*
+	store i32 42, i32* %2
+	%3 = load i32, i32* %2
+	ret i32 %3
+}
+`,
+			want: `new(int32)`,
+		},
+		// Byte buffer accessed as a smaller type.
+		{
+			name: "smaller_size",
+			src: `
+define i16 @f() {
+	%1 = alloca [4 x i8]
+	%2 = bitcast [4 x i8]* %1 to i16*
+	store i16 42, i16* %2
+	%3 = load i16, i16* %2
+	ret i16 %3
+}
+`,
+			notWant: `new(int16)`,
+		},
+		// Byte buffer accessed as a byte pointer.
+		{
+			name: "byte_ptr",
+			src: `
+declare i32 @puts(i8*)
+
+define void @f() {
+	%1 = alloca [4 x i8]
+	%2 = bitcast [4 x i8]* %1 to i8*
+	%3 = call i32 @puts(i8* %2)
+	ret void
+}
+`,
+			notWant: `new(int8)`,
+		},
+	}
+	for _, g := range golden {
+		got, errs := decompileString(t, g.src)
+		if len(errs) > 0 {
+			t.Errorf("%q: unable to decompile; %v", g.name, errs)
+			continue
+		}
+		if len(g.want) > 0 && !strings.Contains(got, g.want) {
+			t.Errorf("%q: output mismatch; expected output containing `%s`, got `%s`", g.name, g.want, got)
+		}
+		if len(g.notWant) > 0 && strings.Contains(got, g.notWant) {
+			t.Errorf("%q: output mismatch; expected output not containing `%s`, got `%s`", g.name, g.notWant, got)
+		}
+	}
+}
+
+func TestInferValueType(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Parameter of internal function only ever bitcast to a single pointer
+		// type.
+		{
+			name: "param",
+			src: `
+define internal i32 @get(i8* %p) {
+	%1 = bitcast i8* %p to i32*
+	%2 = load i32, i32* %1
+	ret i32 %2
+}
+
+define i32 @f(i32* %q) {
+	%1 = bitcast i32* %q to i8*
+	%2 = call i32 @get(i8* %1)
+	ret i32 %2
+}
+`,
+			want: []string{
+				`func get(p *int32) int32 {`,
+				`_1 = p`,
+				`_2 = get((*int32)(unsafe.Pointer(_1)))`,
+			},
+		},
+		// Parameters of external functions must match their callers in other
+		// modules.
+		{
+			name: "external param",
+			src: `
+define i32 @get(i8* %p) {
+	%1 = bitcast i8* %p to i32*
+	%2 = load i32, i32* %1
+	ret i32 %2
+}
+`,
+			want: []string{
+				`func Get(p *int8) int32 {`,
+				`_1 = (*int32)(unsafe.Pointer(p))`,
+			},
+		},
+		// Loaded value only ever bitcast to a single pointer type.
+		{
+			name: "load",
+			src: `
+define i32 @f(i8** %pp) {
+	%p = load i8*, i8** %pp
+	%1 = bitcast i8* %p to i32*
+	%2 = load i32, i32* %1
+	ret i32 %2
+}
+`,
+			want: []string{
+				`p = (*int32)(unsafe.Pointer(*pp))`,
+				`_1 = p`,
+				`_2 = *_1`,
+			},
+		},
+		// Call result only ever bitcast to a single pointer type.
+		{
+			name: "call",
+			src: `
+declare i8* @alloc(i64)
+
+define i32* @f() {
+	%1 = call i8* @alloc(i64 4)
+	%2 = bitcast i8* %1 to i32*
+	ret i32* %2
+}
+`,
+			want: []string{
+				`_1 = (*int32)(unsafe.Pointer(alloc(4)))`,
+				`_2 = _1`,
+			},
+		},
+		// Parameter of internal function only ever accessed at constant offsets.
+		{
+			name: "recovered struct",
+			src: `
+define internal i64 @get(i8* %p) {
+	%1 = bitcast i8* %p to i32*
+	%2 = load i32, i32* %1
+	%3 = getelementptr i8, i8* %p, i64 8
+	%4 = bitcast i8* %3 to i64*
+	%5 = load i64, i64* %4
+	%6 = sext i32 %2 to i64
+	%7 = add i64 %5, %6
+	ret i64 %7
+}
+
+define i64 @f(i8* %p) {
+	%1 = call i64 @get(i8* %p)
+	ret i64 %1
+}
+`,
+			want: []string{
+				`type get_recovered struct {`,
+				`func get(p *get_recovered) int64 {`,
+				`_1 = &p.field0`,
+				`_3 = &p.field2`,
+				`_4 = _3`,
+				`_1 = get((*get_recovered)(unsafe.Pointer(p)))`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
+
+func TestRecoverStruct(t *testing.T) {
+	golden := []struct {
+		name    string
+		offsets map[uint64]*types.PointerType
+		want    *types.StructType
+		indices map[uint64]int
+		// Specifies whether recovery is expected to fail.
+		fail bool
+	}{
+		// Adjacent fields.
+		{
+			name:    "adjacent",
+			offsets: map[uint64]*types.PointerType{0: types.NewPointer(types.I32), 4: types.NewPointer(types.I32)},
+			want:    types.NewStruct(types.I32, types.I32),
+			indices: map[uint64]int{0: 0, 4: 1},
+		},
+		// Padding between fields.
+		{
+			name:    "padding",
+			offsets: map[uint64]*types.PointerType{0: types.NewPointer(types.I32), 8: types.NewPointer(types.I64)},
+			want:    types.NewStruct(types.I32, types.NewArray(4, types.I8), types.I64),
+			indices: map[uint64]int{0: 0, 8: 2},
+		},
+		// Leading padding and array field.
+		{
+			name:    "array",
+			offsets: map[uint64]*types.PointerType{4: types.NewPointer(types.NewArray(2, types.I16))},
+			want:    types.NewStruct(types.NewArray(4, types.I8), types.NewArray(2, types.I16)),
+			indices: map[uint64]int{4: 1},
+		},
+		// Overlapping fields.
+		{
+			name:    "overlapping",
+			offsets: map[uint64]*types.PointerType{0: types.NewPointer(types.I64), 4: types.NewPointer(types.I32)},
+			fail:    true,
+		},
+		// Misaligned field.
+		{
+			name:    "misaligned",
+			offsets: map[uint64]*types.PointerType{2: types.NewPointer(types.I32)},
+			fail:    true,
+		},
+		// Field of unknown size.
+		{
+			name:    "unsized",
+			offsets: map[uint64]*types.PointerType{0: types.NewPointer(types.NewStruct(types.I32))},
+			fail:    true,
+		},
+	}
+	for _, g := range golden {
+		got, indices, ok := recoverStruct(g.offsets)
+		if ok == g.fail {
+			t.Errorf("%q: recovery mismatch; expected failure %v, got %v", g.name, g.fail, !ok)
+			continue
+		}
+		if g.fail {
+			continue
+		}
+		if !types.Equal(got, g.want) {
+			t.Errorf("%q: struct type mismatch; expected %v, got %v", g.name, g.want, got)
+		}
+		if !reflect.DeepEqual(indices, g.indices) {
+			t.Errorf("%q: field indices mismatch; expected %v, got %v", g.name, g.indices, indices)
+		}
+	}
+}
+
+func TestPtrIntArith(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Integer offset added to address.
+		{
+			name: "add",
+			src: `
+define i32* @f(i32* %p) {
+	%1 = ptrtoint i32* %p to i64
+	%2 = add i64 %1, 8
+	%3 = inttoptr i64 %2 to i32*
+	ret i32* %3
+}
+`,
+			want: []string{
+				`_1 = int64(uintptr(unsafe.Pointer(p)))`,
+				`_3 = (*int32)(unsafe.Pointer(uintptr(unsafe.Pointer(p)) + uintptr(8)))`,
+			},
+		},
+		// Address added to integer offset.
+		{
+			name: "add commuted",
+			src: `
+define i32* @f(i32* %p, i64 %n) {
+	%1 = ptrtoint i32* %p to i64
+	%2 = add i64 %n, %1
+	%3 = inttoptr i64 %2 to i32*
+	ret i32* %3
+}
+`,
+			want: []string{
+				`_3 = (*int32)(unsafe.Pointer(uintptr(unsafe.Pointer(p)) + uintptr(n)))`,
+			},
+		},
+		// Integer offset subtracted from address.
+		{
+			name: "sub",
+			src: `
+define i32* @f(i32* %p) {
+	%1 = ptrtoint i32* %p to i64
+	%2 = sub i64 %1, 4
+	%3 = inttoptr i64 %2 to i32*
+	ret i32* %3
+}
+`,
+			want: []string{
+				`_3 = (*int32)(unsafe.Pointer(uintptr(unsafe.Pointer(p)) - uintptr(4)))`,
+			},
+		},
+		// Address converted back to pointer of different type.
+		{
+			name: "round trip",
+			src: `
+define i8* @f(i32* %p) {
+	%1 = ptrtoint i32* %p to i64
+	%2 = inttoptr i64 %1 to i8*
+	ret i8* %2
+}
+`,
+			want: []string{
+				`_2 = (*int8)(unsafe.Pointer(p))`,
+			},
+		},
+		// Integer not computed from address.
+		{
+			name: "int",
+			src: `
+define i32* @f(i64 %x) {
+	%1 = inttoptr i64 %x to i32*
+	ret i32* %1
+}
+`,
+			want: []string{
+				`_1 = (*int32)(unsafe.Pointer(uintptr(x)))`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
@@ -0,0 +1,91 @@
+package decompile
+
+import (
+	"bytes"
+	"go/format"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+	"github.com/mewmew/lnp/pkg/cfa/primitive"
+)
+
+func TestLoopSwitch(t *testing.T) {
+	const src = `
+define void @f(i32* %p) {
+head:
+	%x = load i32, i32* %p
+	switch i32 %x, label %body [
+		i32 1, label %exit
+		i32 2, label %exit
+	]
+
+body:
+	%y = load i32, i32* %p
+	switch i32 %y, label %exit [
+		i32 3, label %head
+		i32 4, label %latch
+	]
+
+latch:
+	store i32 0, i32* %p
+	br label %head
+
+exit:
+	ret void
+}
+`
+	prims := []*primitive.Primitive{
+		{
+			Prim:  "multi_exit_loop",
+			Entry: "head",
+			Nodes: map[string]string{
+				"head":   "head",
+				"body_1": "body",
+				"body_2": "latch",
+				"exit":   "exit",
+			},
+			Exit: "exit",
+		},
+	}
+	m, err := asm.Parse("test.ll", strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("unable to parse LLVM IR assembly; %+v", err)
+	}
+	var errs []error
+	eh := func(err error) {
+		errs = append(errs, err)
+	}
+	gen := NewGenerator(eh, m)
+	gen.PkgName = "p"
+	gen.Prims = func(f *ir.Func) ([]*primitive.Primitive, error) {
+		return prims, nil
+	}
+	file := gen.Decompile()[0]
+	buf := &bytes.Buffer{}
+	if err := format.Node(buf, token.NewFileSet(), file); err != nil {
+		t.Fatalf("unable to format Go source code; %+v", err)
+	}
+	got := buf.String()
+	if len(errs) > 0 {
+		t.Fatalf("unable to decompile; %v", errs)
+	}
+	want := []string{
+		// Cases branching to the follow block.
+		"if x == 1 || x == 2 {\n\t\t\tbreak\n\t\t}",
+		// Case branching to the loop header.
+		"if y == 3 {\n\t\t\tcontinue\n\t\t}",
+		// Default case branching to the follow block.
+		"if !(y == 3 || y == 4) {\n\t\t\tbreak\n\t\t}",
+	}
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("output mismatch; expected output containing `%s`, got `%s`", w, got)
+		}
+	}
+	if strings.Contains(got, "goto") {
+		t.Errorf("unexpected goto statement in output `%s`", got)
+	}
+}
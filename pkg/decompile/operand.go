@@ -0,0 +1,266 @@
+package decompile
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/value"
+)
+
+// instOperands returns the operands of the given LLVM IR instruction.
+func instOperands(inst ir.Instruction) []value.Value {
+	switch inst := inst.(type) {
+	// Binary instructions
+	case *ir.InstAdd:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstFAdd:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstSub:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstFSub:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstMul:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstFMul:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstUDiv:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstSDiv:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstFDiv:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstURem:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstSRem:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstFRem:
+		return []value.Value{inst.X, inst.Y}
+	// Bitwise instructions
+	case *ir.InstShl:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstLShr:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstAShr:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstAnd:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstOr:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstXor:
+		return []value.Value{inst.X, inst.Y}
+	// Vector instructions
+	case *ir.InstExtractElement:
+		return []value.Value{inst.X, inst.Index}
+	case *ir.InstInsertElement:
+		return []value.Value{inst.X, inst.Elem, inst.Index}
+	case *ir.InstShuffleVector:
+		return []value.Value{inst.X, inst.Y, inst.Mask}
+	// Aggregate instructions
+	case *ir.InstExtractValue:
+		return []value.Value{inst.X}
+	case *ir.InstInsertValue:
+		return []value.Value{inst.X, inst.Elem}
+	// Memory instructions
+	case *ir.InstAlloca:
+		if inst.NElems != nil {
+			return []value.Value{inst.NElems}
+		}
+		return nil
+	case *ir.InstLoad:
+		return []value.Value{inst.Src}
+	case *ir.InstStore:
+		return []value.Value{inst.Src, inst.Dst}
+	case *ir.InstFence:
+		return nil
+	case *ir.InstCmpXchg:
+		return []value.Value{inst.Ptr, inst.Cmp, inst.New}
+	case *ir.InstAtomicRMW:
+		return []value.Value{inst.Dst, inst.X}
+	case *ir.InstGetElementPtr:
+		return append([]value.Value{inst.Src}, inst.Indices...)
+	// Conversion instructions
+	case *ir.InstTrunc:
+		return []value.Value{inst.From}
+	case *ir.InstZExt:
+		return []value.Value{inst.From}
+	case *ir.InstSExt:
+		return []value.Value{inst.From}
+	case *ir.InstFPTrunc:
+		return []value.Value{inst.From}
+	case *ir.InstFPExt:
+		return []value.Value{inst.From}
+	case *ir.InstFPToUI:
+		return []value.Value{inst.From}
+	case *ir.InstFPToSI:
+		return []value.Value{inst.From}
+	case *ir.InstUIToFP:
+		return []value.Value{inst.From}
+	case *ir.InstSIToFP:
+		return []value.Value{inst.From}
+	case *ir.InstPtrToInt:
+		return []value.Value{inst.From}
+	case *ir.InstIntToPtr:
+		return []value.Value{inst.From}
+	case *ir.InstBitCast:
+		return []value.Value{inst.From}
+	case *ir.InstAddrSpaceCast:
+		return []value.Value{inst.From}
+	// Other instructions
+	case *ir.InstICmp:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstFCmp:
+		return []value.Value{inst.X, inst.Y}
+	case *ir.InstPhi:
+		var ops []value.Value
+		for _, inc := range inst.Incs {
+			ops = append(ops, inc.X)
+		}
+		return ops
+	case *ir.InstSelect:
+		return []value.Value{inst.Cond, inst.X, inst.Y}
+	case *ir.InstCall:
+		return append([]value.Value{inst.Callee}, inst.Args...)
+	case *ir.InstVAArg:
+		return []value.Value{inst.ArgList}
+	case *ir.InstLandingPad:
+		var ops []value.Value
+		for _, clause := range inst.Clauses {
+			ops = append(ops, clause.X)
+		}
+		return ops
+	case *ir.InstCatchPad:
+		return inst.Args
+	case *ir.InstCleanupPad:
+		return inst.Args
+	default:
+		return nil
+	}
+}
+
+// termOperands returns the operands of the given LLVM IR terminator.
+func termOperands(term ir.Terminator) []value.Value {
+	switch term := term.(type) {
+	case *ir.TermRet:
+		if term.X != nil {
+			return []value.Value{term.X}
+		}
+		return nil
+	case *ir.TermCondBr:
+		return []value.Value{term.Cond}
+	case *ir.TermSwitch:
+		ops := []value.Value{term.X}
+		for _, c := range term.Cases {
+			ops = append(ops, c.X)
+		}
+		return ops
+	case *ir.TermIndirectBr:
+		return []value.Value{term.Addr}
+	case *ir.TermInvoke:
+		return append([]value.Value{term.Invokee}, term.Args...)
+	case *ir.TermResume:
+		return []value.Value{term.X}
+	default:
+		return nil
+	}
+}
+
+// constOperands returns the operands of the given LLVM IR constant.
+func constOperands(c constant.Constant) []constant.Constant {
+	switch c := c.(type) {
+	// Complex constants
+	case *constant.Struct:
+		return c.Fields
+	case *constant.Array:
+		return c.Elems
+	case *constant.Vector:
+		return c.Elems
+	// Binary expressions
+	case *constant.ExprAdd:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprFAdd:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprSub:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprFSub:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprMul:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprFMul:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprUDiv:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprSDiv:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprFDiv:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprURem:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprSRem:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprFRem:
+		return []constant.Constant{c.X, c.Y}
+	// Bitwise expressions
+	case *constant.ExprShl:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprLShr:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprAShr:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprAnd:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprOr:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprXor:
+		return []constant.Constant{c.X, c.Y}
+	// Vector expressions
+	case *constant.ExprExtractElement:
+		return []constant.Constant{c.X, c.Index}
+	case *constant.ExprInsertElement:
+		return []constant.Constant{c.X, c.Elem, c.Index}
+	case *constant.ExprShuffleVector:
+		return []constant.Constant{c.X, c.Y, c.Mask}
+	// Aggregate expressions
+	case *constant.ExprExtractValue:
+		return []constant.Constant{c.X}
+	case *constant.ExprInsertValue:
+		return []constant.Constant{c.X, c.Elem}
+	// Memory expressions
+	case *constant.ExprGetElementPtr:
+		return append([]constant.Constant{c.Src}, c.Indices...)
+	// Conversion expressions
+	case *constant.ExprTrunc:
+		return []constant.Constant{c.From}
+	case *constant.ExprZExt:
+		return []constant.Constant{c.From}
+	case *constant.ExprSExt:
+		return []constant.Constant{c.From}
+	case *constant.ExprFPTrunc:
+		return []constant.Constant{c.From}
+	case *constant.ExprFPExt:
+		return []constant.Constant{c.From}
+	case *constant.ExprFPToUI:
+		return []constant.Constant{c.From}
+	case *constant.ExprFPToSI:
+		return []constant.Constant{c.From}
+	case *constant.ExprUIToFP:
+		return []constant.Constant{c.From}
+	case *constant.ExprSIToFP:
+		return []constant.Constant{c.From}
+	case *constant.ExprPtrToInt:
+		return []constant.Constant{c.From}
+	case *constant.ExprIntToPtr:
+		return []constant.Constant{c.From}
+	case *constant.ExprBitCast:
+		return []constant.Constant{c.From}
+	case *constant.ExprAddrSpaceCast:
+		return []constant.Constant{c.From}
+	// Other expressions
+	case *constant.ExprICmp:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprFCmp:
+		return []constant.Constant{c.X, c.Y}
+	case *constant.ExprSelect:
+		return []constant.Constant{c.Cond, c.X, c.Y}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,165 @@
+package decompile
+
+import (
+	"path"
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/enum"
+)
+
+// PackageName returns a valid Go package name for the decompiled LLVM IR
+// modules.
+//
+// Modules defining a main function belong to the main package. Otherwise, the
+// package name is derived from the directory of the source file of the first
+// module, so that modules compiled from the same source directory decompile
+// into the same Go package. For source files without a directory, the base name
+// of the source file is used, and for modules without a source file, the
+// architecture of the target triple is used.
+//
+//    foo/bar-baz.c           ->   foo
+//    bar-baz.c               ->   bar_baz
+//    x86_64-pc-linux-gnu     ->   x86_64
+func PackageName(ms ...*ir.Module) string {
+	for _, m := range ms {
+		for _, f := range m.Funcs {
+			if f.Name() == "main" && len(f.Blocks) > 0 {
+				return "main"
+			}
+		}
+	}
+	for _, m := range ms {
+		if name := modulePkgName(m); len(name) > 0 {
+			return name
+		}
+	}
+	return "p"
+}
+
+// modulePkgName returns a valid Go package name derived from the source file or
+// target triple of the given LLVM IR module, or an empty string if neither is
+// present.
+func modulePkgName(m *ir.Module) string {
+	// Use forward slash as path separator, as the source file name of modules
+	// compiled on Windows use backslash.
+	srcPath := strings.Replace(m.SourceFilename, `\`, "/", -1)
+	if len(srcPath) > 0 {
+		if dir := path.Dir(srcPath); dir != "." && dir != "/" {
+			if name := sanitizePkgName(path.Base(dir)); len(name) > 0 {
+				return name
+			}
+		}
+		base := path.Base(srcPath)
+		if name := sanitizePkgName(strings.TrimSuffix(base, path.Ext(base))); len(name) > 0 {
+			return name
+		}
+	}
+	if len(m.TargetTriple) > 0 {
+		arch := strings.Split(m.TargetTriple, "-")[0]
+		if name := sanitizePkgName(arch); len(name) > 0 {
+			return name
+		}
+	}
+	return ""
+}
+
+// indexPackage indexes the global variables and functions shared between the
+// LLVM IR modules of the Go package, and assigns Go identifiers to the shared
+// global identifiers.
+//
+// Modules refer to global variables and functions of other modules through
+// external declarations. Each shared global identifier is emitted once per Go
+// package, as the definition if present in any module, and as the declaration
+// otherwise.
+//
+// post-condition: gen.pkgNames maps from shared global identifier (without '@'
+// prefix) to Go identifier.
+func (gen *Generator) indexPackage() {
+	for _, m := range gen.ms {
+		gen.enterModule(m)
+		gen.indexTypeBodies()
+		for _, sym := range moduleSymbols(m) {
+			if isLocal(sym.linkage) || !sym.isDef {
+				continue
+			}
+			gen.defined[sym.name] = true
+			if sym.visibility != enum.VisibilityHidden {
+				gen.exported[sym.name] = true
+			}
+		}
+		// Shared global variables and functions are only lifted to Go string
+		// constants and Go functions with multiple results respectively, if
+		// lifted as such in every module.
+		gen.indexStrGlobals()
+		for _, irGlobal := range m.Globals {
+			if _, ok := gen.strGlobals[irGlobal.Name()]; !ok && !isLocal(irGlobal.Linkage) {
+				gen.notStr[irGlobal.Name()] = true
+			}
+		}
+		gen.indexMultiResultFuncs()
+		for _, irFunc := range m.Funcs {
+			if !gen.multiResults[irFunc.Name()] && !isLocal(irFunc.Linkage) {
+				gen.notMultiResult[irFunc.Name()] = true
+			}
+		}
+	}
+	// Assign Go identifiers to shared global identifiers.
+	for _, m := range gen.ms {
+		for _, sym := range moduleSymbols(m) {
+			if isLocal(sym.linkage) {
+				continue
+			}
+			if _, ok := gen.pkgNames[sym.name]; !ok {
+				gen.pkgNames[sym.name] = gen.sharedName(sym.name)
+			}
+		}
+	}
+}
+
+// resolveShared discards the lifting of shared global variables and functions
+// of the current module to Go string constants and Go functions with multiple
+// results respectively, if not lifted as such in every module.
+func (gen *Generator) resolveShared() {
+	for _, irGlobal := range gen.m.Globals {
+		if !isLocal(irGlobal.Linkage) && gen.notStr[irGlobal.Name()] {
+			delete(gen.strGlobals, irGlobal.Name())
+		}
+	}
+	for _, irFunc := range gen.m.Funcs {
+		if !isLocal(irFunc.Linkage) && gen.notMultiResult[irFunc.Name()] {
+			delete(gen.multiResults, irFunc.Name())
+		}
+	}
+}
+
+// isEmittedElsewhere reports whether the declaration or definition of the given
+// global identifier of the current module is emitted to the Go source file of
+// another module. Declarations of global identifiers defined by any module, and
+// repeated definitions (e.g. of linkonce_odr linkage) are emitted elsewhere.
+//
+// post-condition: gen.external records the global identifiers emitted
+// elsewhere.
+func (gen *Generator) isEmittedElsewhere(name string, linkage enum.Linkage, isDef bool) bool {
+	if isLocal(linkage) {
+		return false
+	}
+	if gen.emitted[name] || (!isDef && gen.defined[name]) {
+		gen.external[name] = true
+		return true
+	}
+	gen.emitted[name] = true
+	return false
+}
+
+// sanitizePkgName returns a valid Go package name based on the given name, or
+// an empty string if no letter or digit is present in name. Package names are
+// lower-case by convention.
+func sanitizePkgName(name string) string {
+	name = strings.Trim(strings.ToLower(name), ".-_ ")
+	if len(name) == 0 || name == "main" {
+		// Only modules defining a main function belong to the main package.
+		return ""
+	}
+	return sanitizeName(name)
+}
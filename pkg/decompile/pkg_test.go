@@ -0,0 +1,109 @@
+package decompile
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/llir/llvm/asm"
+	"github.com/llir/llvm/ir"
+)
+
+func TestPackageName(t *testing.T) {
+	golden := []struct {
+		name string
+		srcs []string
+		want string
+	}{
+		// Base name of source file.
+		{
+			name: "base",
+			srcs: []string{`source_filename = "foo.c"`},
+			want: "foo",
+		},
+		// Invalid characters.
+		{
+			name: "invalid",
+			srcs: []string{`source_filename = "foo-bar.c"`},
+			want: "foo_bar",
+		},
+		// Leading digit.
+		{
+			name: "digit",
+			srcs: []string{`source_filename = "1foo.c"`},
+			want: "_1foo",
+		},
+		// Go keyword.
+		{
+			name: "keyword",
+			srcs: []string{`source_filename = "type.c"`},
+			want: "type_",
+		},
+		// Lower case directory of source file.
+		{
+			name: "dir",
+			srcs: []string{`source_filename = "src/Foo-Bar/baz.c"`},
+			want: "foo_bar",
+		},
+		// Windows path separator.
+		{
+			name: "windows",
+			srcs: []string{`source_filename = "src\5Cbaz.c"`},
+			want: "src",
+		},
+		// Source file in root directory.
+		{
+			name: "root",
+			srcs: []string{`source_filename = "/foo.c"`},
+			want: "foo",
+		},
+		// Source file named main without main function falls back to the
+		// architecture of the target triple.
+		{
+			name: "main file",
+			srcs: []string{`
+source_filename = "main.c"
+target triple = "x86_64-pc-linux-gnu"
+`},
+			want: "x86_64",
+		},
+		// Modules defining a main function belong to the main package.
+		{
+			name: "main func",
+			srcs: []string{`
+source_filename = "foo.c"
+
+define i32 @main() {
+	ret i32 0
+}
+`},
+			want: "main",
+		},
+		// First module with source file or target triple.
+		{
+			name: "modules",
+			srcs: []string{``, `source_filename = "bar.c"`},
+			want: "bar",
+		},
+		// Neither source file nor target triple.
+		{
+			name: "default",
+			srcs: []string{``},
+			want: "p",
+		},
+	}
+	for _, g := range golden {
+		var ms []*ir.Module
+		for i, src := range g.srcs {
+			m, err := asm.Parse(fmt.Sprintf("test_%d.ll", i), strings.NewReader(src))
+			if err != nil {
+				t.Fatalf("%q: unable to parse LLVM IR assembly; %+v", g.name, err)
+			}
+			ms = append(ms, m)
+		}
+		got := PackageName(ms...)
+		if got != g.want {
+			t.Errorf("%q: package name mismatch; expected %q, got %q", g.name, g.want, got)
+		}
+	}
+}
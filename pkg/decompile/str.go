@@ -0,0 +1,342 @@
+package decompile
+
+import (
+	"bytes"
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"strconv"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// indexStrGlobals indexes the LLVM IR global variables holding C strings, which
+// are lifted to Go string constants.
+//
+// A global variable holds a C string if it is immutable, its initializer is a
+// NUL-terminated character array without embedded NUL characters, and every use
+// of the global variable is a pointer to its first element; e.g.
+//
+//    getelementptr ([6 x i8], [6 x i8]* @.str, i64 0, i64 0)
+//
+// post-condition: gen.strGlobals maps from global identifier (without '@'
+// prefix) to the contents (without NUL terminator) of C string global
+// variables.
+func (gen *Generator) indexStrGlobals() {
+	cands := make(map[*ir.Global]string)
+	for _, irGlobal := range gen.m.Globals {
+		if s, ok := cString(irGlobal); ok {
+			cands[irGlobal] = s
+		}
+	}
+	if len(cands) == 0 {
+		return
+	}
+	// Discard candidates used other than through pointers to their first
+	// element.
+	for _, irGlobal := range gen.m.Globals {
+		if irGlobal.Init != nil {
+			checkStrUses(cands, irGlobal.Init)
+		}
+	}
+	for _, irAlias := range gen.m.Aliases {
+		checkStrUses(cands, irAlias.Aliasee)
+	}
+	for _, irFunc := range gen.m.Funcs {
+		for _, block := range irFunc.Blocks {
+			for _, inst := range block.Insts {
+				if v, ok := inst.(value.Value); ok {
+					if _, ok := strGlobalRef(v); ok {
+						continue
+					}
+				}
+				for _, op := range instOperands(inst) {
+					checkStrUses(cands, op)
+				}
+			}
+			for _, op := range termOperands(block.Term) {
+				checkStrUses(cands, op)
+			}
+		}
+	}
+	for irGlobal, s := range cands {
+		gen.strGlobals[irGlobal.Name()] = s
+	}
+}
+
+// checkStrUses removes the C string global variable candidates from cands
+// which are used by v other than through pointers to their first element.
+func checkStrUses(cands map[*ir.Global]string, v value.Value) {
+	if _, ok := strGlobalRef(v); ok {
+		return
+	}
+	switch v := v.(type) {
+	case *ir.Global:
+		delete(cands, v)
+	case constant.Constant:
+		for _, op := range constOperands(v) {
+			checkStrUses(cands, op)
+		}
+	}
+}
+
+// cString returns the contents (without NUL terminator) of the C string held
+// by the given LLVM IR global variable. The boolean return value indicates
+// success.
+func cString(irGlobal *ir.Global) (string, bool) {
+	if !irGlobal.Immutable {
+		return "", false
+	}
+	init, ok := irGlobal.Init.(*constant.CharArray)
+	if !ok {
+		return "", false
+	}
+	if i := bytes.IndexByte(init.X, 0); i == -1 || i != len(init.X)-1 {
+		return "", false
+	}
+	return string(init.X[:len(init.X)-1]), true
+}
+
+// strGlobalRef returns the LLVM IR global variable of character array type
+// which v points to the first element of. The boolean return value indicates
+// success.
+//
+// Pointers to the first element of character arrays have the following forms.
+//
+//    getelementptr ([6 x i8], [6 x i8]* @.str, i64 0, i64 0)
+//    bitcast ([6 x i8]* @.str to i8*)
+func strGlobalRef(v value.Value) (*ir.Global, bool) {
+	var src value.Value
+	switch v := v.(type) {
+	case *constant.ExprGetElementPtr:
+		for _, index := range v.Indices {
+			if !isZeroIndex(index) {
+				return nil, false
+			}
+		}
+		if len(v.Indices) != 2 {
+			return nil, false
+		}
+		src = v.Src
+	case *ir.InstGetElementPtr:
+		for _, index := range v.Indices {
+			if !isZeroIndex(index) {
+				return nil, false
+			}
+		}
+		if len(v.Indices) != 2 {
+			return nil, false
+		}
+		src = v.Src
+	case *constant.ExprBitCast:
+		if !isBytePtr(v.To) {
+			return nil, false
+		}
+		src = v.From
+	case *ir.InstBitCast:
+		if !isBytePtr(v.To) {
+			return nil, false
+		}
+		src = v.From
+	default:
+		return nil, false
+	}
+	irGlobal, ok := src.(*ir.Global)
+	if !ok {
+		return nil, false
+	}
+	t, ok := irGlobal.ContentType.(*types.ArrayType)
+	if !ok || !isByte(t.ElemType) {
+		return nil, false
+	}
+	return irGlobal, true
+}
+
+// strConst returns the name of the Go string constant of the C string global
+// variable which v points to the first element of. The boolean return value
+// indicates success.
+func (gen *Generator) strConst(v value.Value) (string, bool) {
+	irGlobal, ok := strGlobalRef(v)
+	if !ok {
+		return "", false
+	}
+	name := irGlobal.Name()
+	if _, ok := gen.strGlobals[name]; !ok {
+		return "", false
+	}
+	return name, true
+}
+
+// strLit returns the AST Go string literal with the given contents.
+func strLit(s string) *ast.BasicLit {
+	return &ast.BasicLit{
+		Kind:  token.STRING,
+		Value: strconv.Quote(s),
+	}
+}
+
+// strPtrExpr returns the Go expression of a pointer to the first character of
+// a NUL-terminated copy of the Go string constant with the given name.
+//
+//    (*int8)(unsafe.Pointer(&[]byte(name + "\x00")[0]))
+func (gen *Generator) strPtrExpr(name string) ast.Expr {
+	s := &ast.BinaryExpr{
+		X:  gen.globalIdent(name),
+		Op: token.ADD,
+		Y:  strLit("\x00"),
+	}
+	buf := goConvExpr(gotypes.NewSlice(gotypes.Typ[gotypes.Byte]), s)
+	first := &ast.UnaryExpr{
+		Op: token.AND,
+		X: &ast.IndexExpr{
+			X:     buf,
+			Index: goIntLit(0),
+		},
+	}
+	ptrType := gotypes.NewPointer(gotypes.Typ[gotypes.Int8])
+	return goConvExpr(ptrType, gen.unsafePointerExpr(first))
+}
+
+// strParams maps from the names of known C library functions to the indices of
+// their C string parameters, which are only read by the function and thus
+// lifted to Go strings.
+var strParams = map[string][]int{
+	"atof":    {0},
+	"atoi":    {0},
+	"atol":    {0},
+	"fopen":   {0, 1},
+	"fprintf": {1},
+	"fputs":   {0},
+	"getenv":  {0},
+	"perror":  {0},
+	"printf":  {0},
+	"puts":    {0},
+	"remove":  {0},
+	"sprintf": {1},
+	"strcmp":  {0, 1},
+	"strlen":  {0},
+	"strncmp": {0, 1},
+	"system":  {0},
+	// Functions taking variable argument lists.
+	"vfprintf": {1},
+	"vprintf":  {0},
+	"vsprintf": {1},
+}
+
+// isStrParam reports whether the parameter of the given index of the LLVM IR
+// function is a C string parameter of a known C library function, which is
+// lifted to a Go string.
+func isStrParam(f *ir.Func, i int) bool {
+	if len(f.Blocks) > 0 || i >= len(f.Params) || !isBytePtr(f.Params[i].Type()) {
+		// Only external function declarations are C library functions.
+		return false
+	}
+	for _, j := range strParams[f.Name()] {
+		if i == j {
+			return true
+		}
+	}
+	return false
+}
+
+// goStringName is the name of the C string conversion helper function.
+const goStringName = "goString"
+
+// addGoStringHelper appends the C string conversion helper function to the Go
+// source file, if not already present.
+//
+//    // goString returns the Go string of the NUL-terminated C string p.
+//    func goString(p *int8) string {
+//       var buf []byte
+//       for ; *p != 0; p = (*int8)(unsafe.Pointer(uintptr(unsafe.Pointer(p)) + 1)) {
+//          buf = append(buf, byte(*p))
+//       }
+//       return string(buf)
+//    }
+func (gen *Generator) addGoStringHelper() {
+	if gen.helpers[goStringName] {
+		return
+	}
+	gen.helpers[goStringName] = true
+	p := ast.NewIdent("p")
+	buf := ast.NewIdent("buf")
+	ptrType := gotypes.NewPointer(gotypes.Typ[gotypes.Int8])
+	// uintptr(unsafe.Pointer(p)) + 1
+	next := &ast.BinaryExpr{
+		X:  callExpr(ast.NewIdent("uintptr"), gen.unsafePointerExpr(p)),
+		Op: token.ADD,
+		Y:  goIntLit(1),
+	}
+	forStmt := &ast.ForStmt{
+		Cond: &ast.BinaryExpr{
+			X:  &ast.StarExpr{X: p},
+			Op: token.NEQ,
+			Y:  goIntLit(0),
+		},
+		Post: assignStmt(p, goConvExpr(ptrType, gen.unsafePointerExpr(next))),
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				assignStmt(buf, callExpr(ast.NewIdent("append"), buf, callExpr(ast.NewIdent("byte"), &ast.StarExpr{X: p}))),
+			},
+		},
+	}
+	bufDecl := &ast.DeclStmt{
+		Decl: &ast.GenDecl{
+			Tok: token.VAR,
+			Specs: []ast.Spec{
+				&ast.ValueSpec{
+					Names: []*ast.Ident{buf},
+					Type:  &ast.ArrayType{Elt: ast.NewIdent("byte")},
+				},
+			},
+		},
+	}
+	funcDecl := &ast.FuncDecl{
+		Name: ast.NewIdent(goStringName),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{{
+					Names: []*ast.Ident{p},
+					Type:  goTypeExpr(ptrType),
+				}},
+			},
+			Results: &ast.FieldList{
+				List: []*ast.Field{{Type: ast.NewIdent("string")}},
+			},
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				bufDecl,
+				forStmt,
+				&ast.ReturnStmt{Results: []ast.Expr{callExpr(ast.NewIdent("string"), buf)}},
+			},
+		},
+	}
+	gen.file.Decls = append(gen.file.Decls, funcDecl)
+}
+
+// isZeroIndex reports whether the given index is the constant zero.
+func isZeroIndex(index value.Value) bool {
+	i, ok := constIndex(index)
+	return ok && i == 0
+}
+
+// isByte reports whether the given LLVM IR type is an 8-bit integer type.
+func isByte(t types.Type) bool {
+	if t, ok := t.(*types.IntType); ok {
+		return t.BitSize == 8
+	}
+	return false
+}
+
+// isBytePtr reports whether the given LLVM IR type is a pointer to an 8-bit
+// integer type.
+func isBytePtr(t types.Type) bool {
+	if t, ok := t.(*types.PointerType); ok {
+		return isByte(t.ElemType)
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package decompile
+
+import "testing"
+
+func TestStrConst(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// C string passed to a C library function.
+		{
+			name: "sink",
+			src: `
+@.str = private unnamed_addr constant [6 x i8] c"hello\00"
+
+declare i32 @puts(i8*)
+
+define void @f() {
+	%1 = call i32 @puts(i8* getelementptr ([6 x i8], [6 x i8]* @.str, i64 0, i64 0))
+	ret void
+}
+`,
+			want: []string{
+				`func puts(_0 string) int32`,
+				`puts(str)`,
+			},
+		},
+		// C string passed to a function which is not a known C library function.
+		{
+			name: "call",
+			src: `
+@.str = private unnamed_addr constant [6 x i8] c"hello\00"
+
+declare void @g(i8*)
+
+define void @f() {
+	call void @g(i8* getelementptr ([6 x i8], [6 x i8]* @.str, i64 0, i64 0))
+	ret void
+}
+`,
+			want: []string{
+				`g((*int8)(unsafe.Pointer(&[]byte(str + "\x00")[0])))`,
+			},
+		},
+		// C string stored in memory.
+		{
+			name: "store",
+			src: `
+@.str = private unnamed_addr constant [6 x i8] c"hello\00"
+@p = global i8* null
+
+define void @f() {
+	store i8* getelementptr ([6 x i8], [6 x i8]* @.str, i64 0, i64 0), i8** @p
+	ret void
+}
+`,
+			want: []string{
+				`(*int8)(unsafe.Pointer(&[]byte(str + "\x00")[0]))`,
+			},
+		},
+		// Non-constant C string passed to a C library function.
+		{
+			name: "non-const",
+			src: `
+declare i32 @puts(i8*)
+
+define void @f(i8* %s) {
+	%1 = call i32 @puts(i8* %s)
+	ret void
+}
+`,
+			want: []string{
+				`puts(goString(s))`,
+				`func goString(p *int8) string`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
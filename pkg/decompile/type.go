@@ -17,12 +17,60 @@ import (
 func (gen *Generator) indexTypeDefs() {
 	for _, irTypeDef := range gen.m.TypeDefs {
 		name := irTypeDef.Name()
-		typeName := gotypes.NewTypeName(0, nil, name, nil)
+		def := irTypeDef.LLString()
+		// Opaque type definitions are unified with the type definition of the
+		// same type name defined in another module, which is emitted by that
+		// module.
+		external := false
+		if body, ok := gen.typeBodies[name]; ok && isOpaque(irTypeDef) {
+			def = body
+			external = true
+		}
+		// Deduplicate type definitions shared between modules.
+		if prev, ok := gen.pkgTypes[name]; ok && prev.def == def {
+			gen.typeDefs[name] = prev.t
+			if prev.emitted || external {
+				gen.sharedTypes[name] = true
+			} else {
+				prev.emitted = true
+			}
+			continue
+		}
+		typeName := gotypes.NewTypeName(0, nil, gen.uniqueName(sanitizeName(name)), nil)
 		t := gotypes.NewNamed(typeName, nil, nil)
 		gen.typeDefs[name] = t
+		if external {
+			// Placeholder until the body is translated by the defining module.
+			t.SetUnderlying(gotypes.NewStruct(nil, nil))
+			gen.sharedTypes[name] = true
+		}
+		if _, ok := gen.pkgTypes[name]; !ok {
+			gen.pkgTypes[name] = &pkgType{def: def, t: t, emitted: !external}
+		}
+	}
+}
+
+// indexTypeBodies records the definitions of the non-opaque type definitions
+// of the current LLVM IR module, for unifying opaque type definitions with
+// their body.
+func (gen *Generator) indexTypeBodies() {
+	for _, irTypeDef := range gen.m.TypeDefs {
+		if isOpaque(irTypeDef) {
+			continue
+		}
+		name := irTypeDef.Name()
+		if _, ok := gen.typeBodies[name]; !ok {
+			gen.typeBodies[name] = irTypeDef.LLString()
+		}
 	}
 }
 
+// isOpaque reports whether the given LLVM IR type is an opaque struct type.
+func isOpaque(irType types.Type) bool {
+	t, ok := irType.(*types.StructType)
+	return ok && t.Opaque
+}
+
 // === [ Translate ] ===========================================================
 
 // translateTypeDefs translates the type definitions of the LLVM IR module to
@@ -31,6 +79,10 @@ func (gen *Generator) translateTypeDefs() {
 	// Translate LLVM IR type definitions to Go.
 	for _, irTypeDef := range gen.m.TypeDefs {
 		typeName := irTypeDef.Name()
+		if gen.sharedTypes[typeName] {
+			// Skip type definitions emitted by a previous module.
+			continue
+		}
 		t, ok := gen.typeDefs[typeName]
 		if !ok {
 			gen.Errorf("unable to locate type definition with type name %q", typeName)
@@ -49,12 +101,16 @@ func (gen *Generator) translateTypeDefs() {
 	// Append Go type definitions to Go source file.
 	for _, irTypeDef := range gen.m.TypeDefs {
 		typeName := irTypeDef.Name()
+		if gen.sharedTypes[typeName] {
+			// Skip type definitions emitted by a previous module.
+			continue
+		}
 		t, ok := gen.typeDefs[typeName]
 		if !ok {
 			gen.Errorf("unable to locate type definition with type name %q", typeName)
 			continue
 		}
-		typeDecl := newTypeDef(typeName, t.Underlying())
+		typeDecl := newTypeDef(t.Obj().Name(), t.Underlying())
 		gen.file.Decls = append(gen.file.Decls, typeDecl)
 	}
 }
@@ -90,8 +146,13 @@ func (gen *Generator) goUnderlyingType(irType types.Type) (gotypes.Type, error)
 		return gen.goFloatType(irType), nil
 	//case *types.MMXType:
 	case *types.PointerType:
+		if funcType, ok := irType.ElemType.(*types.FuncType); ok {
+			// Go function values refer to the function.
+			return gen.goFuncType(funcType)
+		}
 		return gen.goPointerType(irType)
-	//case *types.VectorType:
+	case *types.VectorType:
+		return gen.goVectorType(irType)
 	//case *types.LabelType:
 	//case *types.TokenType:
 	//case *types.MetadataType:
@@ -142,43 +203,84 @@ func (gen *Generator) goFuncType(irType *types.FuncType) (*gotypes.Signature, er
 
 // goIntType returns the Go integer type corresponding to the given LLVM IR
 // integer type.
-func (gen *Generator) goIntType(irType *types.IntType) *gotypes.Basic {
+//
+// Integer types of non-native bit sizes (e.g. i24) are represented by the next
+// wider Go integer type, holding the sign-extended value. Integer types wider
+// than 64 bits are represented by arbitrary precision integers (*big.Int).
+func (gen *Generator) goIntType(irType *types.IntType) gotypes.Type {
 	// TODO: figure out how to distinguish signed vs. unsigned integer types.
-	// TODO: figure out how to support other bit sizes.
-	switch irType.BitSize {
-	case 1:
+	switch {
+	case irType.BitSize == 1:
 		return gotypes.Typ[gotypes.Bool]
-	case 8:
+	case irType.BitSize <= 8:
 		return gotypes.Typ[gotypes.Int8]
-	case 16:
+	case irType.BitSize <= 16:
 		return gotypes.Typ[gotypes.Int16]
-	case 32:
+	case irType.BitSize <= 32:
 		return gotypes.Typ[gotypes.Int32]
-	case 64:
+	case irType.BitSize <= 64:
 		return gotypes.Typ[gotypes.Int64]
 	default:
-		panic(fmt.Errorf("support for integer type bit size %d not yet implemented", irType.BitSize))
+		return gen.bigType("Int")
+	}
+}
+
+// goUintType returns the unsigned Go integer type with the same bit size as
+// the Go integer type of the given LLVM IR integer type. It is used to
+// reinterpret the bits of integer values in unsigned operations.
+func (gen *Generator) goUintType(irType *types.IntType) *gotypes.Basic {
+	switch {
+	case irType.BitSize <= 8:
+		return gotypes.Typ[gotypes.Uint8]
+	case irType.BitSize <= 16:
+		return gotypes.Typ[gotypes.Uint16]
+	case irType.BitSize <= 32:
+		return gotypes.Typ[gotypes.Uint32]
+	case irType.BitSize <= 64:
+		return gotypes.Typ[gotypes.Uint64]
+	default:
+		panic(fmt.Errorf("support for unsigned integer type bit size %d not yet implemented", irType.BitSize))
 	}
 }
 
 // goFloatType returns the Go floating-point type corresponding to the given
 // LLVM IR floating-point type.
-func (gen *Generator) goFloatType(irType *types.FloatType) *gotypes.Basic {
-	// TODO: figure out how to support remaining float types.
+//
+// Extended precision floating-point types (x86_fp80, fp128 and ppc_fp128) are
+// represented by float64, with a warning about lost precision, unless
+// gen.BigFloat is set, in which case they are represented by arbitrary
+// precision floating-point numbers (*big.Float).
+func (gen *Generator) goFloatType(irType *types.FloatType) gotypes.Type {
 	switch irType.Kind {
-	//case types.FloatKindHalf:
+	case types.FloatKindHalf:
+		return gotypes.Typ[gotypes.Float32]
 	case types.FloatKindFloat:
 		return gotypes.Typ[gotypes.Float32]
 	case types.FloatKindDouble:
 		return gotypes.Typ[gotypes.Float64]
-	//case types.FloatKindFP128:
-	//case types.FloatKindX86_FP80:
-	//case types.FloatKindPPC_FP128:
+	case types.FloatKindX86_FP80, types.FloatKindFP128, types.FloatKindPPC_FP128:
+		if gen.BigFloat {
+			return gen.bigType("Float")
+		}
+		if !gen.warned[irType.Kind] {
+			warn.Printf("representing floating-point type %v as float64; precision may be lost", irType)
+			gen.warned[irType.Kind] = true
+		}
+		return gotypes.Typ[gotypes.Float64]
 	default:
 		panic(fmt.Errorf("support for floating-point type kind %v not yet implemented", irType.Kind))
 	}
 }
 
+// bigType returns the pointer type to the named type of the math/big package
+// (e.g. *big.Int).
+func (gen *Generator) bigType(name string) *gotypes.Pointer {
+	gen.imports["math/big"] = true
+	pkg := gotypes.NewPackage("math/big", "big")
+	obj := gotypes.NewTypeName(0, pkg, name, nil)
+	return gotypes.NewPointer(gotypes.NewNamed(obj, gotypes.NewStruct(nil, nil), nil))
+}
+
 // goPointerType returns the Go pointer type corresponding to the given LLVM IR
 // pointer type.
 func (gen *Generator) goPointerType(irType *types.PointerType) (*gotypes.Pointer, error) {
@@ -199,17 +301,26 @@ func (gen *Generator) goArrayType(irType *types.ArrayType) (*gotypes.Array, erro
 	return gotypes.NewArray(elem, int64(irType.Len)), nil
 }
 
+// goVectorType returns the Go array type corresponding to the given LLVM IR
+// vector type.
+func (gen *Generator) goVectorType(irType *types.VectorType) (*gotypes.Array, error) {
+	elem, err := gen.goType(irType.ElemType)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return gotypes.NewArray(elem, int64(irType.Len)), nil
+}
+
 // goStructType returns the Go struct type corresponding to the given LLVM IR
 // struct type.
 func (gen *Generator) goStructType(irType *types.StructType) (*gotypes.Struct, error) {
 	var fields []*gotypes.Var
 	for i, irField := range irType.Fields {
-		fieldName := fmt.Sprintf("field%d", i)
 		fieldType, err := gen.goType(irField)
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
-		field := gotypes.NewVar(0, nil, fieldName, fieldType)
+		field := gotypes.NewVar(0, nil, fieldName(i), fieldType)
 		fields = append(fields, field)
 	}
 	return gotypes.NewStruct(fields, nil), nil
@@ -233,6 +344,8 @@ func goTypeExpr(goType gotypes.Type) ast.Expr {
 		return goPointerTypeExpr(goType)
 	case *gotypes.Signature:
 		return goFuncTypeExpr(goType)
+	case *gotypes.Slice:
+		return goSliceTypeExpr(goType)
 	case *gotypes.Struct:
 		return goStructTypeExpr(goType)
 	default:
@@ -327,8 +440,16 @@ func goInterfaceTypeExpr(goType *gotypes.Interface) *ast.InterfaceType {
 
 // goNamedTypeExpr returns the AST Go type expression corresponding to the given
 // Go named type.
-func goNamedTypeExpr(goType *gotypes.Named) *ast.Ident {
-	return ast.NewIdent(goType.Obj().Name())
+func goNamedTypeExpr(goType *gotypes.Named) ast.Expr {
+	obj := goType.Obj()
+	if pkg := obj.Pkg(); pkg != nil {
+		// Qualified identifier of imported type.
+		return &ast.SelectorExpr{
+			X:   ast.NewIdent(pkg.Name()),
+			Sel: ast.NewIdent(obj.Name()),
+		}
+	}
+	return ast.NewIdent(obj.Name())
 }
 
 // goPointerTypeExpr returns the AST Go type expression corresponding to the
@@ -338,6 +459,15 @@ func goPointerTypeExpr(goType *gotypes.Pointer) *ast.StarExpr {
 	return &ast.StarExpr{X: elem}
 }
 
+// goSliceTypeExpr returns the AST Go type expression corresponding to the given
+// Go slice type.
+func goSliceTypeExpr(goType *gotypes.Slice) *ast.ArrayType {
+	elem := goTypeExpr(goType.Elem())
+	return &ast.ArrayType{
+		Elt: elem,
+	}
+}
+
 // goStructTypeExpr returns the AST Go type expression corresponding to the
 // given Go struct type.
 func goStructTypeExpr(goType *gotypes.Struct) *ast.StructType {
@@ -361,6 +491,11 @@ func goStructTypeExpr(goType *gotypes.Struct) *ast.StructType {
 
 // ### [ Helper functions ] ####################################################
 
+// fieldName returns the Go field name of the i:th field of a struct type.
+func fieldName(i int) string {
+	return fmt.Sprintf("field%d", i)
+}
+
 // newTypeDef returns a new Go type definition based on the given type name and
 // Go type.
 func newTypeDef(name string, goType gotypes.Type) *ast.GenDecl {
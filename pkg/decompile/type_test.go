@@ -0,0 +1,47 @@
+package decompile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOpaqueTypeDef(t *testing.T) {
+	const opaque = `
+%T = type opaque
+
+declare void @g(%T*)
+`
+	const defined = `
+%T = type { i32 }
+
+define void @g(%T* %t) {
+	ret void
+}
+`
+	golden := []struct {
+		name string
+		srcs []string
+	}{
+		{name: "opaque_first", srcs: []string{opaque, defined}},
+		{name: "defined_first", srcs: []string{defined, opaque}},
+	}
+	for _, g := range golden {
+		outs, errs := decompileStrings(t, g.srcs...)
+		if len(errs) > 0 {
+			t.Errorf("%q: unable to decompile; %v", g.name, errs)
+			continue
+		}
+		all := strings.Join(outs, "\n")
+		// The opaque type definition is unified with the defined type, and
+		// emitted once.
+		if n := strings.Count(all, "type T struct"); n != 1 {
+			t.Errorf("%q: expected one type definition of T, got %d; output `%s`", g.name, n, all)
+		}
+		if !strings.Contains(all, "field0 int32") {
+			t.Errorf("%q: body of type definition T not emitted; output `%s`", g.name, all)
+		}
+		if strings.Contains(all, "T_1") {
+			t.Errorf("%q: opaque type definition assigned distinct Go identifier; output `%s`", g.name, all)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package decompile
+
+import (
+	"go/ast"
+	gotypes "go/types"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// vaParamName is the name of the variadic parameter of Go functions.
+const vaParamName = "args"
+
+// Names of LLVM IR intrinsic functions operating on variable argument lists.
+const (
+	vaStart = "llvm.va_start"
+	vaEnd   = "llvm.va_end"
+	vaCopy  = "llvm.va_copy"
+)
+
+// vaListParams maps from the names of known C library functions taking
+// variable argument lists to the index of their va_list parameter. The va_list
+// parameter is lifted to a variadic parameter, and the variable argument list is
+// passed by spreading the slice of remaining variadic arguments.
+//
+//    vprintf(format, ap_va...)
+var vaListParams = map[string]int{
+	"vfprintf":  2,
+	"vprintf":   1,
+	"vsnprintf": 3,
+	"vsprintf":  2,
+}
+
+// vaListParam returns the index of the va_list parameter of the given callee,
+// if the callee is a known C library function taking a variable argument list.
+// The boolean return value indicates success.
+func vaListParam(callee value.Value) (int, bool) {
+	f, ok := callee.(*ir.Func)
+	if !ok || len(f.Blocks) > 0 {
+		// Only external function declarations are C library functions.
+		return 0, false
+	}
+	i, ok := vaListParams[f.Name()]
+	if !ok || i != len(f.Params)-1 || f.Sig.Variadic {
+		return 0, false
+	}
+	return i, true
+}
+
+// isVAIntrinsic reports whether the given function name is an LLVM IR intrinsic
+// function operating on variable argument lists. Such intrinsics are lifted to
+// operations on the variadic parameter slice, and have no Go declaration.
+func isVAIntrinsic(name string) bool {
+	switch name {
+	case vaStart, vaEnd, vaCopy:
+		return true
+	}
+	return false
+}
+
+// goVariadicParam returns the variadic Go parameter of the given variadic LLVM
+// IR function. The variadic parameter has a typed element type if every va_arg
+// instruction of the function reads arguments of the same type, and is of type
+// ...interface{} otherwise.
+func (gen *Generator) goVariadicParam(irFunc *ir.Func) (*gotypes.Var, error) {
+	var goElemType gotypes.Type = gotypes.NewInterface(nil, nil)
+	if t, ok := vaArgType(irFunc); ok {
+		var err error
+		goElemType, err = gen.goType(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return gotypes.NewVar(0, nil, vaParamName, gotypes.NewSlice(goElemType)), nil
+}
+
+// vaArgType returns the type of the arguments read by the va_arg instructions
+// of the given LLVM IR function. The boolean return value indicates whether
+// every va_arg instruction reads arguments of the same type.
+func vaArgType(irFunc *ir.Func) (types.Type, bool) {
+	var t types.Type
+	for _, block := range irFunc.Blocks {
+		for _, inst := range block.Insts {
+			inst, ok := inst.(*ir.InstVAArg)
+			if !ok {
+				continue
+			}
+			if t != nil && !types.Equal(t, inst.ArgType) {
+				return nil, false
+			}
+			t = inst.ArgType
+		}
+	}
+	return t, t != nil
+}
+
+// indexVALists indexes the instructions of the given LLVM IR function which
+// derive pointers to variable argument lists. Variable argument lists are
+// lifted to slices of the variadic parameter, and the derived pointers are
+// therefore lifted to nothing.
+//
+// post-condition: fgen.vaDerived records the instructions deriving pointers to
+// variable argument lists, and fgen.vaLists records their root values.
+func (fgen *funcGen) indexVALists(irFunc *ir.Func) {
+	_, fgen.vaTyped = vaArgType(irFunc)
+	for _, block := range irFunc.Blocks {
+		for _, inst := range block.Insts {
+			switch inst := inst.(type) {
+			case *ir.InstCall:
+				if i, ok := vaListParam(inst.Callee); ok {
+					fgen.vaLists[fgen.vaListRoot(inst.Args[i])] = true
+					continue
+				}
+				callee, ok := inst.Callee.(*ir.Func)
+				if !ok || !isVAIntrinsic(callee.Name()) {
+					continue
+				}
+				for _, arg := range inst.Args {
+					fgen.vaLists[fgen.vaListRoot(arg)] = true
+				}
+			case *ir.InstVAArg:
+				fgen.vaLists[fgen.vaListRoot(inst.ArgList)] = true
+			}
+		}
+	}
+}
+
+// vaListRoot returns the root value (e.g. alloca instruction) of the given
+// pointer to a variable argument list, recording the instructions deriving the
+// pointer from its root value.
+//
+//    %ap = alloca [1 x %struct.__va_list_tag]
+//    %1 = getelementptr [1 x %struct.__va_list_tag], [1 x %struct.__va_list_tag]* %ap, i64 0, i64 0
+//    %2 = bitcast %struct.__va_list_tag* %1 to i8*
+func (fgen *funcGen) vaListRoot(v value.Value) value.Value {
+	root, derived := vaRoot(v)
+	for _, inst := range derived {
+		fgen.vaDerived[inst] = true
+	}
+	return root
+}
+
+// isVAList reports whether v is a pointer to a variable argument list.
+func (fgen *funcGen) isVAList(v value.Value) bool {
+	root, _ := vaRoot(v)
+	return fgen.vaLists[root]
+}
+
+// vaRoot returns the root value of the given pointer, and the bitcast and
+// zero-index getelementptr instructions deriving the pointer from its root
+// value.
+func vaRoot(v value.Value) (value.Value, []ir.Instruction) {
+	var derived []ir.Instruction
+	for {
+		switch inst := v.(type) {
+		case *ir.InstBitCast:
+			derived = append(derived, inst)
+			v = inst.From
+			continue
+		case *ir.InstGetElementPtr:
+			zero := true
+			for _, index := range inst.Indices {
+				if !isZeroIndex(index) {
+					zero = false
+				}
+			}
+			if zero {
+				derived = append(derived, inst)
+				v = inst.Src
+				continue
+			}
+		}
+		return v, derived
+	}
+}
+
+// vaListIdent returns the Go identifier of the slice of remaining variadic
+// arguments of the given pointer to a variable argument list.
+func (fgen *funcGen) vaListIdent(v value.Value) *ast.Ident {
+	if root, ok := fgen.vaListRoot(v).(namedValue); ok {
+		return ast.NewIdent(newName(root) + "_va")
+	}
+	return ast.NewIdent("va")
+}
+
+// liftVAIntrinsic lifts the call to the LLVM IR intrinsic function operating
+// on variable argument lists to Go source code, emitting to f.
+//
+//    ap_va = args    // llvm.va_start(ap)
+//    ap_va = nil     // llvm.va_end(ap)
+//    dst_va = src_va // llvm.va_copy(dst, src)
+func (fgen *funcGen) liftVAIntrinsic(inst *ir.InstCall, name string) {
+	var stmt ast.Stmt
+	switch name {
+	case vaStart:
+		stmt = assignStmt(fgen.vaListIdent(inst.Args[0]), ast.NewIdent(vaParamName))
+	case vaEnd:
+		stmt = assignStmt(fgen.vaListIdent(inst.Args[0]), ast.NewIdent("nil"))
+	case vaCopy:
+		stmt = assignStmt(fgen.vaListIdent(inst.Args[0]), fgen.vaListIdent(inst.Args[1]))
+	}
+	fgen.cur.List = append(fgen.cur.List, stmt)
+}
+
+// liftInstVAArg lifts the LLVM IR va_arg instruction to Go source code,
+// emitting to f.
+//
+//    x = ap_va[0].(T)
+//    ap_va = ap_va[1:]
+func (fgen *funcGen) liftInstVAArg(inst *ir.InstVAArg) {
+	// Variable name.
+	name := newIdent(inst)
+	// Remaining variadic arguments.
+	va := fgen.vaListIdent(inst.ArgList)
+	var arg ast.Expr = &ast.IndexExpr{
+		X:     va,
+		Index: goIntLit(0),
+	}
+	if !fgen.vaTyped {
+		goType, err := fgen.gen.goType(inst.ArgType)
+		if err != nil {
+			fgen.gen.eh(err)
+			return
+		}
+		arg = &ast.TypeAssertExpr{
+			X:    arg,
+			Type: goTypeExpr(goType),
+		}
+	}
+	rest := &ast.SliceExpr{
+		X:   va,
+		Low: goIntLit(1),
+	}
+	// Append assignment statements.
+	fgen.cur.List = append(fgen.cur.List, assignStmt(name, arg), assignStmt(va, rest))
+}
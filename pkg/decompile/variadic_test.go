@@ -0,0 +1,93 @@
+package decompile
+
+import "testing"
+
+func TestVAListArg(t *testing.T) {
+	const prefix = `
+%struct.__va_list_tag = type { i32, i32, i8*, i8* }
+
+declare void @llvm.va_start(i8*)
+declare void @llvm.va_end(i8*)
+declare i32 @vprintf(i8*, %struct.__va_list_tag*)
+declare void @g(%struct.__va_list_tag*)
+`
+	golden := []struct {
+		name string
+		src  string
+		want []string
+		// Specifies whether decompilation is expected to fail.
+		fail bool
+	}{
+		// Variable argument list forwarded to C library function.
+		{
+			name: "vprintf",
+			src: prefix + `
+define void @f(i8* %format, ...) {
+	%ap = alloca [1 x %struct.__va_list_tag]
+	%1 = getelementptr [1 x %struct.__va_list_tag], [1 x %struct.__va_list_tag]* %ap, i64 0, i64 0
+	%2 = bitcast %struct.__va_list_tag* %1 to i8*
+	call void @llvm.va_start(i8* %2)
+	%3 = getelementptr [1 x %struct.__va_list_tag], [1 x %struct.__va_list_tag]* %ap, i64 0, i64 0
+	%4 = call i32 @vprintf(i8* %format, %struct.__va_list_tag* %3)
+	%5 = getelementptr [1 x %struct.__va_list_tag], [1 x %struct.__va_list_tag]* %ap, i64 0, i64 0
+	%6 = bitcast %struct.__va_list_tag* %5 to i8*
+	call void @llvm.va_end(i8* %6)
+	ret void
+}
+`,
+			want: []string{
+				`func vprintf(_0 string, args ...interface{}) int32`,
+				`ap_va = args`,
+				`vprintf(goString(format), ap_va...)`,
+			},
+		},
+		// Variable argument list passed to unknown function.
+		{
+			name: "unknown",
+			src: prefix + `
+define void @f(i8* %format, ...) {
+	%ap = alloca [1 x %struct.__va_list_tag]
+	%1 = getelementptr [1 x %struct.__va_list_tag], [1 x %struct.__va_list_tag]* %ap, i64 0, i64 0
+	%2 = bitcast %struct.__va_list_tag* %1 to i8*
+	call void @llvm.va_start(i8* %2)
+	%3 = getelementptr [1 x %struct.__va_list_tag], [1 x %struct.__va_list_tag]* %ap, i64 0, i64 0
+	call void @g(%struct.__va_list_tag* %3)
+	ret void
+}
+`,
+			fail: true,
+		},
+		// Parameter colliding with the variadic parameter.
+		{
+			name: "args",
+			src: prefix + `
+define void @f(i8* %args, ...) {
+	%ap = alloca [1 x %struct.__va_list_tag]
+	%1 = getelementptr [1 x %struct.__va_list_tag], [1 x %struct.__va_list_tag]* %ap, i64 0, i64 0
+	%2 = bitcast %struct.__va_list_tag* %1 to i8*
+	call void @llvm.va_start(i8* %2)
+	%3 = getelementptr [1 x %struct.__va_list_tag], [1 x %struct.__va_list_tag]* %ap, i64 0, i64 0
+	%4 = call i32 @vprintf(i8* %args, %struct.__va_list_tag* %3)
+	%5 = getelementptr [1 x %struct.__va_list_tag], [1 x %struct.__va_list_tag]* %ap, i64 0, i64 0
+	%6 = bitcast %struct.__va_list_tag* %5 to i8*
+	call void @llvm.va_end(i8* %6)
+	ret void
+}
+`,
+			want: []string{
+				`func F(args_ `,
+				`ap_va = args`,
+				`vprintf(goString(args_), ap_va...)`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		if gold.fail {
+			if _, errs := decompileString(t, gold.src); len(errs) == 0 {
+				t.Errorf("%q: expected error, got nil", gold.name)
+			}
+			continue
+		}
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
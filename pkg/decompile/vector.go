@@ -0,0 +1,327 @@
+package decompile
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+	"github.com/pkg/errors"
+)
+
+// LLVM IR vectors are lifted to Go arrays, and element-wise operations on
+// vectors are lifted to loops over the array elements.
+
+const (
+	// vecIndexName is the name of the loop index of element-wise vector
+	// operations.
+	vecIndexName = "_i"
+	// vecResultName is the name of the result of element-wise vector operations
+	// lifted to Go expressions.
+	vecResultName = "_v"
+)
+
+// liftBinOp lifts the binary operation op on the LLVM IR operands x and y to Go
+// source code, assigning the result to the variable of inst, emitting to f.
+// Operations on vectors are lifted element-wise.
+func (fgen *funcGen) liftBinOp(inst namedValue, irX, irY value.Value, op elemOp) {
+	// Variable name.
+	name := newIdent(inst)
+	// X and Y operands.
+	x := fgen.liftValue(irX)
+	y := fgen.liftValue(irY)
+	t := irX.Type()
+	if vt, ok := t.(*types.VectorType); ok {
+		loopStmt, err := fgen.gen.vectorLoopStmt(name, x, y, vt, op)
+		if err != nil {
+			fgen.gen.eh(err)
+			return
+		}
+		fgen.cur.List = append(fgen.cur.List, loopStmt)
+		return
+	}
+	expr, err := op(x, y, t)
+	if err != nil {
+		fgen.gen.eh(err)
+		return
+	}
+	// Append assignment statement.
+	fgen.cur.List = append(fgen.cur.List, assignStmt(name, expr))
+}
+
+// elemOp is an operation on the elements x and y of the given LLVM IR element
+// type.
+type elemOp func(x, y ast.Expr, elemType types.Type) (ast.Expr, error)
+
+// vectorLoopStmt returns the Go statement storing the result of the
+// element-wise operation op on the vector operands x and y of the given LLVM IR
+// vector type to dst.
+//
+//    for _i := range dst {
+//       dst[_i] = x[_i] op y[_i]
+//    }
+func (gen *Generator) vectorLoopStmt(dst, x, y ast.Expr, t *types.VectorType, op elemOp) (ast.Stmt, error) {
+	i := ast.NewIdent(vecIndexName)
+	xi := &ast.IndexExpr{X: x, Index: i}
+	yi := &ast.IndexExpr{X: y, Index: i}
+	elem, err := op(xi, yi, t.ElemType)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	rangeStmt := &ast.RangeStmt{
+		Key: i,
+		Tok: token.DEFINE,
+		X:   dst,
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{assignStmt(&ast.IndexExpr{X: dst, Index: i}, elem)},
+		},
+	}
+	return rangeStmt, nil
+}
+
+// vectorOpExpr returns the Go expression of the element-wise operation op on
+// the vector operands x and y of the given LLVM IR vector type.
+//
+//    func() [N]T {
+//       var _v [N]T
+//       for _i := range _v {
+//          _v[_i] = x[_i] op y[_i]
+//       }
+//       return _v
+//    }()
+func (gen *Generator) vectorOpExpr(x, y ast.Expr, t *types.VectorType, op elemOp) (ast.Expr, error) {
+	goType, err := gen.goType(t)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	v := ast.NewIdent(vecResultName)
+	declStmt := &ast.DeclStmt{
+		Decl: &ast.GenDecl{
+			Tok: token.VAR,
+			Specs: []ast.Spec{
+				&ast.ValueSpec{
+					Names: []*ast.Ident{v},
+					Type:  goTypeExpr(goType),
+				},
+			},
+		},
+	}
+	loopStmt, err := gen.vectorLoopStmt(v, x, y, t, op)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	returnStmt := &ast.ReturnStmt{
+		Results: []ast.Expr{v},
+	}
+	return funcLitCall(goType, declStmt, loopStmt, returnStmt), nil
+}
+
+// shuffleVectorExpr returns the Go composite literal permuting the elements of
+// the vector operands x and y of the given LLVM IR vector type, based on the
+// given shuffle mask.
+//
+//    [N]T{x[0], y[1], ...}
+func (gen *Generator) shuffleVectorExpr(x, y ast.Expr, t types.Type, irMask constant.Constant) (ast.Expr, error) {
+	xType, ok := t.(*types.VectorType)
+	if !ok {
+		return nil, errors.Errorf("invalid shufflevector operand type; expected *types.VectorType, got %T", t)
+	}
+	mask, err := shuffleMask(irMask)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	typ := types.NewVector(uint64(len(mask)), xType.ElemType)
+	goType, err := gen.goType(typ)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	goElemType, err := gen.goType(xType.ElemType)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var elems []ast.Expr
+	n := xType.Len
+	for _, m := range mask {
+		index, ok := m.(*constant.Int)
+		if !ok {
+			// Undefined mask element; use zero value.
+			elems = append(elems, zeroValueExpr(goElemType))
+			continue
+		}
+		i := index.X.Uint64()
+		switch {
+		case i < n:
+			elems = append(elems, &ast.IndexExpr{X: x, Index: goUintLit(i)})
+		default:
+			elems = append(elems, &ast.IndexExpr{X: y, Index: goUintLit(i - n)})
+		}
+	}
+	return &ast.CompositeLit{
+		Type: goTypeExpr(goType),
+		Elts: elems,
+	}, nil
+}
+
+// shuffleMask returns the elements of the given shufflevector mask. Undefined
+// mask elements are represented by non-integer constants.
+func shuffleMask(irMask constant.Constant) ([]constant.Constant, error) {
+	switch mask := foldConst(irMask).(type) {
+	case *constant.Vector:
+		return mask.Elems, nil
+	case *constant.ZeroInitializer, *constant.Undef:
+		// Splat of the first element (zeroinitializer), or undefined mask.
+		t, ok := mask.Type().(*types.VectorType)
+		if !ok {
+			return nil, errors.Errorf("invalid shufflevector mask type; expected *types.VectorType, got %T", mask.Type())
+		}
+		var elem constant.Constant = constant.NewUndef(t.ElemType)
+		if _, ok := mask.(*constant.ZeroInitializer); ok {
+			elem = constant.NewInt(t.ElemType.(*types.IntType), 0)
+		}
+		elems := make([]constant.Constant, t.Len)
+		for i := range elems {
+			elems[i] = elem
+		}
+		return elems, nil
+	default:
+		return nil, errors.Errorf("support for shufflevector mask %T not yet implemented", irMask)
+	}
+}
+
+// liftInstExtractElement lifts the LLVM IR extractelement instruction to Go
+// source code, emitting to f.
+//
+//    name = x[index]
+func (fgen *funcGen) liftInstExtractElement(inst *ir.InstExtractElement) {
+	// Variable name.
+	name := newIdent(inst)
+	// Vector and index operands.
+	x := fgen.liftValue(inst.X)
+	index := fgen.liftValue(inst.Index)
+	elem := &ast.IndexExpr{X: x, Index: index}
+	// Append assignment statement.
+	fgen.cur.List = append(fgen.cur.List, assignStmt(name, elem))
+}
+
+// liftInstInsertElement lifts the LLVM IR insertelement instruction to Go
+// source code, emitting to f.
+//
+//    name = x
+//    name[index] = elem
+func (fgen *funcGen) liftInstInsertElement(inst *ir.InstInsertElement) {
+	// Variable name.
+	name := newIdent(inst)
+	// Vector, element and index operands.
+	var x ast.Expr
+	switch inst.X.(type) {
+	case *constant.Undef, *constant.ZeroInitializer:
+		goType, err := fgen.gen.goType(inst.X.Type())
+		if err != nil {
+			fgen.gen.eh(err)
+			return
+		}
+		x = zeroValueExpr(goType)
+	default:
+		x = fgen.liftValue(inst.X)
+	}
+	elem := fgen.liftValue(inst.Elem)
+	index := fgen.liftValue(inst.Index)
+	dst := &ast.IndexExpr{X: name, Index: index}
+	// Append assignment statements.
+	fgen.cur.List = append(fgen.cur.List, assignStmt(name, x), assignStmt(dst, elem))
+}
+
+// liftInstShuffleVector lifts the LLVM IR shufflevector instruction to Go
+// source code, emitting to f.
+//
+//    name = [N]T{x[0], y[1], ...}
+func (fgen *funcGen) liftInstShuffleVector(inst *ir.InstShuffleVector) {
+	// Variable name.
+	name := newIdent(inst)
+	// Vector operands and shuffle mask.
+	x := fgen.liftValue(inst.X)
+	y := fgen.liftValue(inst.Y)
+	mask, ok := inst.Mask.(constant.Constant)
+	if !ok {
+		fgen.gen.Errorf("invalid shufflevector mask; expected constant, got %T", inst.Mask)
+		return
+	}
+	shuffle, err := fgen.gen.shuffleVectorExpr(x, y, inst.X.Type(), mask)
+	if err != nil {
+		fgen.gen.eh(err)
+		return
+	}
+	// Append assignment statement.
+	fgen.cur.List = append(fgen.cur.List, assignStmt(name, shuffle))
+}
+
+// liftVectorConv lifts the LLVM IR conversion instruction on vector operands to
+// Go source code, converting the vector element-wise, emitting to f.
+//
+//    for _i := range name {
+//       name[_i] = T(from[_i])
+//    }
+func (fgen *funcGen) liftVectorConv(inst namedValue, from value.Value, to types.Type, conv func(x ast.Expr, from, to types.Type) (ast.Expr, error)) {
+	// Variable name.
+	name := newIdent(inst)
+	fromType, ok := fgen.gen.valueType(from).(*types.VectorType)
+	toType := to.(*types.VectorType)
+	if !ok || fromType.Len != toType.Len {
+		fgen.gen.Errorf("support for conversion from %v to %v not yet implemented", from.Type(), to)
+		return
+	}
+	// Source value.
+	x := fgen.liftValue(from)
+	op := func(x, _ ast.Expr, elemType types.Type) (ast.Expr, error) {
+		return conv(x, fromType.ElemType, elemType)
+	}
+	loopStmt, err := fgen.gen.vectorLoopStmt(name, x, x, toType, op)
+	if err != nil {
+		fgen.gen.eh(err)
+		return
+	}
+	fgen.cur.List = append(fgen.cur.List, loopStmt)
+}
+
+// liftVectorSelect lifts the LLVM IR select instruction with a vector condition
+// to Go source code, selecting element-wise, emitting to f.
+//
+//    for _i := range name {
+//       if cond[_i] {
+//          name[_i] = x[_i]
+//       } else {
+//          name[_i] = y[_i]
+//       }
+//    }
+func (fgen *funcGen) liftVectorSelect(inst *ir.InstSelect) {
+	// Variable name.
+	name := newIdent(inst)
+	// Condition and operands.
+	cond := fgen.liftValue(inst.Cond)
+	x := fgen.liftValue(inst.X)
+	y := fgen.liftValue(inst.Y)
+	i := ast.NewIdent(vecIndexName)
+	index := func(x ast.Expr) ast.Expr {
+		return &ast.IndexExpr{X: x, Index: i}
+	}
+	ifStmt := &ast.IfStmt{
+		Cond: index(cond),
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{assignStmt(index(name), index(x))},
+		},
+		Else: &ast.BlockStmt{
+			List: []ast.Stmt{assignStmt(index(name), index(y))},
+		},
+	}
+	rangeStmt := &ast.RangeStmt{
+		Key: i,
+		Tok: token.DEFINE,
+		X:   name,
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{ifStmt},
+		},
+	}
+	fgen.cur.List = append(fgen.cur.List, rangeStmt)
+}
@@ -0,0 +1,83 @@
+package decompile
+
+import "testing"
+
+func TestVector(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Splat using zeroinitializer shuffle mask.
+		{
+			name: "splat",
+			src: `
+define <4 x i32> @f(<4 x i32> %x) {
+	%1 = shufflevector <4 x i32> %x, <4 x i32> undef, <4 x i32> zeroinitializer
+	ret <4 x i32> %1
+}
+`,
+			want: []string{
+				`_1 = [4]int32{x[0], x[0], x[0], x[0]}`,
+			},
+		},
+		// Element-wise integer comparison.
+		{
+			name: "icmp",
+			src: `
+define <4 x i1> @f(<4 x i32> %x, <4 x i32> %y) {
+	%1 = icmp ult <4 x i32> %x, %y
+	ret <4 x i1> %1
+}
+`,
+			want: []string{
+				`for _i := range _1 {`,
+				`_1[_i] = uint32(x[_i]) < uint32(y[_i])`,
+			},
+		},
+		// Element-wise floating-point comparison.
+		{
+			name: "fcmp",
+			src: `
+define <2 x i1> @f(<2 x double> %x, <2 x double> %y) {
+	%1 = fcmp olt <2 x double> %x, %y
+	ret <2 x i1> %1
+}
+`,
+			want: []string{
+				`_1[_i] = x[_i] < y[_i]`,
+			},
+		},
+		// Element-wise conversion.
+		{
+			name: "sext",
+			src: `
+define <4 x i64> @f(<4 x i32> %x) {
+	%1 = sext <4 x i32> %x to <4 x i64>
+	ret <4 x i64> %1
+}
+`,
+			want: []string{
+				`_1[_i] = int64(x[_i])`,
+			},
+		},
+		// Element-wise selection.
+		{
+			name: "select",
+			src: `
+define <4 x i32> @f(<4 x i1> %c, <4 x i32> %x, <4 x i32> %y) {
+	%1 = select <4 x i1> %c, <4 x i32> %x, <4 x i32> %y
+	ret <4 x i32> %1
+}
+`,
+			want: []string{
+				`if c[_i] {`,
+				`_1[_i] = x[_i]`,
+				`_1[_i] = y[_i]`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}
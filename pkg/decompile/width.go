@@ -0,0 +1,391 @@
+package decompile
+
+import (
+	"go/ast"
+	"go/token"
+	gotypes "go/types"
+	"math/big"
+	"strconv"
+
+	"github.com/llir/llvm/ir/types"
+	"github.com/pkg/errors"
+)
+
+// This file contains Go expression generators for integer types of non-native
+// bit sizes and extended precision floating-point types.
+//
+// Integers of non-native bit sizes (e.g. i24) are held sign-extended in the
+// next wider Go integer type, and the results of arithmetic are wrapped to the
+// bit size of the integer type. Integers wider than 64 bits are held
+// sign-extended in arbitrary precision integers (*big.Int), and the results of
+// arithmetic are wrapped to the bit size of the integer type by the wrapBigInt
+// helper function.
+
+// isBigInt reports whether the given LLVM IR type is an integer type wider than
+// 64 bits.
+func isBigInt(t types.Type) bool {
+	if t, ok := t.(*types.IntType); ok {
+		return t.BitSize > 64
+	}
+	return false
+}
+
+// isNonNativeInt reports whether the given LLVM IR integer type has a bit size
+// which has no Go integer type counterpart.
+func isNonNativeInt(t *types.IntType) bool {
+	switch t.BitSize {
+	case 1, 8, 16, 32, 64:
+		return false
+	}
+	return t.BitSize < 64
+}
+
+// containerBits returns the bit size of the Go integer type holding values of
+// the given LLVM IR integer type.
+func containerBits(t *types.IntType) uint64 {
+	switch {
+	case t.BitSize <= 8:
+		return 8
+	case t.BitSize <= 16:
+		return 16
+	case t.BitSize <= 32:
+		return 32
+	default:
+		return 64
+	}
+}
+
+// wrapIntExpr returns the Go expression wrapping the integer x to the bit size
+// of the given LLVM IR type, by sign-extending its least significant bits.
+// Integers of native bit sizes are returned unmodified.
+//
+//    (x) << 8 >> 8 // i24 held in int32
+func wrapIntExpr(x ast.Expr, t types.Type) ast.Expr {
+	it, ok := t.(*types.IntType)
+	if !ok || !isNonNativeInt(it) {
+		return x
+	}
+	s := goUintLit(containerBits(it) - uint64(it.BitSize))
+	shl := &ast.BinaryExpr{X: &ast.ParenExpr{X: x}, Op: token.SHL, Y: s}
+	return &ast.BinaryExpr{X: shl, Op: token.SHR, Y: s}
+}
+
+// uintExpr returns the Go expression reinterpreting the bits of the integer x of
+// the given LLVM IR integer type as an unsigned integer. The bits outside of
+// the bit size of non-native integer types are masked out.
+//
+//    uint32(x)
+//    (uint32(x) & 0xFFFFFF) // i24 held in int32
+func (gen *Generator) uintExpr(x ast.Expr, t *types.IntType) ast.Expr {
+	ux := goConvExpr(gen.goUintType(t), x)
+	if !isNonNativeInt(t) {
+		return ux
+	}
+	mask := &ast.BasicLit{
+		Kind:  token.INT,
+		Value: "0x" + strconv.FormatUint(1<<t.BitSize-1, 16),
+	}
+	return &ast.ParenExpr{X: &ast.BinaryExpr{X: ux, Op: token.AND, Y: mask}}
+}
+
+// bigMethods maps from Go binary operator to the corresponding method of
+// math/big.Int and math/big.Float.
+var bigMethods = map[token.Token]string{
+	token.ADD: "Add",
+	token.SUB: "Sub",
+	token.MUL: "Mul",
+	token.QUO: "Quo",
+	token.REM: "Rem",
+	token.AND: "And",
+	token.OR:  "Or",
+	token.XOR: "Xor",
+	token.SHL: "Lsh",
+	token.SHR: "Rsh",
+}
+
+// bigBinOpExpr returns the Go expression of the binary operation op on the
+// arbitrary precision operands x and y, where name is the name of the math/big
+// type (Int or Float).
+//
+//    new(big.Int).Add(x, y)
+//    new(big.Int).Lsh(x, uint(y.Uint64()))
+func (gen *Generator) bigBinOpExpr(x, y ast.Expr, op token.Token, name string) (ast.Expr, error) {
+	method, ok := bigMethods[op]
+	if !ok || (name == "Float" && op != token.ADD && op != token.SUB && op != token.MUL && op != token.QUO) {
+		return nil, errors.Errorf("support for binary operation %v on big.%s operands not yet implemented", op, name)
+	}
+	if op == token.SHL || op == token.SHR {
+		// Use unsigned shift count.
+		y = goConvExpr(gotypes.Typ[gotypes.Uint], callExpr(&ast.SelectorExpr{X: y, Sel: ast.NewIdent("Uint64")}))
+	}
+	z := callExpr(ast.NewIdent("new"), gen.qualIdent("math/big", name))
+	return callExpr(&ast.SelectorExpr{X: z, Sel: ast.NewIdent(method)}, x, y), nil
+}
+
+// bigIntBinOpExpr returns the Go expression of the binary operation op on the
+// arbitrary precision integer operands x and y of the given LLVM IR integer
+// type, wrapping the result to the bit size of the integer type. If unsigned is
+// set, the operands are interpreted as unsigned integers.
+//
+//    wrapBigInt(new(big.Int).Add(x, y), 128, false)
+func (gen *Generator) bigIntBinOpExpr(x, y ast.Expr, op token.Token, t *types.IntType, unsigned bool) (ast.Expr, error) {
+	if unsigned {
+		x = gen.wrapBigIntExpr(x, t, true)
+		if op != token.SHL && op != token.SHR {
+			y = gen.wrapBigIntExpr(y, t, true)
+		}
+	}
+	z, err := gen.bigBinOpExpr(x, y, op, "Int")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch op {
+	case token.AND, token.OR, token.XOR:
+		if !unsigned {
+			// Bitwise operations on two's complement operands never overflow.
+			return z, nil
+		}
+	}
+	return gen.wrapBigIntExpr(z, t, false), nil
+}
+
+// wrapBigIntExpr returns the Go expression wrapping the arbitrary precision
+// integer x to the bit size of the given LLVM IR integer type. If unsigned is
+// set, the result is interpreted as an unsigned integer.
+//
+//    wrapBigInt(x, 128, false)
+func (gen *Generator) wrapBigIntExpr(x ast.Expr, t *types.IntType, unsigned bool) ast.Expr {
+	gen.addWrapBigIntHelper()
+	return callExpr(ast.NewIdent(wrapBigIntName), x, goUintLit(uint64(t.BitSize)), ast.NewIdent(strconv.FormatBool(unsigned)))
+}
+
+// wrapBigIntName is the name of the arbitrary precision integer wrapping helper
+// function.
+const wrapBigIntName = "wrapBigInt"
+
+// addWrapBigIntHelper appends the arbitrary precision integer wrapping helper
+// function to the Go source file, if not already present.
+//
+//    // wrapBigInt wraps x to an integer of the given bit size; interpreted as
+//    // an unsigned integer if unsigned is set, and as a two's complement signed
+//    // integer otherwise.
+//    func wrapBigInt(x *big.Int, bits uint, unsigned bool) *big.Int {
+//       m := new(big.Int).Lsh(big.NewInt(1), bits)
+//       z := new(big.Int).Mod(x, m)
+//       if !unsigned && z.Bit(int(bits)-1) == 1 {
+//          z.Sub(z, m)
+//       }
+//       return z
+//    }
+func (gen *Generator) addWrapBigIntHelper() {
+	if gen.helpers[wrapBigIntName] {
+		return
+	}
+	gen.helpers[wrapBigIntName] = true
+	x := ast.NewIdent("x")
+	bits := ast.NewIdent("bits")
+	unsigned := ast.NewIdent("unsigned")
+	m := ast.NewIdent("m")
+	z := ast.NewIdent("z")
+	bigInt := &ast.StarExpr{X: gen.qualIdent("math/big", "Int")}
+	// method returns the Go call expression of the given method of recv.
+	method := func(recv ast.Expr, name string, args ...ast.Expr) *ast.CallExpr {
+		return callExpr(&ast.SelectorExpr{X: recv, Sel: ast.NewIdent(name)}, args...)
+	}
+	newInt := func() ast.Expr {
+		return callExpr(ast.NewIdent("new"), gen.qualIdent("math/big", "Int"))
+	}
+	one := callExpr(gen.qualIdent("math/big", "NewInt"), goIntLit(1))
+	// int(bits)-1
+	signBit := &ast.BinaryExpr{X: callExpr(ast.NewIdent("int"), bits), Op: token.SUB, Y: goIntLit(1)}
+	ifStmt := &ast.IfStmt{
+		Cond: &ast.BinaryExpr{
+			X:  &ast.UnaryExpr{Op: token.NOT, X: unsigned},
+			Op: token.LAND,
+			Y:  &ast.BinaryExpr{X: method(z, "Bit", signBit), Op: token.EQL, Y: goIntLit(1)},
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{&ast.ExprStmt{X: method(z, "Sub", z, m)}},
+		},
+	}
+	funcDecl := &ast.FuncDecl{
+		Name: ast.NewIdent(wrapBigIntName),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{x}, Type: bigInt},
+					{Names: []*ast.Ident{bits}, Type: ast.NewIdent("uint")},
+					{Names: []*ast.Ident{unsigned}, Type: ast.NewIdent("bool")},
+				},
+			},
+			Results: &ast.FieldList{
+				List: []*ast.Field{{Type: bigInt}},
+			},
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.AssignStmt{Lhs: []ast.Expr{m}, Tok: token.DEFINE, Rhs: []ast.Expr{method(newInt(), "Lsh", one, bits)}},
+				&ast.AssignStmt{Lhs: []ast.Expr{z}, Tok: token.DEFINE, Rhs: []ast.Expr{method(newInt(), "Mod", x, m)}},
+				ifStmt,
+				&ast.ReturnStmt{Results: []ast.Expr{z}},
+			},
+		},
+	}
+	gen.file.Decls = append(gen.file.Decls, funcDecl)
+}
+
+// bigCmpExpr returns the Go expression of the comparison op of the arbitrary
+// precision operands x and y.
+//
+//    x.Cmp(y) < 0
+func bigCmpExpr(x, y ast.Expr, op token.Token) ast.Expr {
+	cmp := callExpr(&ast.SelectorExpr{X: x, Sel: ast.NewIdent("Cmp")}, y)
+	return &ast.BinaryExpr{X: cmp, Op: op, Y: goIntLit(0)}
+}
+
+// bigIntLit returns the Go expression of the given arbitrary precision integer
+// constant.
+//
+//    big.NewInt(42)
+//
+//    func() *big.Int {
+//       x, _ := new(big.Int).SetString("170141183460469231731687303715884105727", 10)
+//       return x
+//    }()
+func (gen *Generator) bigIntLit(x *big.Int) ast.Expr {
+	if x.IsInt64() {
+		return callExpr(gen.qualIdent("math/big", "NewInt"), goIntLit(x.Int64()))
+	}
+	z := callExpr(ast.NewIdent("new"), gen.qualIdent("math/big", "Int"))
+	setString := callExpr(&ast.SelectorExpr{X: z, Sel: ast.NewIdent("SetString")}, strLit(x.String()), goIntLit(10))
+	return gen.bigSetExpr(gen.bigType("Int"), setString)
+}
+
+// isBigFloat reports whether the given LLVM IR type is a floating-point type
+// represented by arbitrary precision floating-point numbers.
+func (gen *Generator) isBigFloat(t types.Type) bool {
+	if !gen.BigFloat {
+		return false
+	}
+	if t, ok := t.(*types.FloatType); ok {
+		switch t.Kind {
+		case types.FloatKindX86_FP80, types.FloatKindFP128, types.FloatKindPPC_FP128:
+			return true
+		}
+	}
+	return false
+}
+
+// bigFloatLit returns the Go expression of the given arbitrary precision
+// floating-point constant.
+//
+//    func() *big.Float {
+//       x, _ := new(big.Float).SetPrec(64).SetString("3.14159265358979323851")
+//       return x
+//    }()
+func (gen *Generator) bigFloatLit(x *big.Float) ast.Expr {
+	z := callExpr(ast.NewIdent("new"), gen.qualIdent("math/big", "Float"))
+	setPrec := callExpr(&ast.SelectorExpr{X: z, Sel: ast.NewIdent("SetPrec")}, goUintLit(uint64(x.Prec())))
+	setString := callExpr(&ast.SelectorExpr{X: setPrec, Sel: ast.NewIdent("SetString")}, strLit(x.Text('g', -1)))
+	return gen.bigSetExpr(gen.bigType("Float"), setString)
+}
+
+// bigSetExpr returns the Go expression of the first result of the given call
+// returning an arbitrary precision number and a second result.
+//
+//    func() T {
+//       x, _ := call
+//       return x
+//    }()
+func (gen *Generator) bigSetExpr(goType gotypes.Type, call ast.Expr) ast.Expr {
+	x := ast.NewIdent("x")
+	defStmt := &ast.AssignStmt{
+		Lhs: []ast.Expr{x, ast.NewIdent("_")},
+		Tok: token.DEFINE,
+		Rhs: []ast.Expr{call},
+	}
+	return funcLitCall(goType, defStmt, &ast.ReturnStmt{Results: []ast.Expr{x}})
+}
+
+// bigFloatConvExpr returns the Go conversion expression of x from the given
+// LLVM IR type to the given LLVM IR type, where either type is represented by
+// arbitrary precision floating-point numbers.
+//
+//    big.NewFloat(float64(x))
+//
+//    func() T {
+//       x, _ := x.Float64()
+//       return T(x)
+//    }()
+func (gen *Generator) bigFloatConvExpr(x ast.Expr, from, to types.Type) (ast.Expr, error) {
+	switch {
+	case gen.isBigFloat(from) && gen.isBigFloat(to):
+		return x, nil
+	case gen.isBigFloat(to):
+		f := goConvExpr(gotypes.Typ[gotypes.Float64], x)
+		return callExpr(gen.qualIdent("math/big", "NewFloat"), f), nil
+	default:
+		return gen.bigFloatValueExpr(x, to)
+	}
+}
+
+// bigFloatValueExpr returns the Go expression of the conversion of the
+// arbitrary precision floating-point value x to the given LLVM IR type.
+//
+//    func() T {
+//       x, _ := x.Float64()
+//       return T(x)
+//    }()
+func (gen *Generator) bigFloatValueExpr(x ast.Expr, to types.Type) (ast.Expr, error) {
+	goType, err := gen.goType(to)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	f := ast.NewIdent("x")
+	defStmt := &ast.AssignStmt{
+		Lhs: []ast.Expr{f, ast.NewIdent("_")},
+		Tok: token.DEFINE,
+		Rhs: []ast.Expr{callExpr(&ast.SelectorExpr{X: x, Sel: ast.NewIdent("Float64")})},
+	}
+	returnStmt := &ast.ReturnStmt{Results: []ast.Expr{goConvExpr(goType, f)}}
+	return funcLitCall(goType, defStmt, returnStmt), nil
+}
+
+// bigIntToFloatExpr returns the Go expression of the conversion of the
+// arbitrary precision integer x of the given LLVM IR integer type to the given
+// floating-point LLVM IR type. If unsigned is set, x is interpreted as an
+// unsigned integer.
+//
+//    new(big.Float).SetInt(wrapBigInt(x, 128, true))
+//
+//    func() float64 {
+//       x, _ := new(big.Float).SetInt(x).Float64()
+//       return float64(x)
+//    }()
+func (gen *Generator) bigIntToFloatExpr(x ast.Expr, from *types.IntType, to types.Type, unsigned bool) (ast.Expr, error) {
+	if unsigned {
+		x = gen.wrapBigIntExpr(x, from, true)
+	}
+	z := callExpr(ast.NewIdent("new"), gen.qualIdent("math/big", "Float"))
+	f := callExpr(&ast.SelectorExpr{X: z, Sel: ast.NewIdent("SetInt")}, x)
+	if gen.isBigFloat(to) {
+		return f, nil
+	}
+	return gen.bigFloatValueExpr(f, to)
+}
+
+// floatToBigIntExpr returns the Go expression of the conversion of the
+// floating-point value x of the given LLVM IR type to the given arbitrary
+// precision LLVM IR integer type, truncating towards zero.
+//
+//    wrapBigInt(func() *big.Int {
+//       x, _ := big.NewFloat(float64(x)).Int(nil)
+//       return x
+//    }(), 128, false)
+func (gen *Generator) floatToBigIntExpr(x ast.Expr, from types.Type, to *types.IntType) ast.Expr {
+	if !gen.isBigFloat(from) {
+		x = callExpr(gen.qualIdent("math/big", "NewFloat"), goConvExpr(gotypes.Typ[gotypes.Float64], x))
+	}
+	i := callExpr(&ast.SelectorExpr{X: x, Sel: ast.NewIdent("Int")}, ast.NewIdent("nil"))
+	return gen.wrapBigIntExpr(gen.bigSetExpr(gen.bigType("Int"), i), to, false)
+}
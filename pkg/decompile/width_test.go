@@ -0,0 +1,159 @@
+package decompile
+
+import "testing"
+
+func TestIntWidth(t *testing.T) {
+	golden := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		// Unsigned comparison of non-native integer type.
+		{
+			name: "i24 ult",
+			src: `
+define i1 @f(i24 %x, i24 %y) {
+	%1 = icmp ult i24 %x, %y
+	ret i1 %1
+}
+`,
+			want: []string{
+				`_1 = (uint32(x) & 0xffffff) < (uint32(y) & 0xffffff)`,
+			},
+		},
+		// Signed comparison of non-native integer type.
+		{
+			name: "i24 slt",
+			src: `
+define i1 @f(i24 %x, i24 %y) {
+	%1 = icmp slt i24 %x, %y
+	ret i1 %1
+}
+`,
+			want: []string{
+				`_1 = x < y`,
+			},
+		},
+		// Signed comparison of arbitrary precision integers.
+		{
+			name: "i128 slt",
+			src: `
+define i1 @f(i128 %x, i128 %y) {
+	%1 = icmp slt i128 %x, %y
+	ret i1 %1
+}
+`,
+			want: []string{
+				`_1 = x.Cmp(y) < 0`,
+			},
+		},
+		// Unsigned comparison of arbitrary precision integers.
+		{
+			name: "i128 ult",
+			src: `
+define i1 @f(i128 %x, i128 %y) {
+	%1 = icmp ult i128 %x, %y
+	ret i1 %1
+}
+`,
+			want: []string{
+				`_1 = wrapBigInt(x, 128, true).Cmp(wrapBigInt(y, 128, true)) < 0`,
+				`func wrapBigInt(x *big.Int, bits uint, unsigned bool) *big.Int {`,
+			},
+		},
+		// Arithmetic on arbitrary precision integers wraps.
+		{
+			name: "i128 add",
+			src: `
+define i128 @f(i128 %x, i128 %y) {
+	%1 = add i128 %x, %y
+	ret i128 %1
+}
+`,
+			want: []string{
+				`_1 = wrapBigInt(new(big.Int).Add(x, y), 128, false)`,
+			},
+		},
+		// Truncation of arbitrary precision integers wraps.
+		{
+			name: "i256 trunc",
+			src: `
+define i128 @f(i256 %x) {
+	%1 = trunc i256 %x to i128
+	ret i128 %1
+}
+`,
+			want: []string{
+				`_1 = wrapBigInt(x, 128, false)`,
+			},
+		},
+		{
+			name: "i128 sext",
+			src: `
+define i256 @f(i128 %x) {
+	%1 = sext i128 %x to i256
+	ret i256 %1
+}
+`,
+			want: []string{
+				`_1 = x`,
+			},
+		},
+		// Floating-point conversion of arbitrary precision integers.
+		{
+			name: "i128 uitofp",
+			src: `
+define double @f(i128 %x) {
+	%1 = uitofp i128 %x to double
+	ret double %1
+}
+`,
+			want: []string{
+				`x, _ := new(big.Float).SetInt(wrapBigInt(x, 128, true)).Float64()`,
+				`return float64(x)`,
+			},
+		},
+		{
+			name: "i128 sitofp",
+			src: `
+define float @f(i128 %x) {
+	%1 = sitofp i128 %x to float
+	ret float %1
+}
+`,
+			want: []string{
+				`x, _ := new(big.Float).SetInt(x).Float64()`,
+				`return float32(x)`,
+			},
+		},
+		{
+			name: "i128 fptoui",
+			src: `
+define i128 @f(double %x) {
+	%1 = fptoui double %x to i128
+	ret i128 %1
+}
+`,
+			want: []string{
+				`_1 = wrapBigInt(func() *big.Int {`,
+				`x, _ := big.NewFloat(float64(x)).Int(nil)`,
+				`}(), 128, false)`,
+			},
+		},
+		{
+			name: "i128 fptosi",
+			src: `
+define i128 @f(float %x) {
+	%1 = fptosi float %x to i128
+	ret i128 %1
+}
+`,
+			want: []string{
+				`x, _ := big.NewFloat(float64(x)).Int(nil)`,
+			},
+		},
+	}
+	for _, gold := range golden {
+		checkDecompile(t, gold.name, gold.src, gold.want)
+	}
+}